@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/notify"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// alerter pushes high-signal events (runs failing and recovering, crimes
+// expiring and expired, new members without OC history) to the configured
+// notifiers. It remembers what it has already announced so each event is
+// pushed once per process, and expired crimes once across processes.
+type alerter struct {
+	notifier     notify.Notifier
+	knownMembers map[int]bool
+	expiredSince int64
+	// sincePath keeps expiredSince between processes
+	sincePath string
+	dryRun    bool
+	// expiring are the IDs of the crimes announced as about to expire
+	expiring map[int]bool
+
+	// failAfter is the --alert-after number of failed runs in a row that
+	// raises an alert; failures counts them, since failingSince
+	failAfter    int
+	failures     int
+	failingSince time.Time
+}
+
+// newAlerter returns an alerter for the notifiers, or nil without any.
+// Expired crimes are announced from those after the last one a previous
+// process announced, as recorded in sincePath, or else from lookback ago.
+func newAlerter(n notify.Multi, lookback time.Duration, sincePath string, failAfter int, dryRun bool) *alerter {
+	if len(n) == 0 {
+		return nil
+	}
+	a := &alerter{
+		notifier:     n,
+		expiredSince: time.Now().Add(-lookback).Unix(),
+		sincePath:    sincePath,
+		failAfter:    max(failAfter, 1),
+		dryRun:       dryRun,
+	}
+	if data, err := os.ReadFile(sincePath); err == nil {
+		if since, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			a.expiredSince = since
+		}
+	}
+	return a
+}
+
+// alertLookback is how far back the first check of a process looks for
+// expired crimes when no previous one recorded where it stopped: the time
+// between runs, from the interval or the schedule.
+func alertLookback(o *options) time.Duration {
+	if every := o.every(); every > 0 || cronSchedule == nil {
+		return every
+	}
+	next := cronSchedule.Next(time.Now())
+	if next.IsZero() {
+		return 0
+	}
+	return cronSchedule.Next(next).Sub(next)
+}
+
+// expiredSincePath is the file in the temp dir keeping the expiry time of
+// the last expired crime announced for the faction of apiKey, so runs by
+// cron don't announce a crime twice or miss one.
+func expiredSincePath(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return filepath.Join(os.TempDir(), "torn-oc-history-"+hex.EncodeToString(sum[:6])+".expired")
+}
+
+func (a *alerter) send(ctx context.Context, title, message string) {
+	if a.dryRun {
+		fmt.Printf("[dry run] notify %q: %s\n", title, message)
+		return
+	}
+	if err := a.notifier.Notify(ctx, title, message); err != nil {
+		slog.Error("send notification", "title", title, "error", err)
+	}
+}
+
+// runDone counts a run towards a failure alert: the failAfter-th run in a
+// row that produced no reports raises one, and the first that does once
+// alerted announces the recovery. A run skipped for the lock counts as
+// nothing.
+func (a *alerter) runDone(ctx context.Context, info *runInfo) {
+	if a == nil {
+		return
+	}
+	switch info.Code {
+	case exitLocked:
+	case exitOK, exitPartial:
+		if a.failures >= a.failAfter {
+			a.send(ctx, "Torn OC History recovered",
+				fmt.Sprintf("Runs succeed again after %d failed in a row since %s.", a.failures, formatTime(a.failingSince)))
+		}
+		a.failures = 0
+	default:
+		if a.failures == 0 {
+			a.failingSince = info.StartedAt
+		}
+		a.failures++
+		if a.failures == a.failAfter {
+			msg := info.failure()
+			if a.failures > 1 {
+				msg = fmt.Sprintf("%d runs in a row failed since %s. Last error: %s", a.failures, formatTime(a.failingSince), msg)
+			}
+			a.send(ctx, "Torn OC History run failed", msg)
+		}
+	}
+}
+
+// newMembers announces members that joined since the previous run and have no
+// recorded OC participation. The first run only records the baseline.
+func (a *alerter) newMembers(ctx context.Context, members []torn.Member, stats MemberStats) {
+	if a == nil {
+		return
+	}
+	first := a.knownMembers == nil
+	if first {
+		a.knownMembers = make(map[int]bool, len(members))
+	}
+
+	var names []string
+	for _, m := range members {
+		if a.knownMembers[m.ID] {
+			continue
+		}
+		a.knownMembers[m.ID] = true
+		if _, ok := stats[m.ID]; !ok && !first {
+			names = append(names, fmt.Sprintf("%s (%d)", m.Name, m.ID))
+		}
+	}
+	if len(names) > 0 {
+		a.send(ctx, "New members without OC history", strings.Join(names, "\n"))
+	}
+}
+
+// expiringCrimes announces the active crimes with open slots that expire
+// within window, with the best candidates for each slot, once per crime.
+func (a *alerter) expiringCrimes(ctx context.Context, active []torn.Crime, members []torn.Member, stats MemberStats, window time.Duration) {
+	if a == nil || window <= 0 {
+		return
+	}
+	if a.expiring == nil {
+		a.expiring = make(map[int]bool)
+	}
+	deadline := time.Now().Add(window).Unix()
+	// forget crimes that are no longer active
+	for id := range a.expiring {
+		if !slices.ContainsFunc(active, func(c torn.Crime) bool { return c.ID == id }) {
+			delete(a.expiring, id)
+		}
+	}
+	var crimes []torn.Crime
+	for _, c := range active {
+		open := slices.ContainsFunc(c.Slots, func(s torn.Slot) bool { return s.User.ID == 0 })
+		if !open || c.ExpiredAt == 0 || c.ExpiredAt > deadline || a.expiring[c.ID] {
+			continue
+		}
+		a.expiring[c.ID] = true
+		crimes = append(crimes, c)
+	}
+	if len(crimes) == 0 {
+		return
+	}
+	items, err := loadItemHolders(itemsFile)
+	if err != nil {
+		slog.Error("read --items", "error", err)
+	}
+	var b strings.Builder
+	printOpenSlots(&b, buildOpenSlots(crimes, members, stats, items), members, items)
+	a.send(ctx, "Organized crimes expiring with open slots", strings.TrimSuffix(b.String(), "\n"))
+}
+
+// expiredCrimes announces crimes that expired since the previous check.
+func (a *alerter) expiredCrimes(ctx context.Context, client *torn.Client) {
+	if a == nil {
+		return
+	}
+	crimes, err := client.FetchCrimesPage("expired", "DESC", 0)
+	if err != nil {
+		slog.Error("fetch expired crimes", "error", err)
+		return
+	}
+
+	since := a.expiredSince
+	var lines []string
+	for _, c := range crimes {
+		if c.ExpiredAt <= since {
+			continue
+		}
+		if c.ExpiredAt > a.expiredSince {
+			a.expiredSince = c.ExpiredAt
+		}
+		lines = append(lines, fmt.Sprintf("%s (difficulty %d) expired at %s", c.Name, c.Difficulty, formatUnix(c.ExpiredAt)))
+	}
+	if len(lines) > 0 {
+		a.send(ctx, "Organized crimes expired", strings.Join(lines, "\n"))
+	}
+	if a.expiredSince != since && !a.dryRun {
+		if err := os.WriteFile(a.sincePath, []byte(strconv.FormatInt(a.expiredSince, 10)+"\n"), 0o600); err != nil {
+			slog.Warn("Failed to record the last expired crime announced", "path", a.sincePath, "error", err)
+		}
+	}
+}
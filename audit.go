@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+)
+
+var auditHeader = []interface{}{"Run At", "Trigger", "Label", "Status", "Exit Code", "Seconds", "Members", "Crimes", "Rows Written", "Errors"}
+
+// auditEntry is one run's line of the --audit-file.
+type auditEntry struct {
+	RunAt    string         `json:"run_at"`
+	Trigger  string         `json:"trigger"`
+	Label    string         `json:"label,omitempty"`
+	Status   string         `json:"status"`
+	ExitCode int            `json:"exit_code"`
+	Seconds  float64        `json:"seconds"`
+	Members  int            `json:"members"`
+	Crimes   int            `json:"crimes"`
+	Rows     map[string]int `json:"rows,omitempty"`
+	Errors   []string       `json:"errors,omitempty"`
+}
+
+func newAuditEntry(info *runInfo) auditEntry {
+	return auditEntry{
+		RunAt:    formatTime(info.StartedAt),
+		Trigger:  info.Trigger,
+		Label:    info.Label,
+		Status:   info.status(),
+		ExitCode: info.Code,
+		Seconds:  time.Since(info.StartedAt).Round(time.Millisecond).Seconds(),
+		Members:  info.Members,
+		Crimes:   info.Crimes,
+		Rows:     info.rows,
+		Errors:   info.redactedErrors(),
+	}
+}
+
+// appendAuditFile appends entry to the JSON lines file at path.
+func appendAuditFile(path string, entry auditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// buildAuditRow renders entry as a row of the audit tab.
+func buildAuditRow(entry auditEntry) []interface{} {
+	var rows []string
+	for _, dest := range slices.Sorted(maps.Keys(entry.Rows)) {
+		rows = append(rows, fmt.Sprintf("%s: %d", dest, entry.Rows[dest]))
+	}
+	return []interface{}{
+		entry.RunAt, entry.Trigger, entry.Label, entry.Status, entry.ExitCode, entry.Seconds,
+		entry.Members, entry.Crimes, strings.Join(rows, "\n"), strings.Join(entry.Errors, "\n"),
+	}
+}
+
+// appendAuditRow appends entry to the audit tab at targetRange, with a
+// header row first if the tab is empty.
+func appendAuditRow(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, entry auditEntry) error {
+	_, err := appendRows(ctx, client, spreadsheetID, targetRange, auditHeader, [][]interface{}{buildAuditRow(entry)})
+	return err
+}
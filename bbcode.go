@@ -0,0 +1,13 @@
+package main
+
+// CPR bands used to colour pass rates, set by --cpr-low and --cpr-high.
+var (
+	cprLow  = 50
+	cprHigh = 70
+)
+
+// renderBBCode renders the report as Torn forum BBCode, one table per member,
+// ready to paste into a faction forum thread.
+func renderBBCode(report Report) string {
+	return renderOptions().BBCode(report)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mnuck/torn-oc-history/internal/catalog"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// catalogFile is set by the --catalog flag: a crime catalog replacing the
+// embedded one, for crimes Torn added since this release.
+var catalogFile string
+
+const catalogUsage = "Crime catalog (names, difficulties and positions) to use instead of the built-in one, as a JSON file laid out like internal/catalog/crimes.json"
+
+// crimeCatalog is the crime catalog in use. loadCatalog replaces it between
+// runs.
+var crimeCatalog = catalog.Default()
+
+var (
+	catalogMu sync.Mutex
+	// catalogWarned are the differences from the catalog already logged
+	catalogWarned = make(map[string]bool)
+)
+
+// loadCatalog switches to the --catalog file, or back to the embedded
+// catalog without one.
+func loadCatalog() error {
+	c := catalog.Default()
+	if catalogFile != "" {
+		var err error
+		if c, err = catalog.Load(catalogFile); err != nil {
+			return err
+		}
+	}
+	crimeCatalog = c
+	return nil
+}
+
+// checkCatalog logs how crimes from the API differ from the catalog, each
+// difference once per process, as a sign that Torn changed the crimes and
+// the catalog needs updating.
+func checkCatalog(crimes ...torn.Crime) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	for _, c := range crimes {
+		for _, problem := range crimeCatalog.Check(c) {
+			if !catalogWarned[problem] {
+				catalogWarned[problem] = true
+				slog.Warn("Unexpected crime from the Torn API; update the crime catalog (--catalog)", "problem", problem, "crime_id", c.ID)
+			}
+		}
+	}
+}
+
+// neverAttempted lists the catalog's positions the member has no pass rate
+// at, at any difficulty.
+func neverAttempted(stats map[int]map[string]RateInfo) []string {
+	var never []string
+	for _, p := range crimeCatalog.Positions(0) {
+		if !attempted(stats, p) {
+			never = append(never, p)
+		}
+	}
+	return never
+}
+
+// attempted reports whether the member has a pass rate at position, in any
+// of its slots, at any difficulty.
+func attempted(stats map[int]map[string]RateInfo, position string) bool {
+	for _, positions := range stats {
+		for p := range positions {
+			if strings.EqualFold(catalog.Role(p), catalog.Role(position)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// catalogPositions are the positions of the catalog's crimes at difficulty
+// that the report's position filter keeps and that have no column among
+// known yet, under any slot's name.
+func catalogPositions(difficulty int, f reportFilter, known map[string]bool) []string {
+	return slices.DeleteFunc(crimeCatalog.Positions(difficulty), func(p string) bool {
+		if f.positions != nil && !f.positions[strings.ToLower(p)] {
+			return true
+		}
+		for k := range known {
+			if strings.EqualFold(catalog.Role(k), p) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// buildCrimesPerWeek counts executed crimes per week, starting Monday UTC.
+func buildCrimesPerWeek(crimes []torn.Crime) [][]interface{} {
+	counts := make(map[time.Time]int)
+	for _, c := range crimes {
+		if c.ExecutedAt == 0 {
+			continue
+		}
+		t := time.Unix(c.ExecutedAt, 0).UTC().Truncate(24 * time.Hour)
+		week := t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		counts[week]++
+	}
+	weeks := make([]time.Time, 0, len(counts))
+	for w := range counts {
+		weeks = append(weeks, w)
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].Before(weeks[j]) })
+
+	rows := [][]interface{}{{"Week", "Crimes"}}
+	for _, w := range weeks {
+		rows = append(rows, []interface{}{w.Format("2006-01-02"), counts[w]})
+	}
+	return rows
+}
+
+// writeCharts writes the crimes-per-week table to the charts tab and rebuilds
+// its charts: a line chart of that table and a CPR histogram of the first
+// report's CPR cells.
+func writeCharts(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, crimes []torn.Crime, blocks []cprBlock) error {
+	sheet := sheetspkg.SheetName(targetRange)
+	if sheet == "" {
+		return fmt.Errorf("range %q must name a sheet", targetRange)
+	}
+	rows := buildCrimesPerWeek(crimes)
+	if err := writeSheets(ctx, client, spreadsheetID, []sheetspkg.RangeValues{{Range: targetRange, Values: rows}}); err != nil {
+		return err
+	}
+	formatTable(ctx, client, spreadsheetID, targetRange, 2, 0, 0)
+
+	row, col := sheetspkg.StartCell(targetRange)
+	end := row + int64(len(rows))
+	charts := []sheetspkg.Chart{{
+		Title:     "Crimes per week",
+		Kind:      "line",
+		Domain:    sheetspkg.Block{Sheet: sheet, StartRow: row, EndRow: end, StartCol: col, EndCol: col + 1},
+		Series:    []sheetspkg.Block{{Sheet: sheet, StartRow: row, EndRow: end, StartCol: col + 1, EndCol: col + 2}},
+		XTitle:    "Week",
+		YTitle:    "Crimes",
+		AnchorRow: row,
+		AnchorCol: col + 3,
+	}}
+	if len(blocks) > 0 {
+		b := blocks[0]
+		hist := sheetspkg.Chart{
+			Title:      "CPR distribution - " + b.Label,
+			Kind:       "histogram",
+			BucketSize: cprValue(10),
+			AnchorRow:  row + 20,
+			AnchorCol:  col + 3,
+		}
+		for c := b.StartCol; c < b.EndCol; c++ {
+			hist.Series = append(hist.Series, sheetspkg.Block{Sheet: b.Sheet, StartRow: b.StartRow, StartCol: c, EndCol: c + 1})
+		}
+		charts = append(charts, hist)
+	}
+	return client.ReplaceCharts(ctx, spreadsheetID, sheet, charts)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/discord"
+	"github.com/mnuck/torn-oc-history/internal/server"
+	"github.com/mnuck/torn-oc-history/internal/store"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// chatMatches is how many members a /cpr query may match before the answer
+// asks for a narrower one instead of listing them all.
+const chatMatches = 3
+
+// discordCommands are the slash commands the bot registers.
+var discordCommands = []discord.Command{
+	{Name: "cpr", Description: "A member's latest CPR at every difficulty and position", Options: []discord.CommandOption{
+		{Type: 3, Name: "member", Description: "Name (or part of it) or ID", Required: true},
+	}},
+	{Name: "notinoc", Description: "The members not in an organized crime"},
+	{Name: "openslots", Description: "The open slots of recruiting and planning crimes, with their best candidates"},
+}
+
+// chatAnswers answers the chat commands from the latest snapshot in st: cpr
+// with a member's name or ID, notinoc and openslots.
+func chatAnswers(st *store.Store) server.Answerer {
+	return func(command, arg string) string {
+		snap := st.Snapshot()
+		if snap.FetchedAt.IsZero() {
+			return "No data fetched yet; try again after the first run."
+		}
+		switch command {
+		case "cpr":
+			return answerCPR(snap, arg)
+		case "notinoc":
+			return answerNotInOC(snap.Members)
+		case "openslots":
+			items, err := loadItemHolders(itemsFile)
+			if err != nil {
+				slog.Error("Failed to read --items", "error", err)
+			}
+			var b strings.Builder
+			printOpenSlots(&b, buildOpenSlots(snap.Active, snap.Members, buildStats(snap.Crimes), items), snap.Members, items)
+			return b.String()
+		}
+		return fmt.Sprintf("Unknown command %q.", command)
+	}
+}
+
+// answerCPR renders the report of the member query names: an ID, a whole
+// name, or else a part of names.
+func answerCPR(snap store.Snapshot, query string) string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return "Give a member's name or ID."
+	}
+	matches := make(map[int]torn.Member)
+	for _, m := range snap.Members {
+		if query == strconv.Itoa(m.ID) || query == strings.ToLower(m.Name) {
+			matches = map[int]torn.Member{m.ID: m}
+			break
+		}
+		if (reportFilter{members: []string{query}}).matchMember(m) {
+			matches[m.ID] = m
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return fmt.Sprintf("No member matches %q.", query)
+	case len(matches) > chatMatches:
+		var names []string
+		for _, m := range matches {
+			names = append(names, fmt.Sprintf("%s (%d)", m.Name, m.ID))
+		}
+		sort.Strings(names)
+		return fmt.Sprintf("%d members match %q: %s", len(names), query, strings.Join(names, ", "))
+	}
+	report := buildReport(matches, buildStats(snap.Crimes))
+	report.GeneratedAt = snap.FetchedAt
+	return strings.Join(generateReportLines(report), "\n")
+}
+
+// answerNotInOC lists the members not in an OC, by name.
+func answerNotInOC(members []torn.Member) string {
+	var lines []string
+	for _, m := range members {
+		if !m.IsInOC {
+			lines = append(lines, fmt.Sprintf("%s (%d) - last seen %s", m.Name, m.ID, m.LastAction.Relative))
+		}
+	}
+	if len(lines) == 0 {
+		return "Every member is in an OC."
+	}
+	sort.Slice(lines, func(i, j int) bool { return strings.ToLower(lines[i]) < strings.ToLower(lines[j]) })
+	return fmt.Sprintf("%d members not in an OC:\n%s", len(lines), strings.Join(lines, "\n"))
+}
+
+// setupSlack enables the Slack slash command when SLACK_SIGNING_SECRET is
+// set.
+func setupSlack(srv *server.Server, st *store.Store) {
+	if secret := os.Getenv("SLACK_SIGNING_SECRET"); secret != "" {
+		srv.EnableSlack(secret, chatAnswers(st))
+	}
+}
+
+// setupDiscordBot enables the slash commands when DISCORD_PUBLIC_KEY is set
+// and, with DISCORD_APPLICATION_ID and DISCORD_BOT_TOKEN, registers them, for
+// DISCORD_GUILD_ID only if set.
+func setupDiscordBot(ctx context.Context, srv *server.Server, st *store.Store, dryRun bool) error {
+	hexKey := os.Getenv("DISCORD_PUBLIC_KEY")
+	if hexKey == "" {
+		return nil
+	}
+	key, err := discord.ParsePublicKey(hexKey)
+	if err != nil {
+		return fmt.Errorf("DISCORD_PUBLIC_KEY: %w", err)
+	}
+	srv.EnableDiscord(key, chatAnswers(st))
+
+	appID, token := os.Getenv("DISCORD_APPLICATION_ID"), os.Getenv("DISCORD_BOT_TOKEN")
+	if appID == "" || token == "" || dryRun {
+		return nil
+	}
+	if err := discord.RegisterCommands(ctx, appID, os.Getenv("DISCORD_GUILD_ID"), token, discordCommands); err != nil {
+		// answering commands registered before still works
+		slog.Error("Failed to register the Discord slash commands", "error", err)
+		return nil
+	}
+	slog.Info("Registered the Discord slash commands", "commands", len(discordCommands))
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// staleAfter is the age past which a pass rate is dimmed in the coloured
+// stdout report, set by --stale-after.
+var staleAfter = 30 * 24 * time.Hour
+
+// ANSI escape sequences for the coloured stdout report.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// useColor resolves --color: "always", "never", or "auto", which colours only
+// when stdout is a terminal and NO_COLOR is unset.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func ansiCPR(rate int) string {
+	switch {
+	case rate >= cprHigh:
+		return ansiGreen
+	case rate >= cprLow:
+		return ansiYellow
+	default:
+		return ansiRed
+	}
+}
+
+// renderColor renders the report for a terminal: one aligned
+// difficulty/position/CPR/executed table per member, pass rates coloured by
+// the --cpr-low and --cpr-high bands, and rates older than --stale-after
+// dimmed.
+func renderColor(report Report) string {
+	posWidth := len("Position")
+	for _, mr := range report.Members {
+		for _, dr := range mr.Difficulties {
+			for _, pr := range dr.Positions {
+				posWidth = max(posWidth, len(pr.Position))
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Report generated at: %s\n", formatTime(report.GeneratedAt))
+	for _, mr := range report.Members {
+		m := mr.Member
+		fmt.Fprintf(&b, "\n%s%s%s (%d) - Last seen: %s (%s)\n", ansiBold, m.Name, ansiReset, m.ID, m.LastAction.Status, m.LastAction.Relative)
+		if len(mr.Difficulties) == 0 {
+			fmt.Fprintf(&b, "  %sNo historical OC participation recorded.%s\n", ansiDim, ansiReset)
+			continue
+		}
+
+		fmt.Fprintf(&b, "  %s%4s  %-*s  %4s  %s%s\n", ansiDim, "Diff", posWidth, "Position", "CPR", "Executed", ansiReset)
+		for _, dr := range mr.Difficulties {
+			for _, pr := range dr.Positions {
+				if pr.Rate == 0 {
+					fmt.Fprintf(&b, "  %s%4d  %-*s  %4s%s\n", ansiDim, dr.Difficulty, posWidth, pr.Position, "-", ansiReset)
+					continue
+				}
+				executed := time.Unix(pr.ExecutedAt, 0)
+				// pad before colouring so the escape codes don't count
+				rate := ansiCPR(pr.Rate) + fmt.Sprintf("%3d%%", pr.Rate) + ansiReset
+				line := fmt.Sprintf("%4d  %-*s  %s  %s", dr.Difficulty, posWidth, pr.Position, rate, formatTime(executed))
+				if staleAfter > 0 && report.GeneratedAt.Sub(executed) > staleAfter {
+					// re-enable dim after the rate's reset
+					line = ansiDim + strings.ReplaceAll(line, ansiReset, ansiReset+ansiDim) + ansiReset
+				}
+				b.WriteString("  " + line + "\n")
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/config"
+	"github.com/mnuck/torn-oc-history/internal/env"
+	"github.com/mnuck/torn-oc-history/internal/keyring"
+	"github.com/mnuck/torn-oc-history/internal/log"
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+)
+
+// command is a subcommand of the CLI. Its run function parses its own flags
+// from args and exits non-zero on failure.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string)
+}
+
+var commands []command
+
+func init() {
+	// assigned here so the help command can list the table it is part of
+	commands = []command{
+		{"init", "Set up interactively: check the API key, create the spreadsheet and write a config file", initCommand},
+		{"report", "Print the report to stdout or send it to Discord", reportCommand},
+		{"sync", "Write the report to Google Sheets", syncCommand},
+		{"export", "Export every member slot of the completed crimes as CSV or JSON", exportCommand},
+		{"plan", "List the open slots of active crimes with their expiry, members and best candidates", planCommand},
+		{"planner", "Same as plan", planCommand},
+		{"tui", "Browse members, filter by position and difficulty and drill into a member's history interactively", tuiCommand},
+		{"watch", "Check for newly completed crimes every minute and update the report only when there are some", watchCommand},
+		{"serve", "Serve the HTTP endpoints, refreshing the data periodically", serveCommand},
+		{"config", "Validate the config file, flags and secrets without calling any API (config validate)", configCommand},
+		{"doctor", "Check the Torn and Google configuration", doctorCommand},
+		{"healthcheck", "Exit 0 if the running daemon is healthy and 1 if not, by its /healthz or --audit-file; for Docker HEALTHCHECK", healthcheckCommand},
+		{"login", "Sign in with a Google account for Sheets access", loginCommand},
+		{"version", "Print the version and commit this binary was built from", versionCommand},
+		{"keyring", "Store or remove a secret such as TORN_API_KEY in the OS keyring", keyringCommand},
+		{"help", "Show this list", helpCommand},
+	}
+}
+
+func main() {
+	defer reportPanic()
+	setupEnvironment()
+	ctx := context.Background()
+
+	if len(os.Args) > 1 {
+		for _, c := range commands {
+			if c.name == os.Args[1] {
+				c.run(ctx, os.Args[2:])
+				return
+			}
+		}
+	}
+	legacyCommand(ctx, os.Args[1:])
+}
+
+// newFlagSet creates the flag set of a subcommand.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n", os.Args[0], name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args into fs after applying the config file and then the
+// TORN_OC_* environment variables, so flags given on the command line override
+// both. The file's env section is applied too.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.String("config", "", "YAML config file with env and flags sections (default "+config.DefaultFile+" if it exists, or TORN_OC_CONFIG)")
+	fs.String("profile", "", "Use the settings of this profile of the config file (or TORN_OC_PROFILE)")
+	logLevel := fs.String("log-level", getEnvWithDefault("LOGLEVEL", "info"), "Log verbosity: debug, info, warn or error")
+	logFormat := fs.String("log-format", log.DefaultFormat(), "Log format: console for people or json for log collectors")
+	logFile := fs.String("log-file", "", "Write the logs to this file instead of stderr, rotating it by --log-max-size and --log-max-age")
+	logMaxSize := fs.Int("log-max-size", 10, "Rotate the --log-file once it reaches this many megabytes; 0 is no limit")
+	logMaxAge := fs.Duration("log-max-age", 24*time.Hour, "Rotate the --log-file once it has been written to for this long; 0 is no limit")
+	logMaxFiles := fs.Int("log-max-files", 7, "Keep this many rotated copies of the --log-file, deleting older ones; 0 keeps them all")
+	zone := fs.String("timezone", "local", "Time zone of displayed timestamps: local, UTC, TCT (Torn City Time, the same as UTC) or an IANA name such as Europe/London")
+	fs.BoolVar(&quiet, "quiet", quiet, "Only log warnings and errors and, instead of the report body, print one summary line per run; for cron")
+	fs.IntVar(&maxPages, "max-pages", maxPages, "Fetch at most this many pages of 100 crimes per category, newest first; 0 fetches every page")
+	fs.IntVar(&maxCrimes, "max-crimes", maxCrimes, "Fetch at most this many crimes per category, newest first; 0 is no limit")
+	format := fs.String("date-format", "rfc3339", "Format of displayed timestamps: rfc3339, datetime, date or a Go layout such as \"02 Jan 2006 15:04\"")
+	fs.StringVar(&runLabel, "label", runLabel, "Tag this run's outputs (About tab, history log, JSON, served snapshot) with a label such as post-war-week, to tell ad-hoc runs from scheduled ones")
+	useKeyring := fs.Bool("keyring", false, "Read secrets such as TORN_API_KEY that aren't in the environment from the OS keyring (see the keyring command)")
+	if err := applyConfig(fs, args); err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	if err := resolveSecrets(*useKeyring); err != nil {
+		slog.Error("Failed to read secrets", "error", err)
+		os.Exit(1)
+	}
+	reloadFlags = func() (func(), error) { return reapplyFlags(fs, args) }
+
+	if quiet && !flagSet(fs, "log-level") && os.Getenv("LOGLEVEL") == "" {
+		*logLevel = "warn"
+	}
+	if *logFile != "" {
+		f, err := log.OpenFile(*logFile, int64(*logMaxSize)<<20, *logMaxAge, *logMaxFiles)
+		if err != nil {
+			slog.Error("Failed to open the log file", "error", err)
+			os.Exit(1)
+		}
+		log.SetOutput(f)
+	}
+	if err := log.Configure(*logLevel, *logFormat); err != nil {
+		slog.Error("Invalid logging flags", "error", err)
+		os.Exit(1)
+	}
+	var err error
+	if timeZone, err = parseTimeZone(*zone); err != nil {
+		slog.Error("--timezone", "error", err)
+		os.Exit(1)
+	}
+	if dateFormat, err = parseDateFormat(*format); err != nil {
+		slog.Error("--date-format", "error", err)
+		os.Exit(1)
+	}
+}
+
+// applyConfig sets the flags of fs from the config file, then from the
+// TORN_OC_* environment variables and finally from args. The file's env
+// section is applied too.
+func applyConfig(fs *flag.FlagSet, args []string) error {
+	path, profile := config.PathFromArgs(args), config.ProfileFromArgs(args)
+	if profile != "" && path == "" {
+		return errors.New("--profile needs a config file")
+	}
+	configPath = path
+	if path != "" {
+		cfg, err := config.Load(path)
+		if err != nil {
+			return fmt.Errorf("load config file: %w", err)
+		}
+		if profile != "" {
+			if cfg, err = cfg.Profile(profile); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			// keep what one profile saves, like a created spreadsheet, away from the others
+			envFile = ".env." + profile
+			if err := env.Load(envFile); err == nil {
+				slog.Debug("Loaded environment variables", "file", envFile)
+			}
+		}
+		cfg.ApplyEnv()
+		if err := cfg.ApplyFlags(fs); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		slog.Debug("Loaded config file", "path", path)
+	}
+	if err := config.ApplyEnvFlags(fs); err != nil {
+		return fmt.Errorf("flag environment variable: %w", err)
+	}
+	return fs.Parse(args)
+}
+
+// reloadFlags re-reads the config file into the flags parsed by parseFlags,
+// for SIGHUP. It returns a function putting the previous values back.
+var reloadFlags func() (restore func(), err error)
+
+// reapplyFlags resets every flag of fs to its default and applies the config
+// file, environment and args again, so settings removed from the file revert.
+// On failure the previous values are put back.
+func reapplyFlags(fs *flag.FlagSet, args []string) (func(), error) {
+	prev := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		prev[f.Name] = f.Value.String()
+		fs.Set(f.Name, f.DefValue)
+	})
+	restore := func() {
+		for name, v := range prev {
+			fs.Set(name, v)
+		}
+	}
+	if err := applyConfig(fs, args); err != nil {
+		restore()
+		return nil, err
+	}
+	return restore, nil
+}
+
+// flagSet reports whether the flag name was set, on the command line or from
+// the config file or environment.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// printCommands lists the subcommands.
+func printCommands() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(out, "\nRun %s <command> -h for the flags of a command.\n", os.Args[0])
+}
+
+// legacyCommand keeps the original command line working: every flag in one
+// set, with --output choosing the destination.
+func legacyCommand(ctx context.Context, args []string) {
+	o := defaultOptions()
+	fs := flag.CommandLine
+	fs.Usage = func() {
+		printCommands()
+		fmt.Fprintf(fs.Output(), "Without a command, all of these flags are accepted:\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&o.Output, "output", o.Output, "output destination: stdout, sheets or discord")
+	o.printFlags(fs)
+	o.selectionFlags(fs)
+	o.sheetsFlags(fs)
+	o.scheduleFlags(fs)
+	o.serverFlags(fs)
+	o.dryRunFlag(fs)
+	login := fs.Bool("login", false, "Sign in with a Google account via the browser, save the refresh token and exit")
+	parseFlags(fs, args)
+
+	if *login {
+		loginCommand(ctx, nil)
+		return
+	}
+	runApp(ctx, o)
+}
+
+func reportCommand(ctx context.Context, args []string) {
+	o := defaultOptions()
+	fs := newFlagSet("report")
+	fs.StringVar(&o.Output, "output", o.Output, "output destination: stdout or discord")
+	o.printFlags(fs)
+	o.selectionFlags(fs)
+	o.scheduleFlags(fs)
+	o.dryRunFlag(fs)
+	parseFlags(fs, args)
+
+	if o.Output == "sheets" {
+		slog.Error("use the sync command to write to Google Sheets")
+		os.Exit(1)
+	}
+	runApp(ctx, o)
+}
+
+func syncCommand(ctx context.Context, args []string) {
+	o := defaultOptions()
+	o.Output = "sheets"
+	fs := newFlagSet("sync")
+	o.selectionFlags(fs)
+	o.sheetsFlags(fs)
+	o.scheduleFlags(fs)
+	o.dryRunFlag(fs)
+	parseFlags(fs, args)
+	runApp(ctx, o)
+}
+
+func watchCommand(ctx context.Context, args []string) {
+	o := defaultOptions()
+	o.Output = "sheets"
+	o.Watch = time.Minute
+	fs := newFlagSet("watch")
+	fs.StringVar(&o.Output, "output", o.Output, "output destination: sheets, stdout or discord")
+	o.printFlags(fs)
+	o.selectionFlags(fs)
+	o.sheetsFlags(fs)
+	o.scheduleFlags(fs)
+	o.dryRunFlag(fs)
+	parseFlags(fs, args)
+
+	if o.Watch <= 0 {
+		slog.Error("--watch must be positive")
+		os.Exit(1)
+	}
+	runApp(ctx, o)
+}
+
+func serveCommand(ctx context.Context, args []string) {
+	o := defaultOptions()
+	o.Output = "none"
+	o.Listen = ":8080"
+	o.Interval = 5 * time.Minute
+	fs := newFlagSet("serve")
+	o.serverFlags(fs)
+	o.scheduleFlags(fs)
+	o.dryRunFlag(fs)
+	parseFlags(fs, args)
+
+	// --schedule, --daily-at and --watch replace the default refresh interval, on
+	// reloads too
+	reapply := reloadFlags
+	reloadFlags = func() (func(), error) {
+		restore, err := reapply()
+		if err == nil && (o.Schedule != "" || o.DailyAt != "" || o.Watch > 0) {
+			o.Interval = 0
+		}
+		return restore, err
+	}
+	if o.Schedule != "" || o.DailyAt != "" || o.Watch > 0 {
+		o.Interval = 0
+	}
+
+	if o.Listen == "" {
+		slog.Error("--listen must not be empty")
+		os.Exit(1)
+	}
+	runApp(ctx, o)
+}
+
+func exportCommand(ctx context.Context, args []string) {
+	fs := newFlagSet("export")
+	format := fs.String("format", "csv", "export format: csv or json")
+	out := fs.String("out", "", "File to write to instead of stdout")
+	dryRun := fs.Bool("dry-run", false, "With --out, report how many records would be written instead of writing the file")
+	parseFlags(fs, args)
+
+	if *format != "csv" && *format != "json" {
+		slog.Error("--format must be either 'csv' or 'json'")
+		os.Exit(1)
+	}
+
+	tornClient := newTornClient(getRequiredEnv("TORN_API_KEY"))
+	members, err := tornClient.FetchMembers()
+	if err != nil {
+		slog.Error("fetch members", "error", err)
+		os.Exit(errorExitCode(err))
+	}
+	crimes, err := tornClient.FetchAllCrimes()
+	if err != nil {
+		slog.Error("fetch crimes", "error", err)
+		os.Exit(errorExitCode(err))
+	}
+
+	records := exportRecords(observations(crimes, members))
+	if *dryRun && *out != "" {
+		fmt.Printf("[dry run] write %d records as %s to %s\n", len(records), *format, *out)
+		return
+	}
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			slog.Error("create export file", "error", err)
+			os.Exit(1)
+		}
+		defer w.Close()
+	}
+	if err := writeExport(w, *format, records); err != nil {
+		slog.Error("write export", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Exported crime slots", "records", len(records))
+}
+
+func planCommand(ctx context.Context, args []string) {
+	fs := newFlagSet("plan")
+	fs.IntVar(&cprLow, "cpr-low", cprLow, "Only offer members whose CPR at the slot's difficulty and position is at least this")
+	fs.StringVar(&itemsFile, "items", itemsFile, itemsUsage)
+	parseFlags(fs, args)
+	items, err := loadItemHolders(itemsFile)
+	if err != nil {
+		slog.Error("read --items", "error", err)
+		os.Exit(1)
+	}
+
+	tornClient := newTornClient(getRequiredEnv("TORN_API_KEY"))
+	members, err := tornClient.FetchMembers()
+	if err != nil {
+		slog.Error("fetch members", "error", err)
+		os.Exit(errorExitCode(err))
+	}
+	crimes, err := tornClient.FetchAllCrimes()
+	if err != nil {
+		slog.Error("fetch crimes", "error", err)
+		os.Exit(errorExitCode(err))
+	}
+	active, err := tornClient.FetchActiveCrimes()
+	if err != nil {
+		slog.Error("fetch active crimes", "error", err)
+		os.Exit(errorExitCode(err))
+	}
+	printOpenSlots(os.Stdout, buildOpenSlots(active, members, buildStats(crimes), items), members, items)
+}
+
+func loginCommand(ctx context.Context, args []string) {
+	fs := newFlagSet("login")
+	parseFlags(fs, args)
+
+	clientFile, tokenFile := sheetspkg.OAuthFilesFromEnv()
+	if err := sheetspkg.Login(ctx, clientFile, tokenFile); err != nil {
+		slog.Error("Google sign-in failed", "error", err)
+		os.Exit(1)
+	}
+	fmt.Println("Saved Google credentials to", tokenFile)
+}
+
+func keyringCommand(ctx context.Context, args []string) {
+	fs := newFlagSet("keyring")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s keyring set|delete NAME\n\nset reads the secret from stdin. NAME is one of %s.\n", os.Args[0], strings.Join(secretEnv, ", "))
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 || !slices.Contains(secretEnv, fs.Arg(1)) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	action, name := fs.Arg(0), fs.Arg(1)
+
+	var err error
+	switch action {
+	case "set":
+		fmt.Fprintf(os.Stderr, "%s: ", name)
+		var secret string
+		secret, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if secret = strings.TrimRight(secret, "\r\n"); secret == "" {
+			slog.Error("No secret given")
+			os.Exit(1)
+		}
+		err = keyring.Set(name, secret)
+	case "delete":
+		err = keyring.Delete(name)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error("Keyring "+action+" failed", "name", name, "error", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Done. Run with --keyring (or TORN_OC_KEYRING=true) to use it.\n")
+}
+
+func helpCommand(ctx context.Context, args []string) {
+	flag.CommandLine.SetOutput(os.Stdout)
+	printCommands()
+}
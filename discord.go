@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/discord"
+)
+
+const topGainers = 10
+
+// Embed colours.
+const (
+	colorInfo    = 0x5865f2
+	colorGood    = 0x2e7d32
+	colorWarning = 0xc62828
+)
+
+// discordMessages renders the report as Discord messages, either plain
+// code-block messages or rich embeds.
+func discordMessages(mode, title string, report Report, sheetURL string) []discord.Message {
+	if mode == "embed" {
+		return discord.SplitEmbeds(buildEmbeds(title, report, sheetURL))
+	}
+	var msgs []discord.Message
+	lines := append([]string{title}, generateReportLines(report)...)
+	for _, chunk := range discord.SplitContent(lines) {
+		msgs = append(msgs, discord.Message{Content: chunk})
+	}
+	return msgs
+}
+
+// previewDiscord prints the messages sendDiscord would post.
+func previewDiscord(w io.Writer, msgs []discord.Message) {
+	for i, msg := range msgs {
+		fmt.Fprintf(w, "[dry run] Discord message %d/%d\n", i+1, len(msgs))
+		if msg.Content != "" {
+			fmt.Fprintln(w, msg.Content)
+		}
+		for _, e := range msg.Embeds {
+			fmt.Fprintf(w, "  embed: %s\n", e.Title)
+			for _, f := range e.Fields {
+				fmt.Fprintf(w, "    %s: %s\n", f.Name, strings.ReplaceAll(f.Value, "\n", "; "))
+			}
+		}
+	}
+}
+
+// sendDiscord posts the report to the webhook, either as plain code-block
+// messages or as rich embeds.
+func sendDiscord(ctx context.Context, hook *discord.Webhook, mode, title string, report Report, sheetURL string) (int, error) {
+	msgs := discordMessages(mode, title, report, sheetURL)
+	for i, msg := range msgs {
+		if err := hook.Send(ctx, msg); err != nil {
+			return i, err
+		}
+	}
+	return len(msgs), nil
+}
+
+// buildEmbeds builds one embed per section: an overview (linking to the
+// spreadsheet when configured), top CPR gainers, low-CPR warnings and members
+// without any OC history.
+func buildEmbeds(title string, report Report, sheetURL string) []discord.Embed {
+	type gain struct {
+		name, detail string
+		delta        int
+	}
+	var gains []gain
+	var warnings []discord.Field
+	var noHistory []string
+
+	for _, mr := range report.Members {
+		if len(mr.Difficulties) == 0 {
+			noHistory = append(noHistory, mr.Member.Name)
+			continue
+		}
+		var low []string
+		for _, dr := range mr.Difficulties {
+			for _, pr := range dr.Positions {
+				if pr.Rate == 0 {
+					continue
+				}
+				if pr.Rate < cprLow {
+					low = append(low, fmt.Sprintf("%s D%d: %d%%", pr.Position, dr.Difficulty, pr.Rate))
+				}
+				if pr.PrevExecutedAt > 0 && pr.Rate > pr.PrevRate {
+					gains = append(gains, gain{
+						name:   mr.Member.Name,
+						detail: fmt.Sprintf("%s D%d: %d%% → %d%%", pr.Position, dr.Difficulty, pr.PrevRate, pr.Rate),
+						delta:  pr.Rate - pr.PrevRate,
+					})
+				}
+			}
+		}
+		if len(low) > 0 {
+			warnings = append(warnings, discord.Field{Name: mr.Member.Name, Value: strings.Join(low, "\n"), Inline: true})
+		}
+	}
+
+	sort.SliceStable(gains, func(i, j int) bool { return gains[i].delta > gains[j].delta })
+	if len(gains) > topGainers {
+		gains = gains[:topGainers]
+	}
+
+	overview := discord.Embed{
+		Title:     title,
+		URL:       sheetURL,
+		Color:     colorInfo,
+		Timestamp: report.GeneratedAt.UTC().Format(time.RFC3339),
+		Description: fmt.Sprintf("%d members, %d with low CPR, %d without OC history",
+			len(report.Members), len(warnings), len(noHistory)),
+	}
+	if sheetURL != "" {
+		overview.Description += fmt.Sprintf("\n[Open spreadsheet](%s)", sheetURL)
+	}
+	embeds := []discord.Embed{overview}
+
+	if len(gains) > 0 {
+		e := discord.Embed{Title: "Top gainers", Color: colorGood}
+		for _, g := range gains {
+			e.Fields = append(e.Fields, discord.Field{Name: fmt.Sprintf("%s (+%d)", g.name, g.delta), Value: g.detail})
+		}
+		embeds = append(embeds, e)
+	}
+	if len(warnings) > 0 {
+		embeds = append(embeds, discord.Embed{Title: fmt.Sprintf("Low CPR (below %d%%)", cprLow), Color: colorWarning, Fields: warnings})
+	}
+	if len(noHistory) > 0 {
+		embeds = append(embeds, discord.Embed{Title: "No OC history", Color: colorWarning, Description: strings.Join(noHistory, ", ")})
+	}
+	return embeds
+}
+
+func spreadsheetURL(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "https://docs.google.com/spreadsheets/d/" + id
+}
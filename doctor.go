@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+)
+
+// checklist prints doctor results as they come in and remembers whether any
+// check failed. Warnings are setups that work but may not be what was meant.
+type checklist struct {
+	failed bool
+}
+
+func (c *checklist) check(name string, err error) bool {
+	if err != nil {
+		c.failed = true
+		fmt.Printf("FAIL  %s: %v\n", name, err)
+		return false
+	}
+	fmt.Printf("ok    %s\n", name)
+	return true
+}
+
+func (c *checklist) warn(name, msg string) {
+	fmt.Printf("warn  %s: %s\n", name, msg)
+}
+
+func doctorCommand(ctx context.Context, args []string) {
+	o := defaultOptions()
+	fs := newFlagSet("doctor")
+	// the ranges to check for, as set in the config file or environment
+	o.sheetsFlags(fs)
+	fs.StringVar(&catalogFile, "catalog", catalogFile, catalogUsage)
+	parseFlags(fs, args)
+
+	var c checklist
+	if catalogFile != "" {
+		c.check("Crime catalog "+catalogFile, loadCatalog())
+	}
+	checkTorn(&c)
+
+	opts, source, err := sheetspkg.CredentialsFromEnv("credentials.json")
+	var client *sheetspkg.Client
+	if err == nil {
+		client, err = sheetspkg.NewClient(ctx, opts...)
+	}
+	if c.check("Google credentials ("+source+")", err) {
+		checkSpreadsheets(ctx, &c, client, o)
+	}
+
+	if c.failed {
+		os.Exit(1)
+	}
+}
+
+// checkTorn checks that the API key is valid and can read the faction's
+// members and crimes, and that the newest crimes are as the crime catalog
+// expects.
+func checkTorn(c *checklist) {
+	key := os.Getenv("TORN_API_KEY")
+	if key == "" {
+		c.check("Torn API key", errors.New("TORN_API_KEY is not set"))
+		return
+	}
+	client := newTornClient(key)
+	faction, err := client.FetchFactionBasic()
+	name := "Torn API key"
+	if err == nil {
+		name = fmt.Sprintf("Torn API key (faction %s [%s])", faction.Name, faction.Tag)
+	}
+	if !c.check(name, err) {
+		return
+	}
+	members, err := client.FetchMembers()
+	c.check(fmt.Sprintf("Faction members (%d)", len(members)), err)
+	page, err := client.FetchCrimesPage("completed", "", 0)
+	if err != nil {
+		err = fmt.Errorf("%w (the key needs Limited access or above and the faction API access permission)", err)
+	}
+	if !c.check("Faction crimes", err) {
+		return
+	}
+	missing, err := client.CheckCrimesSchema()
+	if err == nil && len(missing) > 0 {
+		err = fmt.Errorf("missing %s; Torn changed the payload, so update %s", strings.Join(missing, ", "), versionString())
+	}
+	c.check("Torn crimes schema", err)
+	var problems []string
+	for _, crime := range page {
+		for _, p := range crimeCatalog.Check(crime) {
+			if !slices.Contains(problems, p) {
+				problems = append(problems, p)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		c.warn("Crime catalog", strings.Join(problems, "; ")+"; update it with --catalog")
+	} else {
+		c.check("Crime catalog", nil)
+	}
+}
+
+// checkSpreadsheets checks that each configured spreadsheet can be read and
+// written, and whether the tabs and named ranges the range flags point at
+// exist yet.
+func checkSpreadsheets(ctx context.Context, c *checklist, client *sheetspkg.Client, o *options) {
+	spreadsheetID := os.Getenv("SPREADSHEET_ID")
+	nocSpreadsheet := getEnvWithDefault("SPREADSHEET_ID_NOC", spreadsheetID)
+	allSpreadsheet := getEnvWithDefault("SPREADSHEET_ID_ALL", spreadsheetID)
+	targets := []string{o.NocRange, o.AllRange, o.LogRange, o.SummaryRange, o.ChartsRange, o.PlannerRange, o.RawRange, o.AboutRange, o.AuditRange}
+	targetSpreadsheets := []string{nocSpreadsheet, allSpreadsheet, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID}
+
+	var ids []string
+	byID := make(map[string][]string)
+	unset := false
+	for i, t := range targets {
+		if t == "" {
+			continue
+		}
+		id := targetSpreadsheets[i]
+		if id == "" {
+			unset = true
+			continue
+		}
+		if _, ok := byID[id]; !ok {
+			ids = append(ids, id)
+		}
+		byID[id] = append(byID[id], t)
+	}
+
+	if unset {
+		c.warn("Spreadsheet", "SPREADSHEET_ID is not set; sync will create a spreadsheet")
+	}
+
+	for _, id := range ids {
+		titles, err := client.SheetTitles(ctx, id)
+		if !c.check("Spreadsheet "+id+" access", err) {
+			continue
+		}
+		canEdit, err := client.CanEdit(ctx, id)
+		if err == nil && !canEdit {
+			err = errors.New("the account can only view it; share it with the account as an editor")
+		}
+		c.check("Spreadsheet "+id+" write permission", err)
+
+		tabs := make(map[string]bool, len(titles))
+		for _, t := range titles {
+			tabs[t] = true
+		}
+		var named map[string]string
+		seen := make(map[string]bool)
+		for _, t := range byID[id] {
+			name, fallback, ok := parseNamedTarget(t)
+			if ok {
+				if named == nil {
+					if named, err = client.NamedRanges(ctx, id); !c.check("Named ranges of "+id, err) {
+						break
+					}
+				}
+				if r, found := named[name]; found {
+					c.check("Named range "+name+" ("+r+")", nil)
+					continue
+				}
+				c.warn("Named range "+name, "missing; sync will create it at "+fallback)
+				t = fallback
+			}
+			sheet := sheetspkg.SheetName(t)
+			if sheet == "" || seen[sheet] {
+				// a range without a sheet is on the first tab
+				continue
+			}
+			seen[sheet] = true
+			if tabs[sheet] {
+				c.check("Tab "+sheet, nil)
+			} else {
+				c.warn("Tab "+sheet, "missing; sync will create it")
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/errreport"
+)
+
+// reportErrorsAfter is how many runs in a row must fail before the errors
+// are reported to SENTRY_DSN or ERROR_REPORT_WEBHOOK_URL, so a passing Torn
+// or Google outage doesn't page the maintainer.
+const reportErrorsAfter = 3
+
+// errorReportTimeout bounds the sending of an error report, which for a
+// panic holds up the crash.
+const errorReportTimeout = 10 * time.Second
+
+// reportPanic, deferred in main and in the goroutines of a run, reports a
+// panic along with its stack and then panics again, so the process still
+// crashes as it would have.
+func reportPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	reportError(errreport.Event{Level: "fatal", Message: redact(fmt.Sprint("panic: ", r)), Stack: string(debug.Stack())})
+	panic(r)
+}
+
+// reportError sends e to the error reporters configured in the environment,
+// if any.
+func reportError(e errreport.Event) {
+	reporters, err := errreport.FromEnv(version)
+	if err != nil {
+		slog.Error("Failed to set up error reporting", "error", err)
+		return
+	}
+	if len(reporters) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), errorReportTimeout)
+	defer cancel()
+	if err := reporters.Report(ctx, e); err != nil {
+		slog.Error("send error report", "error", err)
+	}
+}
+
+// errorStreak reports the errors of runs that keep failing, once when the
+// reportErrorsAfter-th run in a row fails. A run skipped for the lock counts
+// as nothing.
+type errorStreak struct {
+	failures int
+}
+
+func (s *errorStreak) runDone(info *runInfo) {
+	switch info.Code {
+	case exitLocked:
+	case exitOK, exitPartial:
+		s.failures = 0
+	default:
+		s.failures++
+		if s.failures != reportErrorsAfter {
+			return
+		}
+		tags := map[string]string{"trigger": info.Trigger, "exit_code": strconv.Itoa(info.Code)}
+		if info.Label != "" {
+			tags["label"] = info.Label
+		}
+		reportError(errreport.Event{
+			Level:   "error",
+			Message: fmt.Sprintf("%d runs in a row failed. Last error: %s", s.failures, info.failure()),
+			Tags:    tags,
+		})
+	}
+}
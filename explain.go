@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// explainMember prints, for each difficulty and position of the member's
+// report, the crime and slot its pass rate was taken from and every older
+// observation it superseded, for settling "my CPR is wrong" disputes. It ends
+// with the positions of the crime catalog the member has never attempted.
+func explainMember(w io.Writer, id int, members []torn.Member, crimes []torn.Crime) {
+	name := "not in the faction"
+	for _, m := range members {
+		if m.ID == id {
+			name = m.Name
+		}
+	}
+	fmt.Fprintf(w, "CPR provenance for member %d (%s), from %d completed crimes\n", id, name, len(crimes))
+
+	type cell struct {
+		difficulty int
+		position   string
+	}
+	// newest first, so the first observation of a cell is normally the one used
+	byCell := make(map[cell][]observation)
+	for _, ob := range observations(crimes, members) {
+		if ob.Member.ID != id {
+			continue
+		}
+		c := cell{ob.Crime.Difficulty, ob.Slot.Position}
+		byCell[c] = append(byCell[c], ob)
+	}
+	if len(byCell) == 0 {
+		fmt.Fprintln(w, "  No historical OC participation recorded.")
+		return
+	}
+
+	cells := make([]cell, 0, len(byCell))
+	for c := range byCell {
+		cells = append(cells, c)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].difficulty != cells[j].difficulty {
+			return cells[i].difficulty < cells[j].difficulty
+		}
+		return cells[i].position < cells[j].position
+	})
+
+	stats := buildStats(crimes)[id]
+	for _, c := range cells {
+		used := stats[c.difficulty][c.position]
+		fmt.Fprintf(w, "\nDifficulty %d, %s: %s\n", c.difficulty, c.position, rateText(used.Rate))
+		for _, ob := range byCell[c] {
+			crime := fmt.Sprintf("crime #%d %s, slot %s, %s, executed %s",
+				ob.Crime.ID, ob.Crime.Name, ob.Slot.Position, rateText(ob.Slot.CheckpointPassRate), formatUnix(ob.Crime.ExecutedAt))
+			switch {
+			case ob.Crime.ID == used.CrimeID:
+				fmt.Fprintf(w, "  reported:   %s\n", crime)
+			case ob.Crime.ExecutedAt == used.ExecutedAt:
+				// buildStats keeps the first of several crimes executed at once
+				fmt.Fprintf(w, "  tied, not used: %s\n", crime)
+			default:
+				fmt.Fprintf(w, "  superseded: %s\n", crime)
+			}
+		}
+	}
+	if never := neverAttempted(stats); len(never) > 0 {
+		fmt.Fprintf(w, "\nNever attempted: %s\n", strings.Join(never, ", "))
+	}
+}
+
+// rateText renders a pass rate as the reports do, "-" for none.
+func rateText(rate int) string {
+	if rate == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", rate)
+}
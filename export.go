@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exportRecord is one observation as written by the export command.
+type exportRecord struct {
+	ExecutedAt time.Time `json:"executed_at"`
+	CrimeID    int       `json:"crime_id"`
+	Crime      string    `json:"crime"`
+	Difficulty int       `json:"difficulty"`
+	MemberID   int       `json:"member_id"`
+	Member     string    `json:"member,omitempty"`
+	InOC       *bool     `json:"in_oc,omitempty"` // unknown for members who have left
+	Position   string    `json:"position"`
+	CPR        int       `json:"cpr"`
+	Outcome    string    `json:"outcome,omitempty"`
+	Label      string    `json:"label,omitempty"` // --label; JSON only
+}
+
+func exportRecords(obs []observation) []exportRecord {
+	records := make([]exportRecord, 0, len(obs))
+	for _, ob := range obs {
+		r := exportRecord{
+			ExecutedAt: time.Unix(ob.Crime.ExecutedAt, 0).In(timeZone),
+			CrimeID:    ob.Crime.ID,
+			Crime:      ob.Crime.Name,
+			Difficulty: ob.Crime.Difficulty,
+			MemberID:   ob.Member.ID,
+			Member:     ob.Member.Name,
+			Position:   ob.Slot.Position,
+			CPR:        ob.Slot.CheckpointPassRate,
+			Outcome:    ob.Slot.User.Outcome,
+			Label:      runLabel,
+		}
+		if ob.InFaction {
+			inOC := ob.Member.IsInOC
+			r.InOC = &inOC
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// writeExport writes records as CSV with a header row, or as a JSON array.
+func writeExport(w io.Writer, format string, records []exportRecord) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"executed_at", "crime_id", "crime", "difficulty", "member_id", "member", "in_oc", "position", "cpr", "outcome"})
+		for _, r := range records {
+			inOC := ""
+			if r.InOC != nil {
+				inOC = strconv.FormatBool(*r.InOC)
+			}
+			cw.Write([]string{
+				formatTime(r.ExecutedAt), strconv.Itoa(r.CrimeID), r.Crime, strconv.Itoa(r.Difficulty),
+				strconv.Itoa(r.MemberID), r.Member, inOC, r.Position, strconv.Itoa(r.CPR), r.Outcome,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
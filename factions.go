@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/mnuck/torn-oc-history/internal/config"
+	"github.com/mnuck/torn-oc-history/internal/server"
+	"github.com/mnuck/torn-oc-history/internal/store"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// factionName is what a --factions profile may be called, as it becomes part
+// of the URLs.
+var factionName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// faction is a config profile of --factions, served under /f/<name>/ next to
+// the faction of the process's own runs.
+type faction struct {
+	name            string
+	key             string
+	label           string
+	cprLow, cprHigh int
+	store           *store.Store
+}
+
+// loadFactions reads the --factions profiles from the config file: each
+// needs its own TORN_API_KEY, and may set its label and CPR bands.
+func loadFactions(names []string) ([]*faction, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if configPath == "" {
+		return nil, errors.New("--factions needs a config file with their profiles")
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	var factions []*faction
+	for _, name := range names {
+		if !factionName.MatchString(name) {
+			return nil, fmt.Errorf("faction %q: profiles served with --factions must be named with lower-case letters, digits, - and _", name)
+		}
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("faction %q: no such profile in %s", name, configPath)
+		}
+		f := &faction{name: name, key: p.Env["TORN_API_KEY"], cprLow: cprLow, cprHigh: cprHigh, store: store.New()}
+		if f.key == "" {
+			return nil, fmt.Errorf("faction %q: the profile has no TORN_API_KEY of its own", name)
+		}
+		if label, ok := p.Flags["label"]; ok {
+			f.label = fmt.Sprint(label)
+		}
+		for flag, band := range map[string]*int{"cpr-low": &f.cprLow, "cpr-high": &f.cprHigh} {
+			if v, ok := p.Flags[flag]; ok {
+				if *band, err = strconv.Atoi(fmt.Sprint(v)); err != nil {
+					return nil, fmt.Errorf("faction %q: %s: %w", name, flag, err)
+				}
+			}
+		}
+		factions = append(factions, f)
+	}
+	return factions, nil
+}
+
+// serveFactions adds the factions to srv.
+func serveFactions(srv *server.Server, factions []*faction, o *options) {
+	for _, f := range factions {
+		f.store.SetMaxAge(o.healthMaxAge())
+		srv.AddFaction(f.name, f.store)
+	}
+}
+
+// refresh fetches the faction's members and crimes into its store.
+func (f *faction) refresh() error {
+	c := torn.NewClient(f.key)
+	c.MaxPages, c.MaxCrimes = maxPages, maxCrimes
+	members, err := c.FetchMembers()
+	if err != nil {
+		return fmt.Errorf("fetch members: %w", err)
+	}
+	crimes, err := c.FetchAllCrimes()
+	if err != nil {
+		return fmt.Errorf("fetch crimes: %w", err)
+	}
+	active, err := c.FetchActiveCrimes()
+	if err != nil {
+		return fmt.Errorf("fetch active crimes: %w", err)
+	}
+	f.store.Update(members, crimes)
+	f.store.SetActive(active)
+	f.store.SetLabel(f.label)
+	f.store.SetCPRBands(f.cprLow, f.cprHigh)
+	return nil
+}
+
+// refreshFactions refreshes every faction at once after a run, recording
+// each outcome for its health checks.
+func refreshFactions(factions []*faction) {
+	var wg sync.WaitGroup
+	for _, f := range factions {
+		wg.Go(func() {
+			defer reportPanic()
+			if err := f.refresh(); err != nil {
+				slog.Error("Failed to refresh faction", "faction", f.name, "error", err)
+				f.store.RecordRun(redact(err.Error(), f.key))
+				return
+			}
+			slog.Debug("Refreshed faction", "faction", f.name)
+			f.store.RecordRun("")
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// healthcheckFailures is how many runs in a row may fail before the audit
+// file check fails, as /healthz allows.
+const healthcheckFailures = 3
+
+// healthcheckCommand checks a running daemon for Docker HEALTHCHECK, Nomad and
+// similar: by its /healthz endpoint, or without --listen by the last runs in
+// its --audit-file. It takes the daemon's flags and config file so both agree,
+// prints one status line and exits 0 when healthy and 1 otherwise.
+func healthcheckCommand(ctx context.Context, args []string) {
+	o := defaultOptions()
+	fs := newFlagSet("healthcheck")
+	o.serverFlags(fs)
+	o.scheduleFlags(fs)
+	timeout := fs.Duration("timeout", 5*time.Second, "Give up on /healthz after this long")
+	parseFlags(fs, args)
+
+	var status string
+	var err error
+	if o.Listen == "" && o.AuditFile != "" {
+		status, err = checkAuditFile(o.AuditFile, o.healthMaxAge())
+	} else {
+		if o.Listen == "" {
+			o.Listen = ":8080"
+		}
+		ctx, cancel := context.WithTimeout(ctx, *timeout)
+		defer cancel()
+		status, err = checkHealthz(ctx, healthzURL(o.Listen, o.BasePath))
+	}
+	if err != nil {
+		fmt.Println("unhealthy:", err)
+		os.Exit(1)
+	}
+	fmt.Println(status)
+}
+
+// healthzURL is the /healthz URL of a daemon listening on addr, e.g. :8080,
+// under its --base-path, reached over loopback when it listens on every
+// interface. A full URL is taken as it is.
+func healthzURL(addr, basePath string) string {
+	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, "80"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + strings.TrimSuffix(basePath, "/") + "/healthz"
+}
+
+// checkHealthz queries url and describes the daemon's health, failing unless
+// it answers 200.
+func checkHealthz(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var h struct {
+		Status      string `json:"status"`
+		Reason      string `json:"reason"`
+		LastSuccess string `json:"last_success"`
+		LastError   string `json:"last_error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		return "", fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := h.Reason
+		if h.LastError != "" {
+			msg += ": " + h.LastError
+		}
+		return "", errors.New(msg)
+	}
+	if h.LastSuccess == "" {
+		return "ok: no run yet", nil
+	}
+	return "ok: last successful run " + h.LastSuccess, nil
+}
+
+// checkAuditFile describes the daemon's health from the runs in its audit
+// file, failing once the last healthcheckFailures runs failed or the last
+// successful run is older than maxAge (0 is no limit).
+func checkAuditFile(path string, maxAge time.Duration) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var last, lastSuccess *auditEntry
+	failures := 0
+	scan := bufio.NewScanner(f)
+	scan.Buffer(nil, 1<<20)
+	for scan.Scan() {
+		var e auditEntry
+		if err := json.Unmarshal(scan.Bytes(), &e); err != nil {
+			continue
+		}
+		switch e.ExitCode {
+		case exitLocked:
+			continue
+		case exitOK, exitPartial:
+			failures = 0
+			lastSuccess = &e
+		default:
+			failures++
+		}
+		last = &e
+	}
+	if err := scan.Err(); err != nil {
+		return "", err
+	}
+
+	switch {
+	case last == nil:
+		return "", fmt.Errorf("no runs recorded in %s", path)
+	case failures >= healthcheckFailures && len(last.Errors) > 0:
+		return "", fmt.Errorf("the last %d runs failed, the last with: %s", failures, strings.Join(last.Errors, "; "))
+	case failures >= healthcheckFailures:
+		return "", fmt.Errorf("the last %d runs failed", failures)
+	case lastSuccess == nil:
+		return "ok: no successful run yet", nil
+	}
+	if maxAge > 0 {
+		at, err := parseAuditTime(lastSuccess.RunAt)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		if age := time.Since(at); age > maxAge {
+			return "", fmt.Errorf("no successful run for %s", age.Round(time.Second))
+		}
+	}
+	return "ok: last successful run " + lastSuccess.RunAt, nil
+}
+
+// parseAuditTime reads the run_at of an audit entry, which is written in
+// --date-format and --timezone.
+func parseAuditTime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(dateFormat, s, timeZone); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("run_at %q isn't in the --date-format", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/config"
+	"github.com/mnuck/torn-oc-history/internal/env"
+	"github.com/mnuck/torn-oc-history/internal/keyring"
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// prompter asks questions on the terminal for init.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints question and returns the trimmed answer, or def for an empty
+// one. It exits at end of input.
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(p.out)
+		os.Exit(1)
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def
+	}
+	return line
+}
+
+// yes asks a yes/no question.
+func (p *prompter) yes(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		switch strings.ToLower(p.ask(question+" ("+hint+")", "")) {
+		case "":
+			return def
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
+
+func initCommand(ctx context.Context, args []string) {
+	fs := newFlagSet("init")
+	path := fs.String("config", config.DefaultFile, "Config file to write")
+	fs.Parse(args)
+
+	p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	fmt.Println("This sets up torn-oc-history in the current directory. Press enter to accept a [default].")
+
+	if _, err := os.Stat(*path); err == nil && !p.yes(*path+" exists. Overwrite it?", false) {
+		os.Exit(1)
+	}
+
+	// the key, checked against the faction endpoints the reports need
+	var tornClient *torn.Client
+	for tornClient == nil {
+		key := p.ask("Torn API key (Limited access, or Custom with faction crimes and members)", os.Getenv("TORN_API_KEY"))
+		if key == "" {
+			continue
+		}
+		c := newTornClient(key)
+		faction, err := c.FetchFactionBasic()
+		if err == nil {
+			_, err = c.FetchCrimesPage("completed", "", 0)
+		}
+		if err != nil {
+			fmt.Println("That key doesn't work:", err)
+			continue
+		}
+		fmt.Printf("Key OK: faction %s [%s].\n", faction.Name, faction.Tag)
+		tornClient = c
+	}
+	os.Setenv("TORN_API_KEY", tornClient.Key)
+
+	flags := map[string]string{"output": "stdout"}
+	if saveKey(p, tornClient.Key) {
+		flags["keyring"] = "true"
+	}
+	if p.yes("Write the reports to Google Sheets?", true) {
+		flags["output"] = "sheets"
+		if p.yes("Add a one-page summary tab?", true) {
+			flags["range-summary"] = "Summary!A1"
+		}
+		if p.yes("Add a tab describing the last run?", true) {
+			flags["range-about"] = "About!A1"
+		}
+		if os.Getenv("SPREADSHEET_ID") == "" && p.yes("Create the spreadsheet now?", true) {
+			createFromInit(ctx, p, tornClient, flags)
+		}
+	}
+	if p.yes("Run every few minutes instead of once?", false) {
+		flags["interval"] = p.ask("Interval", "15m")
+	}
+
+	if err := os.WriteFile(*path, []byte(starterConfig(flags)), 0o644); err != nil {
+		slog.Error("Failed to write config file", "path", *path, "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s. Check it with `%s config validate`, then run `%s` to produce the first report.\n", *path, os.Args[0], os.Args[0])
+}
+
+// saveKey keeps the API key out of the config file, which tends to get shared:
+// in the .env file, or in the OS keyring if available and wanted. It reports
+// whether the keyring was used.
+func saveKey(p *prompter, key string) bool {
+	if p.yes("Store the key in the OS keyring instead of "+envFile+"?", false) {
+		err := keyring.Set("TORN_API_KEY", key)
+		if err == nil {
+			fmt.Println("Stored the key in the keyring.")
+			return true
+		}
+		fmt.Println("Could not use the keyring:", err)
+	}
+	if err := env.Set(envFile, "TORN_API_KEY", key); err != nil {
+		slog.Error("Failed to save TORN_API_KEY", "file", envFile, "error", err)
+		os.Exit(1)
+	}
+	fmt.Println("Saved the key to", envFile)
+	return false
+}
+
+// createFromInit creates the spreadsheet with a tab for every range the
+// config will point at. Its ID is saved to the .env file.
+func createFromInit(ctx context.Context, p *prompter, tornClient *torn.Client, flags map[string]string) {
+	opts, source, err := sheetspkg.CredentialsFromEnv("credentials.json")
+	var client *sheetspkg.Client
+	if err == nil {
+		client, err = sheetspkg.NewClient(ctx, opts...)
+	}
+	if err != nil {
+		fmt.Printf("No usable Google credentials (%v). Run `%s login` or add credentials.json, then `%s sync` creates the spreadsheet.\n", err, os.Args[0], os.Args[0])
+		return
+	}
+	fmt.Println("Using Google credentials from", source)
+	if emails := p.ask("Share it with (comma-separated emails, optional)", os.Getenv("SHARE_EMAILS")); emails != "" {
+		os.Setenv("SHARE_EMAILS", emails)
+	}
+
+	ranges := []string{defaultOptions().NocRange}
+	for _, name := range []string{"range-summary", "range-about"} {
+		if r := flags[name]; r != "" {
+			ranges = append(ranges, r)
+		}
+	}
+	if _, err := createSpreadsheet(ctx, client, tornClient, ranges); err != nil {
+		fmt.Printf("Could not create the spreadsheet (%v); `%s sync` will try again.\n", err, os.Args[0])
+	}
+}
+
+// starterConfig renders the flags chosen in init as a commented config file.
+func starterConfig(flags map[string]string) string {
+	var b strings.Builder
+	b.WriteString("# torn-oc-history settings; see the README for every flag.\n")
+	b.WriteString("# Secrets such as TORN_API_KEY live in .env or the keyring, not here.\n")
+	b.WriteString("env: {}\n\nflags:\n")
+	for _, name := range []string{"output", "keyring", "range-summary", "range-about", "interval"} {
+		if v, ok := flags[name]; ok {
+			fmt.Fprintf(&b, "  %s: %q\n", name, v)
+		}
+	}
+	b.WriteString("  # cpr-low: 50\n  # cpr-high: 75\n")
+	return b.String()
+}
@@ -0,0 +1,134 @@
+// Package catalog lists the crimes of Torn's organized crimes 2.0 with their
+// difficulty and the positions of their slots, so the reports can tell a
+// position a member has never attempted from one without data at a
+// difficulty, and notice crimes Torn added or changed.
+package catalog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// embedded is the catalog built in, as of this release. Edit crimes.json, or
+// pass a copy to Load, when Torn changes the crimes.
+//
+//go:embed crimes.json
+var embedded []byte
+
+// Crime is a crime of the catalog. Positions lists a slot per entry, so a
+// position taken by several slots appears as often.
+type Crime struct {
+	Name       string   `json:"name"`
+	Difficulty int      `json:"difficulty"`
+	Positions  []string `json:"positions"`
+}
+
+// Catalog is a set of crimes, looked up by name ignoring case.
+type Catalog struct {
+	Crimes []Crime `json:"crimes"`
+	byName map[string]Crime
+}
+
+// Default is the embedded catalog.
+func Default() *Catalog {
+	c, err := Parse(embedded)
+	if err != nil {
+		panic("catalog: embedded crimes.json: " + err.Error())
+	}
+	return c
+}
+
+// Load reads a catalog from a file laid out as crimes.json.
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes a catalog, checking every crime has a name, difficulty and
+// positions, and no name appears twice.
+func Parse(data []byte) (*Catalog, error) {
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	c.byName = make(map[string]Crime, len(c.Crimes))
+	for i, crime := range c.Crimes {
+		switch key := strings.ToLower(crime.Name); {
+		case crime.Name == "":
+			return nil, fmt.Errorf("crime %d has no name", i+1)
+		case crime.Difficulty < 1:
+			return nil, fmt.Errorf("crime %q has no difficulty", crime.Name)
+		case len(crime.Positions) == 0:
+			return nil, fmt.Errorf("crime %q has no positions", crime.Name)
+		case c.byName[key].Name != "":
+			return nil, fmt.Errorf("crime %q is listed twice", crime.Name)
+		default:
+			c.byName[key] = crime
+		}
+	}
+	return &c, nil
+}
+
+// Crime looks a crime up by name.
+func (c *Catalog) Crime(name string) (Crime, bool) {
+	crime, ok := c.byName[strings.ToLower(name)]
+	return crime, ok
+}
+
+// Positions are the positions of the crimes at difficulty, or of every crime
+// when difficulty is 0, sorted and each listed once.
+func (c *Catalog) Positions(difficulty int) []string {
+	var positions []string
+	for _, crime := range c.Crimes {
+		if difficulty != 0 && crime.Difficulty != difficulty {
+			continue
+		}
+		for _, p := range crime.Positions {
+			if !slices.Contains(positions, p) {
+				positions = append(positions, p)
+			}
+		}
+	}
+	slices.Sort(positions)
+	return positions
+}
+
+// slotNumber is the " #2" of a position name numbering slots of the same
+// position.
+var slotNumber = regexp.MustCompile(` #\d+$`)
+
+// Role is a position without the number of its slot, such as Looter for
+// "Looter #2", as the catalog names positions.
+func Role(position string) string {
+	return slotNumber.ReplaceAllString(position, "")
+}
+
+// Check describes how a crime from the API differs from the catalog: a name
+// it doesn't list, another difficulty, or positions its slots don't have.
+func (c *Catalog) Check(crime torn.Crime) []string {
+	want, ok := c.Crime(crime.Name)
+	if !ok {
+		return []string{fmt.Sprintf("crime %q (difficulty %d) is not in the catalog", crime.Name, crime.Difficulty)}
+	}
+	var problems []string
+	if crime.Difficulty != want.Difficulty {
+		problems = append(problems, fmt.Sprintf("crime %q has difficulty %d, the catalog %d", crime.Name, crime.Difficulty, want.Difficulty))
+	}
+	for _, s := range crime.Slots {
+		position := Role(s.Position)
+		if !slices.ContainsFunc(want.Positions, func(p string) bool { return strings.EqualFold(p, position) }) {
+			problems = append(problems, fmt.Sprintf("crime %q has a %s slot, which the catalog doesn't list", crime.Name, s.Position))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is read when no config file is named and it exists.
+const DefaultFile = "torn-oc-history.yaml"
+
+// File is a configuration file. Env holds environment variables such as
+// TORN_API_KEY, SPREADSHEET_ID or DISCORD_WEBHOOK_URL; Flags holds command
+// line flags by name (without dashes), e.g. "range-noc" or "interval".
+// Profiles hold per-faction settings on top of those, selected with
+// --profile.
+type File struct {
+	Env      map[string]string      `yaml:"env"`
+	Flags    map[string]interface{} `yaml:"flags"`
+	Profiles map[string]File        `yaml:"profiles"`
+}
+
+// Profile returns the file's settings with those of the named profile laid
+// over them.
+func (f *File) Profile(name string) (*File, error) {
+	p, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	merged := &File{Env: make(map[string]string), Flags: make(map[string]interface{})}
+	for _, src := range []File{*f, p} {
+		for k, v := range src.Env {
+			merged.Env[k] = v
+		}
+		for k, v := range src.Flags {
+			merged.Flags[k] = v
+		}
+	}
+	return merged, nil
+}
+
+// Load parses the YAML config file at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// fromFile holds the environment variables ApplyEnv has set, which a
+// reloaded file may change.
+var fromFile = make(map[string]bool)
+
+// ApplyEnv sets the file's environment variables that are not already set,
+// so the real environment and .env take precedence. Variables it set from an
+// earlier load are updated, or unset when the file no longer has them.
+func (f *File) ApplyEnv() {
+	for k := range fromFile {
+		if _, ok := f.Env[k]; !ok {
+			os.Unsetenv(k)
+			delete(fromFile, k)
+		}
+	}
+	for k, v := range f.Env {
+		if _, ok := os.LookupEnv(k); !ok || fromFile[k] {
+			os.Setenv(k, v)
+			fromFile[k] = true
+		}
+	}
+}
+
+// ApplyFlags sets the file's flags that fs defines, to be overridden by
+// parsing the command line afterwards. Flags fs doesn't define belong to other
+// commands and are skipped. Lists are joined with commas.
+func (f *File) ApplyFlags(fs *flag.FlagSet) error {
+	for name, v := range f.Flags {
+		if fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, flagValue(v)); err != nil {
+			return fmt.Errorf("flag %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// UnknownFlags returns the names in the file's flags, including those of its
+// profiles, that fs doesn't define, sorted.
+func (f *File) UnknownFlags(fs *flag.FlagSet) []string {
+	sets := []map[string]interface{}{f.Flags}
+	for _, p := range f.Profiles {
+		sets = append(sets, p.Flags)
+	}
+	seen := make(map[string]bool)
+	var unknown []string
+	for _, flags := range sets {
+		for name := range flags {
+			if fs.Lookup(name) == nil && !seen[name] {
+				seen[name] = true
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func flagValue(v interface{}) string {
+	if list, ok := v.([]interface{}); ok {
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// PathFromArgs returns the config file named by a --config flag in args, else
+// by TORN_OC_CONFIG, else DefaultFile if it exists, else "".
+func PathFromArgs(args []string) string {
+	if path, ok := argValue(args, "config"); ok {
+		return path
+	}
+	if path := os.Getenv(EnvName("config")); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultFile); err == nil {
+		return DefaultFile
+	}
+	return ""
+}
+
+// ProfileFromArgs returns the profile named by a --profile flag in args, else
+// by TORN_OC_PROFILE, else "".
+func ProfileFromArgs(args []string) string {
+	if name, ok := argValue(args, "profile"); ok {
+		return name
+	}
+	return os.Getenv(EnvName("profile"))
+}
+
+// argValue finds the value of the string flag name in args ahead of parsing
+// them, as the config file has to be applied first. Arguments after "--" are
+// not looked at.
+func argValue(args []string, name string) (string, bool) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		n, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if n != name {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// EnvPrefix starts the environment variable that sets each flag.
+const EnvPrefix = "TORN_OC_"
+
+// EnvName returns the environment variable that sets a flag: "range-noc" is
+// set by TORN_OC_RANGE_NOC.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// ApplyEnvFlags sets every flag of fs whose environment variable (see
+// EnvName) is set. Call it after ApplyFlags and before parsing the command
+// line, so the environment overrides the config file and flags override both.
+func ApplyEnvFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := EnvName(f.Name)
+		if v, ok := os.LookupEnv(name); ok && err == nil {
+			if e := fs.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("%s: %w", name, e)
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,162 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Discord API limits for webhook messages.
+const (
+	MaxContent          = 2000
+	MaxEmbedsPerMessage = 10
+	MaxEmbedTotal       = 6000
+	MaxFields           = 25
+	MaxTitle            = 256
+	MaxDescription      = 4096
+	MaxFieldName        = 256
+	MaxFieldValue       = 1024
+)
+
+type Message struct {
+	Content string  `json:"content,omitempty"`
+	Embeds  []Embed `json:"embeds,omitempty"`
+}
+
+type Embed struct {
+	Title       string  `json:"title,omitempty"`
+	URL         string  `json:"url,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Color       int     `json:"color,omitempty"`
+	Timestamp   string  `json:"timestamp,omitempty"`
+	Fields      []Field `json:"fields,omitempty"`
+}
+
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// Webhook posts messages to a Discord channel webhook.
+type Webhook struct {
+	URL string
+}
+
+func (w *Webhook) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode discord message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build discord request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send discord message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("discord bad status: %s: %s", resp.Status, string(b))
+	}
+	return nil
+}
+
+// SplitContent splits lines of text into code-block messages that each fit
+// within the content limit.
+func SplitContent(lines []string) []string {
+	const fence = "```\n"
+	limit := MaxContent - 2*len(fence)
+
+	var chunks []string
+	var b strings.Builder
+	for _, line := range lines {
+		line = truncate(line, limit-1)
+		if b.Len()+len(line)+1 > limit {
+			chunks = append(chunks, fence+b.String()+fence)
+			b.Reset()
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if b.Len() > 0 {
+		chunks = append(chunks, fence+b.String()+fence)
+	}
+	return chunks
+}
+
+// SplitEmbeds truncates oversized embed parts, moves fields beyond the per-embed
+// limit into continuation embeds, and groups the result into as few messages
+// as the per-message limits allow.
+func SplitEmbeds(embeds []Embed) []Message {
+	var parts []Embed
+	for _, e := range embeds {
+		e.Title = truncate(e.Title, MaxTitle)
+		e.Description = truncate(e.Description, MaxDescription)
+		for i := range e.Fields {
+			e.Fields[i].Name = truncate(e.Fields[i].Name, MaxFieldName)
+			e.Fields[i].Value = truncate(e.Fields[i].Value, MaxFieldValue)
+		}
+		fields := e.Fields
+		first := e
+		first.Fields = nil
+		for {
+			n := min(len(fields), MaxFields)
+			part := first
+			part.Fields = fields[:n]
+			for n > 0 && embedSize(part) > MaxEmbedTotal {
+				n--
+				part.Fields = fields[:n]
+			}
+			parts = append(parts, part)
+			fields = fields[n:]
+			if len(fields) == 0 || n == 0 {
+				break
+			}
+			first = Embed{Title: truncate(e.Title+" (cont.)", MaxTitle), Color: e.Color}
+		}
+	}
+
+	var msgs []Message
+	var cur Message
+	size := 0
+	for _, p := range parts {
+		s := embedSize(p)
+		if len(cur.Embeds) == MaxEmbedsPerMessage || (len(cur.Embeds) > 0 && size+s > MaxEmbedTotal) {
+			msgs = append(msgs, cur)
+			cur, size = Message{}, 0
+		}
+		cur.Embeds = append(cur.Embeds, p)
+		size += s
+	}
+	if len(cur.Embeds) > 0 {
+		msgs = append(msgs, cur)
+	}
+	return msgs
+}
+
+// embedSize counts the characters Discord includes in the 6000 total limit.
+func embedSize(e Embed) int {
+	n := len([]rune(e.Title)) + len([]rune(e.Description))
+	for _, f := range e.Fields {
+		n += len([]rune(f.Name)) + len([]rune(f.Value))
+	}
+	return n
+}
+
+func truncate(s string, limit int) string {
+	r := []rune(s)
+	if len(r) <= limit {
+		return s
+	}
+	return string(r[:limit-1]) + "…"
+}
@@ -0,0 +1,131 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// apiBase is the Discord REST API the slash commands are registered with.
+const apiBase = "https://discord.com/api/v10"
+
+// Interaction types and callback types of the interactions endpoint.
+const (
+	InteractionPing               = 1
+	InteractionApplicationCommand = 2
+	CallbackPong                  = 1
+	CallbackMessage               = 4
+	// FlagEphemeral shows a reply only to the member who asked.
+	FlagEphemeral = 1 << 6
+)
+
+// Interaction is what Discord posts to an interactions endpoint when a member
+// uses a slash command; for a type 1 ping, only Type is set.
+type Interaction struct {
+	Type int `json:"type"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+// Option returns the value of the named option as text, "" if not given.
+func (i *Interaction) Option(name string) string {
+	for _, o := range i.Data.Options {
+		if o.Name != name {
+			continue
+		}
+		var s string
+		if json.Unmarshal(o.Value, &s) == nil {
+			return s
+		}
+		return string(o.Value)
+	}
+	return ""
+}
+
+// InteractionResponse answers an interaction.
+type InteractionResponse struct {
+	Type int              `json:"type"`
+	Data *InteractionData `json:"data,omitempty"`
+}
+
+type InteractionData struct {
+	Content string `json:"content"`
+	Flags   int    `json:"flags,omitempty"`
+}
+
+// ParsePublicKey parses the hex public key of a Discord application.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%q is not the hex public key of a Discord application", s)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Verify checks the signature Discord puts on each request to an
+// interactions endpoint; Discord refuses an endpoint that accepts unsigned
+// requests.
+func Verify(key ed25519.PublicKey, r *http.Request, body []byte) bool {
+	sig, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	msg := append([]byte(r.Header.Get("X-Signature-Timestamp")), body...)
+	return ed25519.Verify(key, msg, sig)
+}
+
+// Command is a slash command to register.
+type Command struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
+
+type CommandOption struct {
+	// Type 3 is a string.
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// RegisterCommands replaces the slash commands of the application with cmds:
+// those of one guild, which apply at once, or with guildID empty the global
+// ones, which can take up to an hour to show.
+func RegisterCommands(ctx context.Context, appID, guildID, botToken string, cmds []Command) error {
+	url := fmt.Sprintf("%s/applications/%s/commands", apiBase, appID)
+	if guildID != "" {
+		url = fmt.Sprintf("%s/applications/%s/guilds/%s/commands", apiBase, appID, guildID)
+	}
+	body, err := json.Marshal(cmds)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build discord request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bot "+botToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to register discord commands: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("discord bad status: %s: %s", resp.Status, string(b))
+	}
+	return nil
+}
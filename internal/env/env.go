@@ -2,6 +2,7 @@ package env
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"strings"
 )
@@ -78,3 +79,52 @@ func Load(path string) error {
 	}
 	return sc.Err()
 }
+
+// Set writes KEY=VALUE into the file at path, replacing an existing
+// assignment of key or appending one, and creating the file if needed.
+func Set(path, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	entry := key + "=" + value
+	var lines []string
+	found := false
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+	for i, line := range lines {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == key {
+			lines[i] = entry
+			found = true
+		}
+	}
+	if !found {
+		lines = append(lines, entry)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+}
+
+// LoadFiles sets each of keys that is unset from the file named by its _FILE
+// variable, if any, following the Docker and Kubernetes secrets convention:
+// TORN_API_KEY_FILE=/run/secrets/torn_api_key sets TORN_API_KEY from that
+// file. A trailing newline is dropped.
+func LoadFiles(keys []string) error {
+	for _, key := range keys {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		path := os.Getenv(key + "_FILE")
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s_FILE: %w", key, err)
+		}
+		os.Setenv(key, strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
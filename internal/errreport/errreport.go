@@ -0,0 +1,179 @@
+// Package errreport sends the panics and persistent errors of unattended
+// deployments to Sentry or to a generic webhook, so they reach the
+// maintainer instead of dying in container logs.
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event is one error to report.
+type Event struct {
+	// Level is "fatal" for a panic and "error" otherwise.
+	Level   string
+	Message string
+	// Stack is the goroutine stack of a panic, as debug.Stack prints it.
+	Stack string
+	Tags  map[string]string
+}
+
+// Reporter delivers an error event.
+type Reporter interface {
+	Report(ctx context.Context, e Event) error
+}
+
+// Multi reports to every configured reporter.
+type Multi []Reporter
+
+func (m Multi) Report(ctx context.Context, e Event) error {
+	var errs []error
+	for _, r := range m {
+		if err := r.Report(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FromEnv builds the reporters configured through SENTRY_DSN (with
+// SENTRY_ENVIRONMENT) and ERROR_REPORT_WEBHOOK_URL, tagging the events with
+// release. An empty Multi means error reporting is disabled.
+func FromEnv(release string) (Multi, error) {
+	var m Multi
+	host, _ := os.Hostname()
+	if dsn := os.Getenv("SENTRY_DSN"); dsn != "" {
+		s, err := NewSentry(dsn)
+		if err != nil {
+			return nil, err
+		}
+		s.Release, s.Environment, s.ServerName = release, os.Getenv("SENTRY_ENVIRONMENT"), host
+		m = append(m, s)
+	}
+	if u := os.Getenv("ERROR_REPORT_WEBHOOK_URL"); u != "" {
+		m = append(m, &Webhook{URL: u, Release: release, Host: host})
+	}
+	return m, nil
+}
+
+// Sentry sends events to a Sentry project (or a compatible service such as
+// GlitchTip) through its envelope endpoint.
+type Sentry struct {
+	endpoint string
+	key      string
+
+	Release     string
+	Environment string
+	ServerName  string
+}
+
+// NewSentry parses a DSN such as https://<key>@o1.ingest.sentry.io/<project>.
+func NewSentry(dsn string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User == nil {
+		return nil, fmt.Errorf("SENTRY_DSN %q is not a DSN such as https://<key>@o1.ingest.sentry.io/<project>", dsn)
+	}
+	path := strings.TrimRight(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	project := path[i+1:]
+	if project == "" {
+		return nil, errors.New("SENTRY_DSN has no project ID")
+	}
+	endpoint := fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:i], project)
+	return &Sentry{endpoint: endpoint, key: u.User.Username()}, nil
+}
+
+func (s *Sentry) Report(ctx context.Context, e Event) error {
+	id := eventID()
+	now := time.Now().UTC().Format(time.RFC3339)
+	event := map[string]any{
+		"event_id":    id,
+		"timestamp":   now,
+		"level":       e.Level,
+		"platform":    "go",
+		"logger":      "torn-oc-history",
+		"release":     s.Release,
+		"environment": s.Environment,
+		"server_name": s.ServerName,
+		"message":     map[string]string{"formatted": e.Message},
+		"tags":        e.Tags,
+	}
+	if e.Stack != "" {
+		event["exception"] = map[string]any{"values": []map[string]string{{"type": "panic", "value": e.Message}}}
+		event["extra"] = map[string]string{"stack": e.Stack}
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, item := range []any{map[string]string{"event_id": id, "sent_at": now}, map[string]string{"type": "event"}, event} {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to build Sentry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=torn-oc-history/%s, sentry_key=%s", s.Release, s.key))
+	return send(req, "Sentry")
+}
+
+// Webhook posts events as JSON to any URL, for error trackers and chat
+// integrations without Sentry's protocol.
+type Webhook struct {
+	URL     string
+	Release string
+	Host    string
+}
+
+func (w *Webhook) Report(ctx context.Context, e Event) error {
+	body, err := json.Marshal(map[string]any{
+		"time":    time.Now().UTC().Format(time.RFC3339),
+		"level":   e.Level,
+		"message": e.Message,
+		"stack":   e.Stack,
+		"release": w.Release,
+		"host":    w.Host,
+		"tags":    e.Tags,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build error report request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return send(req, "error report webhook")
+}
+
+func send(req *http.Request, what string) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send to %s: %w", what, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s bad status: %s: %s", what, resp.Status, string(body))
+	}
+	return nil
+}
+
+func eventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,338 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Object is a value with fields. Field returns the value of a field for the
+// arguments given: nil, a bool, number or string, another Object, or a slice
+// of either. Lists of objects are []Object.
+type Object interface {
+	TypeName() string
+	Field(name string, args Args) (any, error)
+}
+
+// ErrNoField is what Field returns for a field the type doesn't have.
+var ErrNoField = errors.New("no such field")
+
+// Args are the arguments of a field, with variables substituted.
+type Args map[string]any
+
+// Int returns the integer argument name and whether it was given.
+func (a Args) Int(name string) (int, bool) {
+	switch v := a[name].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), v == float64(int(v))
+	}
+	return 0, false
+}
+
+// String returns the string (or enum) argument name and whether it was
+// given.
+func (a Args) String(name string) (string, bool) {
+	v, ok := a[name].(string)
+	return v, ok
+}
+
+// Bool returns the boolean argument name and whether it was given.
+func (a Args) Bool(name string) (bool, bool) {
+	v, ok := a[name].(bool)
+	return v, ok
+}
+
+// Request is the body of a GraphQL request over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Response is the result of a request: the data and any errors, of which a
+// field's leave it null.
+type Response struct {
+	Data   *orderedMap `json:"data,omitempty"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Bounds on the work a query can ask for, as lists of objects nest in
+// cycles: MaxDepth on how deeply its selections nest, and MaxFields on how
+// many fields it resolves, counting those of every item of a list.
+const (
+	MaxDepth  = 10
+	MaxFields = 50000
+)
+
+// Execute runs the query of req against root.
+func Execute(root Object, req Request) Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return Response{Errors: []Error{{Message: op.kind + " operations aren't supported"}}}
+	}
+	depth, err := doc.depth(op.selection, make(map[string]bool))
+	if err == nil && depth > MaxDepth {
+		err = fmt.Errorf("the query nests %d levels deep, more than %d", depth, MaxDepth)
+	}
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	e := &executor{doc: doc, vars: make(map[string]any)}
+	for _, v := range op.variables {
+		if given, ok := req.Variables[v.name]; ok {
+			e.vars[v.name] = given
+		} else if v.def != nil {
+			e.vars[v.name] = e.literal(v.def)
+		}
+	}
+	data := e.selectionSet(root, op.selection, nil)
+	return Response{Data: data, Errors: e.errors}
+}
+
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) != 1 {
+			return nil, errors.New("the document must have exactly one operation, or the request an operationName")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("no operation named %q", name)
+}
+
+// depth is how deeply sels nest, following fragments, which mustn't spread
+// themselves.
+func (d *document) depth(sels []selection, visited map[string]bool) (int, error) {
+	deepest := 0
+	for _, s := range sels {
+		n := 0
+		var err error
+		switch {
+		case s.spread != "":
+			f, ok := d.fragments[s.spread]
+			if !ok {
+				continue
+			}
+			if visited[s.spread] {
+				return 0, fmt.Errorf("fragment %q spreads itself", s.spread)
+			}
+			visited[s.spread] = true
+			n, err = d.depth(f.selection, visited)
+			delete(visited, s.spread)
+		case s.inline:
+			n, err = d.depth(s.selection, visited)
+		default:
+			n, err = d.depth(s.selection, visited)
+			n++
+		}
+		if err != nil {
+			return 0, err
+		}
+		deepest = max(deepest, n)
+	}
+	return deepest, nil
+}
+
+type executor struct {
+	doc    *document
+	vars   map[string]any
+	errors []Error
+	// fields counts the fields resolved, up to MaxFields
+	fields int
+}
+
+func (e *executor) fail(path []any, format string, a ...any) {
+	e.errors = append(e.errors, Error{Message: fmt.Sprintf(format, a...), Path: append([]any(nil), path...)})
+}
+
+// selectionSet resolves the fields of sels on obj, in the order they were
+// asked for.
+func (e *executor) selectionSet(obj Object, sels []selection, path []any) *orderedMap {
+	out := &orderedMap{values: make(map[string]any)}
+	fields := make(map[string][]selection)
+	e.collect(obj, sels, out, fields, make(map[string]bool))
+	for _, key := range out.keys {
+		group := fields[key]
+		f := group[0]
+		fieldPath := append(path, key)
+		if e.fields++; e.fields > MaxFields {
+			if e.fields == MaxFields+1 {
+				e.fail(fieldPath, "the query resolves more than %d fields; ask for fewer or page the lists", MaxFields)
+			}
+			out.values[key] = nil
+			continue
+		}
+		if f.name == "__typename" {
+			out.values[key] = obj.TypeName()
+			continue
+		}
+		v, err := obj.Field(f.name, e.args(f.args))
+		if errors.Is(err, ErrNoField) {
+			e.fail(fieldPath, "cannot query field %q on type %q", f.name, obj.TypeName())
+			out.values[key] = nil
+			continue
+		}
+		if err != nil {
+			e.fail(fieldPath, "%v", err)
+			out.values[key] = nil
+			continue
+		}
+		var sub []selection
+		for _, g := range group {
+			sub = append(sub, g.selection...)
+		}
+		out.values[key] = e.complete(v, f, sub, fieldPath)
+	}
+	return out
+}
+
+// complete resolves the sub-selection of a field's value.
+func (e *executor) complete(v any, f selection, sub []selection, path []any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case Object:
+		if len(sub) == 0 {
+			e.fail(path, "field %q of type %q must have a selection of subfields", f.name, v.TypeName())
+			return nil
+		}
+		return e.selectionSet(v, sub, path)
+	case []Object:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = e.complete(item, f, sub, append(path, i))
+		}
+		return list
+	}
+	if len(sub) > 0 {
+		e.fail(path, "field %q is a scalar and can't have a selection of subfields", f.name)
+		return nil
+	}
+	return v
+}
+
+// collect lists the fields of sels that apply to obj by response key, into
+// out's keys in order, following fragments and honouring @skip and @include.
+func (e *executor) collect(obj Object, sels []selection, out *orderedMap, fields map[string][]selection, visited map[string]bool) {
+	for _, s := range sels {
+		if !e.included(s.directives) {
+			continue
+		}
+		switch {
+		case s.spread != "":
+			f, ok := e.doc.fragments[s.spread]
+			if !ok {
+				e.fail(nil, "unknown fragment %q", s.spread)
+				continue
+			}
+			if visited[s.spread] || f.on != obj.TypeName() {
+				continue
+			}
+			visited[s.spread] = true
+			e.collect(obj, f.selection, out, fields, visited)
+		case s.inline:
+			if s.on == "" || s.on == obj.TypeName() {
+				e.collect(obj, s.selection, out, fields, visited)
+			}
+		default:
+			if _, ok := fields[s.alias]; !ok {
+				out.keys = append(out.keys, s.alias)
+			}
+			fields[s.alias] = append(fields[s.alias], s)
+		}
+	}
+}
+
+func (e *executor) included(ds []directive) bool {
+	for _, d := range ds {
+		cond, _ := e.args(d.args).Bool("if")
+		if d.name == "skip" && cond || d.name == "include" && !cond {
+			return false
+		}
+	}
+	return true
+}
+
+// args resolves the variables in the arguments of a field.
+func (e *executor) args(in map[string]value) Args {
+	args := make(Args, len(in))
+	for name, v := range in {
+		if resolved := e.resolve(v); resolved != nil {
+			args[name] = resolved
+		}
+	}
+	return args
+}
+
+func (e *executor) resolve(v value) any {
+	if ref, ok := v.(variableRef); ok {
+		return e.vars[string(ref)]
+	}
+	return e.literal(v)
+}
+
+// literal converts a parsed literal to the Go values of decoded JSON.
+func (e *executor) literal(v value) any {
+	switch v := v.(type) {
+	case enumValue:
+		return string(v)
+	case []value:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = e.resolve(item)
+		}
+		return list
+	case map[string]value:
+		obj := make(map[string]any, len(v))
+		for k, item := range v {
+			obj[k] = e.resolve(item)
+		}
+		return obj
+	}
+	return v
+}
+
+// orderedMap is a JSON object that keeps the order of its keys.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		val, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
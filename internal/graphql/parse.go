@@ -0,0 +1,517 @@
+// Package graphql executes GraphQL queries against Go resolvers. It covers
+// the query language that clients send for reads: operations with variables,
+// aliases, arguments, fragments, inline fragments and the @include and @skip
+// directives. Mutations, subscriptions and introspection aren't supported;
+// the schema is published as SDL instead.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind      string // query, mutation or subscription
+	name      string
+	variables []variableDef
+	selection []selection
+}
+
+type variableDef struct {
+	name string
+	def  value // nil without a default
+}
+
+type fragment struct {
+	on        string
+	selection []selection
+}
+
+// selection is a field, a fragment spread (spread set) or an inline fragment
+// (inline set).
+type selection struct {
+	alias, name string
+	args        map[string]value
+	directives  []directive
+	selection   []selection
+
+	spread string
+	inline bool
+	on     string
+}
+
+type directive struct {
+	name string
+	args map[string]value
+}
+
+// value is a literal or a variable reference, resolved when executing.
+type value interface{}
+
+type variableRef string
+
+type enumValue string
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// parse parses a query document.
+func parse(src string) (*document, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.isPunct("{"):
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selection: sel})
+		case p.tok.kind == tokName && p.tok.text == "fragment":
+			if err := p.fragmentDefinition(doc); err != nil {
+				return nil, err
+			}
+		case p.tok.kind == tokName && (p.tok.text == "query" || p.tok.text == "mutation" || p.tok.text == "subscription"):
+			op, err := p.operationDefinition()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		default:
+			return nil, p.errorf("unexpected %q", p.tok.text)
+		}
+	}
+	return doc, nil
+}
+
+func (p *parser) operationDefinition() (*operation, error) {
+	op := &operation{kind: p.tok.text}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.name = p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isPunct("(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		for !p.isPunct(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if err := p.skipType(); err != nil {
+				return nil, err
+			}
+			v := variableDef{name: name}
+			if p.isPunct("=") {
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+				if v.def, err = p.value(); err != nil {
+					return nil, err
+				}
+			}
+			op.variables = append(op.variables, v)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selection = sel
+	return op, nil
+}
+
+// skipType reads a variable's type, such as [Int!]!, which the resolvers
+// check for themselves.
+func (p *parser) skipType() error {
+	if p.isPunct("[") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.isPunct("!") {
+		return p.next()
+	}
+	return nil
+}
+
+func (p *parser) fragmentDefinition(doc *document) error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	if on, err := p.name(); err != nil || on != "on" {
+		return p.errorf("expected \"on\" after fragment %s", name)
+	}
+	f := &fragment{}
+	if f.on, err = p.name(); err != nil {
+		return err
+	}
+	if _, err := p.directives(); err != nil {
+		return err
+	}
+	if f.selection, err = p.selectionSet(); err != nil {
+		return err
+	}
+	doc.fragments[name] = f
+	return nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for !p.isPunct("}") {
+		if p.tok.kind == tokEOF {
+			return nil, p.errorf("unterminated selection set")
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	return sels, p.next()
+}
+
+func (p *parser) selection() (selection, error) {
+	var sel selection
+	var err error
+	if p.isPunct("...") {
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		if p.tok.kind == tokName && p.tok.text != "on" {
+			sel.spread = p.tok.text
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			sel.directives, err = p.directives()
+			return sel, err
+		}
+		sel.inline = true
+		if p.tok.kind == tokName {
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			if sel.on, err = p.name(); err != nil {
+				return sel, err
+			}
+		}
+		if sel.directives, err = p.directives(); err != nil {
+			return sel, err
+		}
+		sel.selection, err = p.selectionSet()
+		return sel, err
+	}
+
+	if sel.name, err = p.name(); err != nil {
+		return sel, err
+	}
+	sel.alias = sel.name
+	if p.isPunct(":") {
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		if sel.name, err = p.name(); err != nil {
+			return sel, err
+		}
+	}
+	if p.isPunct("(") {
+		if sel.args, err = p.arguments(); err != nil {
+			return sel, err
+		}
+	}
+	if sel.directives, err = p.directives(); err != nil {
+		return sel, err
+	}
+	if p.isPunct("{") {
+		sel.selection, err = p.selectionSet()
+	}
+	return sel, err
+}
+
+func (p *parser) arguments() (map[string]value, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	args := make(map[string]value)
+	for !p.isPunct(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.next()
+}
+
+func (p *parser) directives() ([]directive, error) {
+	var ds []directive
+	for p.isPunct("@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		d := directive{name: name}
+		if p.isPunct("(") {
+			if d.args, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+func (p *parser) value() (value, error) {
+	tok := p.tok
+	switch {
+	case p.isPunct("$"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return variableRef(name), err
+	case p.isPunct("["):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := []value{}
+		for !p.isPunct("]") {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+	case p.isPunct("{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		obj := make(map[string]value)
+		for !p.isPunct("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.next()
+	case tok.kind == tokInt:
+		n, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid integer %s", tok.text)
+		}
+		return n, p.next()
+	case tok.kind == tokFloat:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", tok.text)
+		}
+		return f, p.next()
+	case tok.kind == tokString:
+		return tok.text, p.next()
+	case tok.kind == tokName:
+		var v value
+		switch tok.text {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = enumValue(tok.text)
+		}
+		return v, p.next()
+	}
+	return nil, p.errorf("expected a value, not %q", tok.text)
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.errorf("expected a name, not %q", p.tok.text)
+	}
+	name := p.tok.text
+	return name, p.next()
+}
+
+func (p *parser) isPunct(s string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == s
+}
+
+func (p *parser) expect(s string) error {
+	if !p.isPunct(s) {
+		return p.errorf("expected %q, not %q", s, p.tok.text)
+	}
+	return p.next()
+}
+
+func (p *parser) errorf(format string, a ...any) error {
+	line := 1 + strings.Count(p.src[:p.tok.pos], "\n")
+	return fmt.Errorf("syntax error at line %d: %s", line, fmt.Sprintf(format, a...))
+}
+
+// next reads the next token, skipping whitespace, commas and comments.
+func (p *parser) next() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else {
+			break
+		}
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, text: "end of query", pos: start}
+		return nil
+	}
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokPunct, text: "...", pos: start}
+	case strings.IndexByte("!$()[]{}:=@|&", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokPunct, text: string(c), pos: start}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokName, text: p.src[start:p.pos], pos: start}
+	case c == '-' || c >= '0' && c <= '9':
+		p.pos++
+		kind := tokInt
+		for p.pos < len(p.src) {
+			d := p.src[p.pos]
+			if d == '.' || d == 'e' || d == 'E' || (d == '-' || d == '+') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E') {
+				kind = tokFloat
+			} else if d < '0' || d > '9' {
+				break
+			}
+			p.pos++
+		}
+		p.tok = token{kind: kind, text: p.src[start:p.pos], pos: start}
+	case c == '"':
+		s, err := p.stringLiteral()
+		if err != nil {
+			return err
+		}
+		p.tok = token{kind: tokString, text: s, pos: start}
+	default:
+		p.tok = token{kind: tokPunct, text: string(c), pos: start}
+		return p.errorf("unexpected character %q", c)
+	}
+	return nil
+}
+
+func (p *parser) stringLiteral() (string, error) {
+	start := p.pos
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			return "", fmt.Errorf("syntax error: unterminated block string")
+		}
+		p.pos += 3 + end + 3
+		return strings.TrimSpace(p.src[start+3 : start+3+end]), nil
+	}
+	p.pos++
+	for p.pos < len(p.src) && p.src[p.pos] != '"' && p.src[p.pos] != '\n' {
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '"' {
+		return "", fmt.Errorf("syntax error: unterminated string")
+	}
+	p.pos++
+	s, err := strconv.Unquote(p.src[start:p.pos])
+	if err != nil {
+		return "", fmt.Errorf("syntax error: invalid string %s", p.src[start:p.pos])
+	}
+	return s, nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,100 @@
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Service names the tool's entries in the keyring.
+const Service = "torn-oc-history"
+
+// ErrNotFound is returned by Get when the keyring has no such entry.
+var ErrNotFound = errors.New("not found in keyring")
+
+// ErrUnsupported is returned on systems without a supported keyring tool.
+var ErrUnsupported = errors.New("no supported keyring: needs secret-tool (libsecret) on Linux or security on macOS")
+
+// The OS keyring is reached through its command line tool rather than a
+// native binding, keeping the binary static: secret-tool on Linux (GNOME
+// Keyring, KWallet via libsecret) and security on macOS.
+
+// Get returns the secret stored under name.
+func Get(name string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", Service, "key", name)
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", Service, "-a", name, "-w")
+	default:
+		return "", ErrUnsupported
+	}
+	out, err := run(cmd, nil)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// both tools exit non-zero with no output for a missing entry
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	secret := strings.TrimRight(out, "\r\n")
+	if secret == "" {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set stores secret under name, replacing any previous value.
+func Set(name, secret string) error {
+	var cmd *exec.Cmd
+	var stdin []byte
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "store", "--label", Service+" "+name, "service", Service, "key", name)
+		stdin = []byte(secret)
+	case "darwin":
+		// security only takes the secret as an argument, so it shows up in the
+		// process list for the moment the command runs
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", Service, "-a", name, "-w", secret)
+	default:
+		return ErrUnsupported
+	}
+	_, err := run(cmd, stdin)
+	return err
+}
+
+// Delete removes the secret stored under name.
+func Delete(name string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "clear", "service", Service, "key", name)
+	case "darwin":
+		cmd = exec.Command("security", "delete-generic-password", "-s", Service, "-a", name)
+	default:
+		return ErrUnsupported
+	}
+	_, err := run(cmd, nil)
+	return err
+}
+
+func run(cmd *exec.Cmd, stdin []byte) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", ErrUnsupported
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", cmd.Args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return stdout.String(), nil
+}
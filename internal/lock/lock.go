@@ -0,0 +1,38 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned when another process holds the lock.
+var ErrLocked = errors.New("lock is held by another process")
+
+// pollInterval is how often Acquire retries while waiting.
+const pollInterval = time.Second
+
+// Lock is a held lock. Release it when done.
+type Lock struct {
+	f    *os.File
+	path string
+}
+
+// Acquire takes the lock on path, creating the file if needed. If another
+// process holds it, Acquire retries for up to wait and then returns ErrLocked.
+// The lock is released when the process exits, however it exits.
+func Acquire(path string, wait time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		l, err := tryLock(path)
+		if !errors.Is(err, ErrLocked) || !time.Now().Before(deadline) {
+			return l, err
+		}
+		time.Sleep(min(pollInterval, time.Until(deadline)))
+	}
+}
+
+// Release gives the lock up.
+func (l *Lock) Release() error {
+	return l.release()
+}
@@ -0,0 +1,28 @@
+//go:build !unix
+
+package lock
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// Without flock the lock is the existence of the file, which a crashed
+// process leaves behind; remove it by hand then.
+func tryLock(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	return &Lock{f: f, path: path}, nil
+}
+
+func (l *Lock) release() error {
+	l.f.Close()
+	return os.Remove(l.path)
+}
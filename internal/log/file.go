@@ -0,0 +1,131 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedLayout names the rotated copies of a log file: app.log becomes
+// app.log.20240131-150405.
+const rotatedLayout = "20060102-150405"
+
+// File is a log file that rotates itself: once it reaches MaxSize bytes or
+// has been written to for MaxAge, it is renamed with the time appended and a
+// new one started. Only the newest Keep rotated copies are kept. Zero limits
+// are off.
+type File struct {
+	path    string
+	maxSize int64
+	maxAge  time.Duration
+	keep    int
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	started time.Time
+}
+
+// OpenFile opens the log file at path for appending, creating it if needed.
+// An existing file already past maxAge is rotated first.
+func OpenFile(path string, maxSize int64, maxAge time.Duration, keep int) (*File, error) {
+	l := &File{path: path, maxSize: maxSize, maxAge: maxAge, keep: keep}
+	if fi, err := os.Stat(path); err == nil && fi.Size() > 0 && maxAge > 0 && time.Since(fi.ModTime()) > maxAge {
+		if err := l.rename(); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *File) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.size, l.started = f, fi.Size(), time.Now()
+	return nil
+}
+
+// Write appends p, rotating the file first if p would take it past its
+// limits. A write error after a failed rotation goes to the old file.
+func (l *File) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.size > 0 && (l.maxSize > 0 && l.size+int64(len(p)) > l.maxSize || l.maxAge > 0 && time.Since(l.started) > l.maxAge) {
+		if err := l.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "log file %s: rotate: %v\n", l.path, err)
+		}
+	}
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// Close closes the file.
+func (l *File) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+func (l *File) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	renameErr := l.rename()
+	if err := l.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	l.prune()
+	return nil
+}
+
+// rename moves the current file aside under the time of rotation.
+func (l *File) rename() error {
+	name := l.path + "." + time.Now().Format(rotatedLayout)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s.%s.%d", l.path, time.Now().Format(rotatedLayout), i)
+	}
+	return os.Rename(l.path, name)
+}
+
+// prune removes the oldest rotated copies beyond keep.
+func (l *File) prune() {
+	if l.keep <= 0 {
+		return
+	}
+	rotated, err := filepath.Glob(l.path + ".*")
+	if err != nil {
+		return
+	}
+	rotated = slices.DeleteFunc(rotated, func(name string) bool {
+		stamp, _, _ := strings.Cut(strings.TrimPrefix(name, l.path+"."), ".")
+		_, err := time.Parse(rotatedLayout, stamp)
+		return err != nil
+	})
+	// the names sort by the time they were rotated
+	slices.Sort(rotated)
+	for len(rotated) > l.keep {
+		os.Remove(rotated[0])
+		rotated = rotated[1:]
+	}
+}
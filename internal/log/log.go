@@ -1,16 +1,43 @@
 package log
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
 )
 
+// output is where the logs go: stderr unless SetOutput chose a file.
+var output io.Writer = os.Stderr
+
+// SetOutput sends the logs of the next Configure to w instead of stderr.
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 // Setup configures the global logger based on ENV and LOGLEVEL environment variables.
 func Setup() {
+	// an unknown LOGLEVEL falls back to info
+	Configure(os.Getenv("LOGLEVEL"), DefaultFormat())
+}
+
+// DefaultFormat is the log format used unless one is chosen: json when ENV is
+// production, console otherwise.
+func DefaultFormat() string {
+	if os.Getenv("ENV") == "production" {
+		return "json"
+	}
+	return "console"
+}
+
+// Configure sets the global logger's level (debug, info, warn or error; empty
+// is info) and format (console or json). Unknown values are reported, leaving
+// info and console in their place.
+func Configure(levelStr, format string) error {
 	var level slog.Level
-	levelStr := strings.ToLower(os.Getenv("LOGLEVEL"))
-	switch levelStr {
+	var err error
+	switch strings.ToLower(levelStr) {
 	case "debug":
 		level = slog.LevelDebug
 	case "info", "":
@@ -23,15 +50,23 @@ func Setup() {
 		level = slog.LevelError
 	default:
 		level = slog.LevelInfo
+		err = fmt.Errorf("unknown log level %q", levelStr)
 	}
 
 	opts := &slog.HandlerOptions{Level: level}
 	var handler slog.Handler
-	if os.Getenv("ENV") == "production" {
-		handler = slog.NewJSONHandler(os.Stderr, opts)
-	} else {
-		handler = slog.NewTextHandler(os.Stderr, opts)
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(output, opts)
+	case "console", "text", "":
+		handler = slog.NewTextHandler(output, opts)
+	default:
+		handler = slog.NewTextHandler(output, opts)
+		if err == nil {
+			err = fmt.Errorf("unknown log format %q", format)
+		}
 	}
 
 	slog.SetDefault(slog.New(handler))
+	return err
 }
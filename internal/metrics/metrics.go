@@ -0,0 +1,102 @@
+// Package metrics keeps the daemon's own metrics and renders them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metric is one named series family: counters only go up, gauges are set.
+type metric struct {
+	help, kind string
+	values     map[string]float64 // by label string, e.g. `result="ok"`
+}
+
+var (
+	mu      sync.Mutex
+	metrics = map[string]*metric{}
+)
+
+func get(name, kind, help string) *metric {
+	m, ok := metrics[name]
+	if !ok {
+		m = &metric{help: help, kind: kind, values: map[string]float64{}}
+		metrics[name] = m
+	}
+	return m
+}
+
+func add(name, help, labels string, v float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	get(name, "counter", help).values[labels] += v
+}
+
+func set(name, help, labels string, v float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	get(name, "gauge", help).values[labels] = v
+}
+
+// RecordRun records the outcome of a run of the reports.
+func RecordRun(started time.Time, crimes int, ok bool) {
+	result := `result="failure"`
+	if ok {
+		result = `result="success"`
+		set("torn_oc_last_success_timestamp_seconds", "Unix time of the last run without errors.", "", float64(time.Now().Unix()))
+	}
+	add("torn_oc_runs_total", "Runs of the reports, by result.", result, 1)
+	set("torn_oc_last_run_timestamp_seconds", "Unix time the last run finished.", "", float64(time.Now().Unix()))
+	set("torn_oc_run_duration_seconds", "Duration of the last run.", "", time.Since(started).Seconds())
+	set("torn_oc_crimes_fetched", "Completed crimes fetched by the last run.", "", float64(crimes))
+}
+
+// APIError counts a failed API call: api is torn or sheets, kind e.g. auth,
+// rate_limit or other.
+func APIError(api, kind string) {
+	add("torn_oc_api_errors_total", "Failed API calls, by API and kind of error.", fmt.Sprintf(`api=%q,kind=%q`, api, kind), 1)
+}
+
+// SheetWrite records how long a write of report tabs to a spreadsheet took.
+func SheetWrite(d time.Duration) {
+	add("torn_oc_sheet_write_seconds_total", "Total time spent writing report tabs to Google Sheets.", "", d.Seconds())
+	add("torn_oc_sheet_writes_total", "Writes of report tabs to Google Sheets.", "", 1)
+	set("torn_oc_sheet_write_last_duration_seconds", "Duration of the last write of report tabs to Google Sheets.", "", d.Seconds())
+}
+
+// Write renders every metric in the Prometheus text format.
+func Write(w io.Writer) error {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		m := metrics[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, m.help, name, m.kind)
+		labels := make([]string, 0, len(m.values))
+		for l := range m.values {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			series := name
+			if l != "" {
+				series += "{" + l + "}"
+			}
+			fmt.Fprintf(&b, "%s %s\n", series, strconv.FormatFloat(m.values[l], 'f', -1, 64))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
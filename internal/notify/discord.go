@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/mnuck/torn-oc-history/internal/discord"
+)
+
+// Discord posts notifications to a Discord channel webhook as embeds.
+type Discord struct {
+	URL string
+}
+
+func (d *Discord) Notify(ctx context.Context, title, message string) error {
+	w := &discord.Webhook{URL: d.URL}
+	return w.Send(ctx, discord.Message{Embeds: []discord.Embed{{
+		Title:       truncate(title, discord.MaxTitle),
+		Description: truncate(message, discord.MaxDescription),
+	}}})
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
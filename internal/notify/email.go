@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends messages by SMTP, authenticating with PLAIN when a username is
+// set. The server must offer STARTTLS for the credentials to be sent.
+type Email struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (e *Email) Notify(ctx context.Context, title, message string) error {
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+	to := strings.Join(e.To, ", ")
+	msg := "From: " + e.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + strings.ReplaceAll(title, "\n", " ") + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + strings.ReplaceAll(message, "\n", "\r\n") + "\r\n"
+
+	// smtp.SendMail takes no context, so it runs until done or ctx ends
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(e.Host, e.Port), auth, e.From, e.To, []byte(msg))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email notification: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to send email notification: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Notifier delivers a short, high-signal push notification.
+type Notifier interface {
+	Notify(ctx context.Context, title, message string) error
+}
+
+// Multi fans a notification out to every configured notifier.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, title, message string) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, title, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FromEnv builds the notifiers configured through NTFY_*, PUSHOVER_*,
+// DISCORD_ALERT_WEBHOOK_URL, TELEGRAM_* and SMTP_* environment variables. An empty Multi means notifications are disabled.
+func FromEnv() Multi {
+	var m Multi
+	if topic := os.Getenv("NTFY_TOPIC"); topic != "" {
+		server := os.Getenv("NTFY_SERVER")
+		if server == "" {
+			server = "https://ntfy.sh"
+		}
+		m = append(m, &Ntfy{Server: server, Topic: topic, Token: os.Getenv("NTFY_TOKEN")})
+	}
+	token, user := os.Getenv("PUSHOVER_TOKEN"), os.Getenv("PUSHOVER_USER")
+	if token != "" && user != "" {
+		m = append(m, &Pushover{Token: token, User: user})
+	}
+	if url := os.Getenv("DISCORD_ALERT_WEBHOOK_URL"); url != "" {
+		m = append(m, &Discord{URL: url})
+	}
+	token, chat := os.Getenv("TELEGRAM_BOT_TOKEN"), os.Getenv("TELEGRAM_CHAT_ID")
+	if token != "" && chat != "" {
+		m = append(m, &Telegram{Token: token, ChatID: chat})
+	}
+	if host, to := os.Getenv("SMTP_HOST"), os.Getenv("ALERT_EMAIL_TO"); host != "" && to != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		from := os.Getenv("ALERT_EMAIL_FROM")
+		if from == "" {
+			from = os.Getenv("SMTP_USERNAME")
+		}
+		e := &Email{Host: host, Port: port, Username: os.Getenv("SMTP_USERNAME"), Password: os.Getenv("SMTP_PASSWORD"), From: from}
+		for _, addr := range strings.Split(to, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				e.To = append(e.To, addr)
+			}
+		}
+		m = append(m, e)
+	}
+	return m
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Ntfy publishes messages to an ntfy.sh (or self-hosted ntfy) topic.
+type Ntfy struct {
+	Server string
+	Topic  string
+	Token  string
+}
+
+func (n *Ntfy) Notify(ctx context.Context, title, message string) error {
+	url := strings.TrimRight(n.Server, "/") + "/" + n.Topic
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(message))
+	if err != nil {
+		return fmt.Errorf("failed to build ntfy request: %w", err)
+	}
+	req.Header.Set("Title", title)
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send ntfy notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ntfy bad status: %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const pushoverURL = "https://api.pushover.net/1/messages.json"
+
+// Pushover sends messages through the Pushover API.
+type Pushover struct {
+	Token string
+	User  string
+}
+
+func (p *Pushover) Notify(ctx context.Context, title, message string) error {
+	form := url.Values{
+		"token":   {p.Token},
+		"user":    {p.User},
+		"title":   {title},
+		"message": {message},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushoverURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build pushover request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send pushover notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("pushover bad status: %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const telegramURL = "https://api.telegram.org/bot%s/sendMessage"
+
+// Telegram sends messages to a chat through a Telegram bot.
+type Telegram struct {
+	Token  string
+	ChatID string
+}
+
+func (t *Telegram) Notify(ctx context.Context, title, message string) error {
+	form := url.Values{
+		"chat_id": {t.ChatID},
+		"text":    {title + "\n\n" + message},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(telegramURL, t.Token), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// the URL holds the bot token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send telegram notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("telegram bad status: %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
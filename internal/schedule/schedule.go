@@ -0,0 +1,177 @@
+// Package schedule parses cron expressions and finds the times they match.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week.
+type Schedule struct {
+	minute, hour, dom, month, dow []bool
+	// as in cron, when both day fields are restricted either may match
+	domAny, dowAny bool
+	loc            *time.Location
+}
+
+// macros are the @ shorthands cron implementations commonly accept.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// Parse parses a cron expression such as "0 18 * * *" or "*/15 * * * sat,sun"
+// whose times are in loc. Each field takes *, numbers, ranges (1-5), lists
+// (1,3,5) and steps (*/15, 0-30/10); months and days of the week also take
+// three-letter names, and Sunday is 0 or 7.
+func Parse(spec string, loc *time.Location) (*Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q: want 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(fields))
+	}
+
+	s := &Schedule{loc: loc}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("%q: minute: %w", spec, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("%q: hour: %w", spec, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("%q: day of month: %w", spec, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("%q: month: %w", spec, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("%q: day of week: %w", spec, err)
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.domAny, s.dowAny = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseField returns which values from lo to hi the field matches, indexed by
+// value. names, if any, stand for lo, lo+1 and so on.
+func parseField(field string, lo, hi int, names []string) ([]bool, error) {
+	match := make([]bool, hi+1)
+	value := func(s string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				return lo + i, nil
+			}
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < lo || n > hi {
+			return 0, fmt.Errorf("%q is not a number from %d to %d", s, lo, hi)
+		}
+		return n, nil
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+
+		first, last := lo, hi
+		switch from, to, isRange := strings.Cut(rng, "-"); {
+		case rng == "*":
+		case isRange:
+			var err error
+			if first, err = value(from); err != nil {
+				return nil, err
+			}
+			if last, err = value(to); err != nil {
+				return nil, err
+			}
+			if first > last {
+				return nil, fmt.Errorf("range %q runs backwards", rng)
+			}
+		default:
+			n, err := value(rng)
+			if err != nil {
+				return nil, err
+			}
+			first = n
+			if !hasStep {
+				last = n
+			}
+		}
+		for v := first; v <= last; v += step {
+			match[v] = true
+		}
+	}
+	return match, nil
+}
+
+// Next returns the first time after t that the schedule matches, in the
+// schedule's location, or the zero time if there is none in the next five
+// years (e.g. for February 30th).
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, mo, d := t.Date()
+		switch {
+		case !s.month[mo]:
+			t = forward(t, time.Date(y, mo+1, 1, 0, 0, 0, 0, s.loc))
+		case !s.dayMatches(t):
+			t = forward(t, time.Date(y, mo, d+1, 0, 0, 0, 0, s.loc))
+		case !s.hour[t.Hour()]:
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// forward returns next, unless a daylight saving change made it a time that
+// doesn't exist and Date normalized it to one before t; then an hour after t.
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Hour)
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[t.Weekday()]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
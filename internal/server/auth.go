@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Scopes are what a token can be limited to, each covering some endpoints;
+// refresh is the only one that does more than read. The health checks, and
+// the chat commands, which are signed, stay open.
+var Scopes = []string{"dashboard", "grafana", "feeds", "events", "graphql", "metrics", "grpc", "refresh"}
+
+// Token is a bearer token and the scopes it may read; all of them if none.
+type Token struct {
+	Secret string
+	Scopes []string
+}
+
+// ParseTokens parses a comma-separated list of tokens, each optionally
+// followed by a colon and the scopes it is limited to, joined by "+":
+// "s3cret,grafana-token:grafana+metrics".
+func ParseTokens(s string) ([]Token, error) {
+	var tokens []Token
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		secret, scopes, _ := strings.Cut(item, ":")
+		if secret == "" {
+			return nil, fmt.Errorf("a token with scopes %q is empty", scopes)
+		}
+		t := Token{Secret: secret}
+		if scopes != "" {
+			for _, scope := range strings.Split(scopes, "+") {
+				if !slices.Contains(Scopes, scope) {
+					return nil, fmt.Errorf("unknown scope %q; the scopes are %s", scope, strings.Join(Scopes, ", "))
+				}
+				t.Scopes = append(t.Scopes, scope)
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+// auth holds the credentials requests must present, once enabled.
+type auth struct {
+	tokens         []Token
+	user, password string
+}
+
+// EnableAuth requires the endpoints but the health checks and chat commands
+// to be called with one of tokens, as a bearer token or a basic auth
+// password, or with the basic auth user and password if user isn't empty.
+func (s *Server) EnableAuth(tokens []Token, user, password string) {
+	s.auth = &auth{tokens: tokens, user: user, password: password}
+	for _, sub := range s.factions {
+		sub.auth = s.auth
+	}
+}
+
+// check reports whether the Authorization header authz holds known
+// credentials, and whether they may read scope.
+func (a *auth) check(authz, scope string) (known, allowed bool) {
+	secret, ok := strings.CutPrefix(authz, "Bearer ")
+	if !ok {
+		r := http.Request{Header: http.Header{"Authorization": {authz}}}
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			return false, false
+		}
+		if a.user != "" && equal(user, a.user) && equal(password, a.password) {
+			return true, true
+		}
+		secret = password
+	}
+	for _, t := range a.tokens {
+		if equal(secret, t.Secret) {
+			return true, len(t.Scopes) == 0 || slices.Contains(t.Scopes, scope)
+		}
+	}
+	return false, false
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// scoped serves h only to requests whose credentials may read scope, once
+// auth is enabled. Browsers are asked for basic auth, with which a token
+// works as the password.
+func (s *Server) scoped(scope string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			h(w, r)
+			return
+		}
+		known, allowed := s.auth.check(r.Header.Get("Authorization"), scope)
+		switch {
+		case !known:
+			w.Header().Set("WWW-Authenticate", `Basic realm="torn-oc-history", charset="UTF-8"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		case !allowed:
+			http.Error(w, fmt.Sprintf("the token may not read %s", scope), http.StatusForbidden)
+		default:
+			h(w, r)
+		}
+	}
+}
+
+// authenticated reports whether a request holds known credentials, of any
+// scope, or authentication is off.
+func (s *Server) authenticated(r *http.Request) bool {
+	if s.auth == nil {
+		return true
+	}
+	known, _ := s.auth.check(r.Header.Get("Authorization"), "")
+	return known
+}
+
+// checkGRPC is scoped for an RPC, reading the authorization metadata.
+func (s *Server) checkGRPC(ctx context.Context) error {
+	if s.auth == nil {
+		return nil
+	}
+	var authz string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) > 0 {
+			authz = v[0]
+		}
+	}
+	known, allowed := s.auth.check(authz, "grpc")
+	switch {
+	case !known:
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	case !allowed:
+		return status.Error(codes.PermissionDenied, "the token may not read grpc")
+	}
+	return nil
+}
+
+func (s *Server) grpcUnaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.checkGRPC(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) grpcStreamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.checkGRPC(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+const icsTime = "20060102T150405Z"
+
+// handleCalendar serves ready/expiry times of recruiting and planning crimes
+// as an iCalendar feed.
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	snap := s.store.Snapshot()
+	stamp := snap.FetchedAt.UTC().Format(icsTime)
+
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//torn-oc-history//EN")
+	writeICSLine(&b, "X-WR-CALNAME:Torn OC")
+	for _, c := range snap.Active {
+		if c.ReadyAt > 0 {
+			writeICSEvent(&b, fmt.Sprintf("crime-%d-ready@torn-oc-history", c.ID), stamp, c.ReadyAt,
+				"OC ready: "+c.Name, crimeDescription(c))
+		}
+		if c.ExpiredAt > 0 {
+			writeICSEvent(&b, fmt.Sprintf("crime-%d-expiry@torn-oc-history", c.ID), stamp, c.ExpiredAt,
+				"OC expires: "+c.Name, crimeDescription(c))
+		}
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func crimeDescription(c torn.Crime) string {
+	open := 0
+	for _, slot := range c.Slots {
+		if slot.User.ID == 0 {
+			open++
+		}
+	}
+	return fmt.Sprintf("Difficulty %d, status %s, %d of %d slots open", c.Difficulty, c.Status, open, len(c.Slots))
+}
+
+func writeICSEvent(b *strings.Builder, uid, stamp string, at int64, summary, description string) {
+	start := time.Unix(at, 0).UTC()
+	writeICSLine(b, "BEGIN:VEVENT")
+	writeICSLine(b, "UID:"+uid)
+	writeICSLine(b, "DTSTAMP:"+stamp)
+	writeICSLine(b, "DTSTART:"+start.Format(icsTime))
+	writeICSLine(b, "DTEND:"+start.Add(15*time.Minute).Format(icsTime))
+	writeICSLine(b, "SUMMARY:"+escapeICS(summary))
+	writeICSLine(b, "DESCRIPTION:"+escapeICS(description))
+	writeICSLine(b, "END:VEVENT")
+}
+
+func escapeICS(v string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(v)
+}
+
+// writeICSLine writes a content line, folding it at 75 octets as RFC 5545 requires.
+func writeICSLine(b *strings.Builder, line string) {
+	// continuation lines start with a space, which counts toward the limit
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// don't split a multi-byte UTF-8 sequence
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
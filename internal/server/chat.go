@@ -0,0 +1,141 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/discord"
+)
+
+// Answerer answers a chat command such as "cpr", given its argument, from the
+// latest data. The caller renders the reports, so it supplies the answers.
+type Answerer func(command, arg string) string
+
+// maxCommandBody bounds the request bodies of the chat command endpoints.
+const maxCommandBody = 64 << 10
+
+// EnableDiscord serves Discord slash commands at POST /discord/interactions,
+// checking each request against the application's public key.
+func (s *Server) EnableDiscord(key ed25519.PublicKey, answer Answerer) {
+	s.discordKey, s.answer = key, answer
+}
+
+func (s *Server) handleDiscordInteraction(w http.ResponseWriter, r *http.Request) {
+	if s.discordKey == nil {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCommandBody))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !discord.Verify(s.discordKey, r, body) {
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+	var in discord.Interaction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	switch in.Type {
+	case discord.InteractionPing:
+		writeJSON(w, discord.InteractionResponse{Type: discord.CallbackPong})
+	case discord.InteractionApplicationCommand:
+		answer := s.answer(in.Data.Name, in.Option("member"))
+		writeJSON(w, discord.InteractionResponse{Type: discord.CallbackMessage, Data: &discord.InteractionData{
+			Content: codeBlock(answer, discord.MaxContent),
+			Flags:   discord.FlagEphemeral,
+		}})
+	default:
+		http.Error(w, "unsupported interaction", http.StatusBadRequest)
+	}
+}
+
+// EnableSlack serves a Slack slash command at POST /slack/command, checking
+// each request against the app's signing secret.
+func (s *Server) EnableSlack(signingSecret string, answer Answerer) {
+	s.slackSecret, s.answer = signingSecret, answer
+}
+
+// slackMaxSkew is how old a Slack request may be, against replays.
+const slackMaxSkew = 5 * time.Minute
+
+// slackMaxText is how long a Slack answer may be.
+const slackMaxText = 3000
+
+// handleSlackCommand answers the slash command: with "notinoc" or
+// "openslots" those lists, otherwise the CPR of the member named.
+func (s *Server) handleSlackCommand(w http.ResponseWriter, r *http.Request) {
+	if s.slackSecret == "" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCommandBody))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !verifySlack(s.slackSecret, r, body, time.Now()) {
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	text := strings.TrimSpace(form.Get("text"))
+	var answer string
+	switch strings.ToLower(text) {
+	case "notinoc", "openslots":
+		answer = s.answer(strings.ToLower(text), "")
+	default:
+		answer = s.answer("cpr", text)
+	}
+	writeJSON(w, map[string]string{"response_type": "ephemeral", "text": codeBlock(answer, slackMaxText)})
+}
+
+// verifySlack checks the signature Slack puts on each request: an HMAC of
+// the timestamp and body under the signing secret.
+func verifySlack(secret string, r *http.Request, body []byte, now time.Time) bool {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || now.Sub(time.Unix(sec, 0)).Abs() > slackMaxSkew {
+		return false
+	}
+	sig, ok := strings.CutPrefix(r.Header.Get("X-Slack-Signature"), "v0=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// codeBlock fences text for chat, cut to fit in limit characters.
+func codeBlock(text string, limit int) string {
+	const fence = "```"
+	// the fences and their newlines
+	room := limit - 2*len(fence) - 2
+	text = strings.TrimRight(text, "\n")
+	if r := []rune(text); len(r) > room {
+		text = string(r[:room-2]) + "\n…"
+	}
+	return fence + "\n" + text + "\n" + fence
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/store"
+)
+
+// web holds the dashboard's page, script and stylesheet, which render the
+// JSON of /dashboard/data.json in the browser.
+//
+//go:embed web
+var web embed.FS
+
+// dashboardData is the dashboard's view of the snapshot: every member with
+// their latest pass rate per difficulty and position, and the crimes
+// executed per week.
+type dashboardData struct {
+	FetchedAt string            `json:"fetched_at,omitempty"`
+	Label     string            `json:"label,omitempty"`
+	CPRLow    int               `json:"cpr_low"`
+	CPRHigh   int               `json:"cpr_high"`
+	Total     int               `json:"total"` // members listed before offset and limit
+	Members   []dashboardMember `json:"members"`
+	Weeks     []dashboardWeek   `json:"weeks"`
+}
+
+type dashboardMember struct {
+	ID         int             `json:"id"`
+	Name       string          `json:"name"`
+	InOC       bool            `json:"in_oc"`
+	LastAction string          `json:"last_action,omitempty"`
+	Rates      []dashboardRate `json:"rates"`
+}
+
+type dashboardRate struct {
+	Difficulty int    `json:"difficulty"`
+	Position   string `json:"position"`
+	Rate       int    `json:"rate"`
+	// PrevRate is the rate of the observation before, when there was one
+	PrevRate   *int   `json:"prev_rate,omitempty"`
+	ExecutedAt int64  `json:"executed_at"`
+	CrimeName  string `json:"crime_name"`
+}
+
+type dashboardWeek struct {
+	Week       string `json:"week"`
+	Successful int    `json:"successful"`
+	Failed     int    `json:"failed"`
+}
+
+// dashboardFiles serves the embedded dashboard under /dashboard/.
+func dashboardFiles() http.Handler {
+	sub, err := fs.Sub(web, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/dashboard/", http.FileServerFS(sub))
+}
+
+// WriteDashboard writes the dashboard of the store's snapshot to dir as a
+// static site: its page, script and stylesheet along with data.json, for
+// publishing without a server. Each file is replaced whole, so a host
+// serving dir never sees one half written.
+func WriteDashboard(dir string, st *store.Store) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	dashboard, err := buildDashboard(st.Snapshot(), st.Index(), listQuery{})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(dashboard)
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{"data.json": data}
+	entries, err := fs.ReadDir(web, "web")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if files[e.Name()], err = fs.ReadFile(web, "web/"+e.Name()); err != nil {
+			return err
+		}
+	}
+	for name, b := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path+".tmp", b, 0o644); err != nil {
+			return err
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleDashboardData serves the data the dashboard renders. The list
+// parameters narrow it down, such as to one member with ?id=, for clients
+// that don't need all of it.
+func (s *Server) handleDashboardData(w http.ResponseWriter, r *http.Request) {
+	q, err := parseListQuery(r.URL.Query())
+	if err == nil {
+		q.members, err = parseMemberQuery(r.URL.Query())
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := buildDashboard(s.store.Snapshot(), s.store.Index(), q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, data)
+}
+
+// buildDashboard keeps, like the reports, the pass rate of each member's
+// most recently executed crime at every difficulty and position, along with
+// the one before it; and counts the crimes executed per week. Members left
+// without rates by the query's rate filter are dropped.
+func buildDashboard(snap store.Snapshot, x *store.Index, q listQuery) (dashboardData, error) {
+	weeks := make(map[string]*dashboardWeek)
+	for _, c := range snap.Crimes {
+		if c.ExecutedAt == 0 || c.ExecutedAt < q.rates.since {
+			continue
+		}
+		t := time.Unix(c.ExecutedAt, 0).UTC().Truncate(24 * time.Hour)
+		week := t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7)).Format("2006-01-02")
+		if weeks[week] == nil {
+			weeks[week] = &dashboardWeek{Week: week}
+		}
+		if strings.EqualFold(c.Status, "Successful") {
+			weeks[week].Successful++
+		} else {
+			weeks[week].Failed++
+		}
+	}
+
+	data := dashboardData{Label: snap.Label, CPRLow: snap.CPRLow, CPRHigh: snap.CPRHigh, Members: []dashboardMember{}, Weeks: []dashboardWeek{}}
+	if !snap.FetchedAt.IsZero() {
+		data.FetchedAt = snap.FetchedAt.UTC().Format(time.RFC3339)
+	}
+	members := q.members.apply(snap.Members)
+	slices.SortStableFunc(members, memberSorts["name"])
+	members, err := sortBy(members, q.sort, memberSorts)
+	if err != nil {
+		return data, err
+	}
+	for _, m := range members {
+		rates := q.rates.apply(x.Rates(m.ID))
+		if len(rates) == 0 && q.rates != (rateFilter{}) {
+			continue
+		}
+		r := []dashboardRate{}
+		for _, rate := range rates {
+			r = append(r, dashboardRate(rate))
+		}
+		data.Members = append(data.Members, dashboardMember{ID: m.ID, Name: m.Name, InOC: m.IsInOC, LastAction: m.LastAction.Relative, Rates: r})
+	}
+	data.Total = len(data.Members)
+	data.Members = append([]dashboardMember{}, page(data.Members, q.offset, q.limit)...)
+	for _, w := range weeks {
+		data.Weeks = append(data.Weeks, *w)
+	}
+	sort.Slice(data.Weeks, func(i, j int) bool { return data.Weeks[i].Week < data.Weeks[j].Week })
+	return data, nil
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// eventsHeartbeat is how often an idle event stream sends a comment, so
+// proxies don't close it.
+const eventsHeartbeat = 30 * time.Second
+
+// crimeEvent is the data of a "crime" event: a completed crime with the names
+// of its participants.
+type crimeEvent struct {
+	ID         int           `json:"id"`
+	Name       string        `json:"name"`
+	Difficulty int           `json:"difficulty"`
+	Status     string        `json:"status"`
+	ExecutedAt int64         `json:"executed_at"`
+	Rewards    *torn.Rewards `json:"rewards,omitempty"`
+	Slots      []eventSlot   `json:"slots"`
+}
+
+type eventSlot struct {
+	Position string `json:"position"`
+	MemberID int    `json:"member_id"`
+	Member   string `json:"member,omitempty"`
+	CPR      int    `json:"cpr"`
+	Outcome  string `json:"outcome,omitempty"`
+}
+
+// handleEvents streams a server-sent "crime" event for every completed crime
+// a run finds that the previous one hadn't. A client reconnecting with
+// Last-Event-ID first gets the crimes executed after that one.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	updates, unsubscribe := s.store.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	snap := s.store.Snapshot()
+	names := make(map[int]string, len(snap.Members))
+	for _, m := range snap.Members {
+		names[m.ID] = m.Name
+	}
+	if last, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		if !s.writeCrimeEvents(w, missedCrimes(snap.Crimes, last), names) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case crimes := <-updates:
+			snap := s.store.Snapshot()
+			clear(names)
+			for _, m := range snap.Members {
+				names[m.ID] = m.Name
+			}
+			if !s.writeCrimeEvents(w, crimes, names) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// missedCrimes are the crimes executed after the one with ID last, oldest
+// first; none if it isn't among them.
+func missedCrimes(crimes []torn.Crime, last int) []torn.Crime {
+	var since int64 = -1
+	for _, c := range crimes {
+		if c.ID == last {
+			since = c.ExecutedAt
+		}
+	}
+	if since < 0 {
+		return nil
+	}
+	var missed []torn.Crime
+	for _, c := range crimes {
+		if c.ExecutedAt > since && c.ID != last {
+			missed = append(missed, c)
+		}
+	}
+	sort.Slice(missed, func(i, j int) bool { return missed[i].ExecutedAt < missed[j].ExecutedAt })
+	return missed
+}
+
+// writeCrimeEvents writes an event per crime, reporting whether the client
+// is still there.
+func (s *Server) writeCrimeEvents(w http.ResponseWriter, crimes []torn.Crime, names map[int]string) bool {
+	for _, c := range crimes {
+		e := crimeEvent{ID: c.ID, Name: c.Name, Difficulty: c.Difficulty, Status: c.Status, ExecutedAt: c.ExecutedAt, Rewards: c.Rewards, Slots: []eventSlot{}}
+		for _, slot := range c.Slots {
+			e.Slots = append(e.Slots, eventSlot{Position: slot.Position, MemberID: slot.User.ID, Member: names[slot.User.ID], CPR: slot.CheckpointPassRate, Outcome: slot.User.Outcome})
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			slog.Error("encode event", "error", err)
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "event: crime\nid: %d\ndata: %s\n\n", c.ID, data); err != nil {
+			return false
+		}
+	}
+	return true
+}
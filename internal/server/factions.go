@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mnuck/torn-oc-history/internal/store"
+)
+
+// AddFaction serves the data of another faction, kept in st, under
+// /f/<name>/: every endpoint but the chat commands, with the same
+// credentials. A refresh there runs every faction. gRPC clients pick it with
+// the faction metadata.
+func (s *Server) AddFaction(name string, st *store.Store) {
+	sub := &Server{store: st, mux: http.NewServeMux(), started: s.started, closing: s.closing, auth: s.auth, refresh: s.refresh}
+	sub.routes()
+	if s.factions == nil {
+		s.factions = make(map[string]*Server)
+	}
+	s.factions[name] = sub
+	s.mux.Handle("/f/"+name+"/", mount("/f/"+name, sub.mux))
+}
+
+// storeFor is the store of the faction an RPC's metadata names, or of the
+// server's own without one.
+func (s *Server) storeFor(ctx context.Context) (*store.Store, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	names := md.Get("faction")
+	if len(names) == 0 || names[0] == "" {
+		return s.store, nil
+	}
+	sub, ok := s.factions[names[0]]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no faction %q", names[0])
+	}
+	return sub.store, nil
+}
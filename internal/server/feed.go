@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+const feedEntries = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// handleFeed serves the most recently executed crimes as an Atom feed.
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	snap := s.store.Snapshot()
+	names := make(map[int]string, len(snap.Members))
+	for _, m := range snap.Members {
+		names[m.ID] = m.Name
+	}
+
+	crimes := make([]torn.Crime, len(snap.Crimes))
+	copy(crimes, snap.Crimes)
+	sort.Slice(crimes, func(i, j int) bool { return crimes[i].ExecutedAt > crimes[j].ExecutedAt })
+	if len(crimes) > feedEntries {
+		crimes = crimes[:feedEntries]
+	}
+
+	feed := atomFeed{
+		ID:      "urn:torn-oc-history:crimes",
+		Title:   "Torn OC History - completed crimes",
+		Updated: snap.FetchedAt.UTC().Format(time.RFC3339),
+		Link:    atomLink{Rel: "self", Href: baseURL(r) + "/feed.atom"},
+	}
+	if snap.Label != "" {
+		feed.Title += " [" + snap.Label + "]"
+	}
+	for _, c := range crimes {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      fmt.Sprintf("urn:torn-oc-history:crime:%d", c.ID),
+			Title:   fmt.Sprintf("%s (difficulty %d) - %s", c.Name, c.Difficulty, c.Status),
+			Updated: time.Unix(c.ExecutedAt, 0).UTC().Format(time.RFC3339),
+			Content: atomContent{Type: "text", Body: crimeSummary(c, names)},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		slog.Error("encode feed", "error", err)
+	}
+}
+
+func crimeSummary(c torn.Crime, names map[int]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Outcome: %s\n", c.Status)
+	if c.Rewards != nil {
+		fmt.Fprintf(&b, "Payout: $%d, %d respect\n", c.Rewards.Money, c.Rewards.Respect)
+	}
+	b.WriteString("Participants:\n")
+	for _, slot := range c.Slots {
+		name, ok := names[slot.User.ID]
+		if !ok {
+			name = fmt.Sprintf("[%d]", slot.User.ID)
+		}
+		fmt.Fprintf(&b, "  %s: %s (%d%%)", slot.Position, name, slot.CheckpointPassRate)
+		if slot.User.Outcome != "" {
+			fmt.Fprintf(&b, " - %s", slot.User.Outcome)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
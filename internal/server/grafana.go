@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/store"
+)
+
+// The handlers below implement the Grafana simple-JSON datasource contract.
+// Each current faction member is exposed as a "<name> [<id>]" target whose
+// datapoints are the checkpoint pass rates of every slot they filled.
+
+type grafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+type grafanaSeries struct {
+	Target     string     `json:"target"`
+	Datapoints [][2]int64 `json:"datapoints"`
+}
+
+func (s *Server) handleGrafanaTest(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	var req grafanaSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	filter := strings.ToLower(req.Target)
+	targets := []string{}
+	for _, name := range memberTargets(s.store.Snapshot()) {
+		if strings.Contains(strings.ToLower(name), filter) {
+			targets = append(targets, name)
+		}
+	}
+	sort.Strings(targets)
+	writeJSON(w, targets)
+}
+
+func (s *Server) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req grafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	snap := s.store.Snapshot()
+	names := memberTargets(snap)
+	from, to := req.Range.From.Unix(), req.Range.To.Unix()
+
+	series := make([]grafanaSeries, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		id, ok := targetMember(names, t.Target)
+		if !ok {
+			continue
+		}
+		gs := grafanaSeries{Target: t.Target, Datapoints: [][2]int64{}}
+		for _, c := range snap.Crimes {
+			if c.ExecutedAt < from || c.ExecutedAt > to {
+				continue
+			}
+			for _, slot := range c.Slots {
+				if slot.User.ID == id {
+					gs.Datapoints = append(gs.Datapoints, [2]int64{int64(slot.CheckpointPassRate), c.ExecutedAt * 1000})
+				}
+			}
+		}
+		sort.Slice(gs.Datapoints, func(i, j int) bool { return gs.Datapoints[i][1] < gs.Datapoints[j][1] })
+		series = append(series, gs)
+	}
+	writeJSON(w, series)
+}
+
+// memberTargets maps member IDs to their Grafana target names.
+func memberTargets(snap store.Snapshot) map[int]string {
+	names := make(map[int]string, len(snap.Members))
+	for _, m := range snap.Members {
+		names[m.ID] = fmt.Sprintf("%s [%d]", m.Name, m.ID)
+	}
+	return names
+}
+
+func targetMember(names map[int]string, target string) (int, bool) {
+	for id, name := range names {
+		if name == target {
+			return id, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,386 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/graphql"
+	"github.com/mnuck/torn-oc-history/internal/store"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// maxQueryBody bounds the request body of POST /graphql.
+const maxQueryBody = 64 << 10
+
+// graphqlSchema describes what /graphql answers. Introspection isn't
+// supported, so this is published at /graphql/schema.graphql for clients and
+// code generators.
+const graphqlSchema = `# Lists are sorted ascending by sort, or descending by it prefixed with "-",
+# such as "-executed_at". A page of a list skips offset items and has at most
+# limit: 100 unless given, and no more than 1000. Queries can nest 10 levels
+# deep and resolve 50000 fields.
+type Query {
+  "When the data was fetched, RFC 3339; null before the first run."
+  fetchedAt: String
+  label: String
+  """
+  Members by name, ignoring case, and whether they are in an OC, by name
+  unless sorted by id or last_action.
+  """
+  members(name: String, inOC: Boolean, sort: String, offset: Int, limit: Int): [Member!]!
+  member(id: Int!): Member
+  """
+  Completed crimes, newest first unless sorted by executed_at, difficulty,
+  name or id, executed at or after since (Unix seconds). position and
+  memberId keep the crimes with a slot of the position or member.
+  """
+  crimes(status: String, since: Int, difficulty: Int, position: String, memberId: Int, sort: String, offset: Int, limit: Int): [Crime!]!
+  "The crimes recruiting or planning."
+  activeCrimes(difficulty: Int, position: String, sort: String, offset: Int, limit: Int): [Crime!]!
+}
+
+type Member {
+  id: Int!
+  name: String!
+  inOC: Boolean!
+  lastAction: String
+  """
+  The latest pass rate at every difficulty and position observed at or
+  after since, in that order unless sorted by cpr or executed_at.
+  """
+  stats(difficulty: Int, position: String, since: Int, sort: String): [Stat!]!
+  "The completed crimes the member took part in, newest first."
+  crimes(status: String, since: Int, difficulty: Int, position: String, sort: String, offset: Int, limit: Int): [Crime!]!
+}
+
+type Stat {
+  difficulty: Int!
+  position: String!
+  cpr: Int!
+  "The pass rate of the observation before, if there was one."
+  previousCpr: Int
+  executedAt: Int!
+  crimeName: String!
+}
+
+type Crime {
+  id: Int!
+  name: String!
+  difficulty: Int!
+  status: String!
+  readyAt: Int
+  executedAt: Int
+  expiredAt: Int
+  money: Float
+  respect: Int
+  slots: [Slot!]!
+}
+
+type Slot {
+  position: String!
+  cpr: Int!
+  outcome: String
+  "0 for an open slot."
+  memberId: Int!
+  member: Member
+}
+`
+
+// handleGraphQL answers a GraphQL query, posted as JSON or, for GET, in the
+// query, operationName and variables parameters. A GET without a query
+// serves the schema.
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxQueryBody))
+		if err != nil || json.Unmarshal(body, &req) != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if req.Query == "" {
+			s.handleGraphQLSchema(w, r)
+			return
+		}
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	writeJSON(w, graphql.Execute(newGQLQuery(s.store.Snapshot(), s.store.Index()), req))
+}
+
+func (s *Server) handleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, graphqlSchema)
+}
+
+// gqlData is the snapshot a query resolves against, with the lookups its
+// fields share.
+type gqlData struct {
+	snap    store.Snapshot
+	index   *store.Index
+	members map[int]torn.Member
+}
+
+// gqlQuery is the root Query type.
+type gqlQuery struct{ d *gqlData }
+
+func newGQLQuery(snap store.Snapshot, x *store.Index) gqlQuery {
+	d := &gqlData{snap: snap, index: x, members: make(map[int]torn.Member, len(snap.Members))}
+	for _, m := range snap.Members {
+		d.members[m.ID] = m
+	}
+	return gqlQuery{d}
+}
+
+func (q gqlQuery) TypeName() string { return "Query" }
+
+func (q gqlQuery) Field(name string, args graphql.Args) (any, error) {
+	switch name {
+	case "fetchedAt":
+		if q.d.snap.FetchedAt.IsZero() {
+			return nil, nil
+		}
+		return q.d.snap.FetchedAt.UTC().Format(time.RFC3339), nil
+	case "label":
+		return q.d.snap.Label, nil
+	case "members":
+		f := memberFilter{}
+		f.name, _ = args.String("name")
+		if inOC, ok := args.Bool("inOC"); ok {
+			f.inOC = &inOC
+		}
+		members := f.apply(q.d.snap.Members)
+		slices.SortStableFunc(members, memberSorts["name"])
+		sortSpec, _ := args.String("sort")
+		members, err := sortBy(members, sortSpec, memberSorts)
+		if err != nil {
+			return nil, err
+		}
+		offset, limit, err := gqlPage(args)
+		if err != nil {
+			return nil, err
+		}
+		list := []graphql.Object{}
+		for _, m := range page(members, offset, limit) {
+			list = append(list, gqlMember{q.d, m})
+		}
+		return list, nil
+	case "member":
+		id, ok := args.Int("id")
+		if !ok {
+			return nil, fmt.Errorf("member needs an id")
+		}
+		m, ok := q.d.members[id]
+		if !ok {
+			return nil, nil
+		}
+		return gqlMember{q.d, m}, nil
+	case "crimes":
+		since, _ := args.Int("since")
+		return q.d.crimes(q.d.index.Crimes(int64(since)), args)
+	case "activeCrimes":
+		return q.d.crimes(q.d.snap.Active, args)
+	}
+	return nil, graphql.ErrNoField
+}
+
+// crimes lists the crimes, in their order unless sorted, that the status,
+// since, difficulty, position and memberId arguments keep, and the page of
+// them offset and limit ask for.
+func (d *gqlData) crimes(crimes []torn.Crime, args graphql.Args) ([]graphql.Object, error) {
+	var f crimeFilter
+	f.status, _ = args.String("status")
+	f.position, _ = args.String("position")
+	f.difficulty, _ = args.Int("difficulty")
+	f.member, _ = args.Int("memberId")
+	since, _ := args.Int("since")
+	f.since = int64(since)
+	sortSpec, _ := args.String("sort")
+	crimes, err := sortBy(f.apply(crimes), sortSpec, crimeSorts)
+	if err != nil {
+		return nil, err
+	}
+	offset, limit, err := gqlPage(args)
+	if err != nil {
+		return nil, err
+	}
+	list := []graphql.Object{}
+	for _, c := range page(crimes, offset, limit) {
+		list = append(list, gqlCrime{d, c})
+	}
+	return list, nil
+}
+
+// The limit of GraphQL lists when none is given, and the most allowed.
+const (
+	gqlDefaultLimit = 100
+	gqlMaxLimit     = 1000
+)
+
+// gqlPage reads the offset and limit arguments of a list.
+func gqlPage(args graphql.Args) (offset, limit int, err error) {
+	offset, _ = args.Int("offset")
+	limit, ok := args.Int("limit")
+	switch {
+	case !ok:
+		limit = gqlDefaultLimit
+	case limit < 1 || limit > gqlMaxLimit:
+		return 0, 0, fmt.Errorf("limit must be from 1 to %d", gqlMaxLimit)
+	}
+	return offset, limit, nil
+}
+
+type gqlMember struct {
+	d *gqlData
+	m torn.Member
+}
+
+func (m gqlMember) TypeName() string { return "Member" }
+
+func (m gqlMember) Field(name string, args graphql.Args) (any, error) {
+	switch name {
+	case "id":
+		return m.m.ID, nil
+	case "name":
+		return m.m.Name, nil
+	case "inOC":
+		return m.m.IsInOC, nil
+	case "lastAction":
+		return m.m.LastAction.Relative, nil
+	case "stats":
+		var f rateFilter
+		f.difficulty, _ = args.Int("difficulty")
+		f.position, _ = args.String("position")
+		since, _ := args.Int("since")
+		f.since = int64(since)
+		sortSpec, _ := args.String("sort")
+		rates, err := sortBy(f.apply(m.d.index.Rates(m.m.ID)), sortSpec, rateSorts)
+		if err != nil {
+			return nil, err
+		}
+		list := []graphql.Object{}
+		for _, r := range rates {
+			list = append(list, gqlStat(r))
+		}
+		return list, nil
+	case "crimes":
+		return m.d.crimes(m.d.index.MemberCrimes(m.m.ID), args)
+	}
+	return nil, graphql.ErrNoField
+}
+
+type gqlStat store.Rate
+
+func (st gqlStat) TypeName() string { return "Stat" }
+
+func (st gqlStat) Field(name string, args graphql.Args) (any, error) {
+	switch name {
+	case "difficulty":
+		return st.Difficulty, nil
+	case "position":
+		return st.Position, nil
+	case "cpr":
+		return st.Rate, nil
+	case "previousCpr":
+		if st.PrevRate == nil {
+			return nil, nil
+		}
+		return *st.PrevRate, nil
+	case "executedAt":
+		return st.ExecutedAt, nil
+	case "crimeName":
+		return st.CrimeName, nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+type gqlCrime struct {
+	d *gqlData
+	c torn.Crime
+}
+
+func (c gqlCrime) TypeName() string { return "Crime" }
+
+func (c gqlCrime) Field(name string, args graphql.Args) (any, error) {
+	switch name {
+	case "id":
+		return c.c.ID, nil
+	case "name":
+		return c.c.Name, nil
+	case "difficulty":
+		return c.c.Difficulty, nil
+	case "status":
+		return c.c.Status, nil
+	case "readyAt":
+		return orNull(c.c.ReadyAt), nil
+	case "executedAt":
+		return orNull(c.c.ExecutedAt), nil
+	case "expiredAt":
+		return orNull(c.c.ExpiredAt), nil
+	case "money":
+		if c.c.Rewards == nil {
+			return nil, nil
+		}
+		return c.c.Rewards.Money, nil
+	case "respect":
+		if c.c.Rewards == nil {
+			return nil, nil
+		}
+		return c.c.Rewards.Respect, nil
+	case "slots":
+		list := []graphql.Object{}
+		for _, slot := range c.c.Slots {
+			list = append(list, gqlSlot{c.d, slot})
+		}
+		return list, nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// orNull makes an unset timestamp null.
+func orNull(t int64) any {
+	if t == 0 {
+		return nil
+	}
+	return t
+}
+
+type gqlSlot struct {
+	d    *gqlData
+	slot torn.Slot
+}
+
+func (s gqlSlot) TypeName() string { return "Slot" }
+
+func (s gqlSlot) Field(name string, args graphql.Args) (any, error) {
+	switch name {
+	case "position":
+		return s.slot.Position, nil
+	case "cpr":
+		return s.slot.CheckpointPassRate, nil
+	case "outcome":
+		if s.slot.User.Outcome == "" {
+			return nil, nil
+		}
+		return s.slot.User.Outcome, nil
+	case "memberId":
+		return s.slot.User.ID, nil
+	case "member":
+		m, ok := s.d.members[s.slot.User.ID]
+		if !ok {
+			return nil, nil
+		}
+		return gqlMember{s.d, m}, nil
+	}
+	return nil, graphql.ErrNoField
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mnuck/torn-oc-history/internal/store"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+	tornocv1 "github.com/mnuck/torn-oc-history/proto/tornoc/v1"
+)
+
+// grpcService serves the TornOC gRPC API from the same store as the HTTP
+// endpoints.
+type grpcService struct {
+	tornocv1.UnimplementedTornOCServer
+	s *Server
+}
+
+// ListenAndServeGRPC blocks serving the gRPC API on addr until Shutdown.
+func (s *Server) ListenAndServeGRPC(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("gRPC server listening", "addr", addr)
+	return s.grpc.Serve(ln)
+}
+
+func (g grpcService) Members(ctx context.Context, req *tornocv1.MembersRequest) (*tornocv1.MembersResponse, error) {
+	st, err := g.s.storeFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap := st.Snapshot()
+	members := memberFilter{name: req.Name, inOC: req.InOc}.apply(snap.Members)
+	slices.SortStableFunc(members, memberSorts["name"])
+	if members, err = sortBy(members, req.Sort, memberSorts); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &tornocv1.MembersResponse{FetchedAt: fetchedAt(snap.FetchedAt), Total: int32(len(members))}
+	for _, m := range page(members, int(req.Offset), int(req.Limit)) {
+		resp.Members = append(resp.Members, &tornocv1.Member{Id: int64(m.ID), Name: m.Name, InOc: m.IsInOC, LastAction: m.LastAction.Relative, LastActionAt: m.LastAction.Timestamp})
+	}
+	return resp, nil
+}
+
+// memberRate is a rate of the member it belongs to, as Stats lists them.
+type memberRate struct {
+	member int
+	store.Rate
+}
+
+func (g grpcService) Stats(ctx context.Context, req *tornocv1.StatsRequest) (*tornocv1.StatsResponse, error) {
+	st, err := g.s.storeFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap := st.Snapshot()
+	x := st.Index()
+	f := rateFilter{difficulty: int(req.Difficulty), position: req.Position, since: req.Since}
+	var rates []memberRate
+	for _, id := range x.RatedMembers() {
+		if req.MemberId != 0 && int64(id) != req.MemberId {
+			continue
+		}
+		for _, r := range f.apply(x.Rates(id)) {
+			rates = append(rates, memberRate{id, r})
+		}
+	}
+	orders := map[string]func(a, b memberRate) int{
+		"member_id": func(a, b memberRate) int { return cmp.Compare(a.member, b.member) },
+	}
+	for name, order := range rateSorts {
+		orders[name] = func(a, b memberRate) int { return order(a.Rate, b.Rate) }
+	}
+	if rates, err = sortBy(rates, req.Sort, orders); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &tornocv1.StatsResponse{FetchedAt: fetchedAt(snap.FetchedAt), Total: int32(len(rates))}
+	for _, r := range page(rates, int(req.Offset), int(req.Limit)) {
+		stat := &tornocv1.Stat{MemberId: int64(r.member), Difficulty: int32(r.Difficulty), Position: r.Position, Cpr: int32(r.Rate.Rate), ExecutedAt: r.ExecutedAt, CrimeName: r.CrimeName}
+		if r.PrevRate != nil {
+			prev := int32(*r.PrevRate)
+			stat.PreviousCpr = &prev
+		}
+		resp.Stats = append(resp.Stats, stat)
+	}
+	return resp, nil
+}
+
+func (g grpcService) Crimes(ctx context.Context, req *tornocv1.CrimesRequest) (*tornocv1.CrimesResponse, error) {
+	st, err := g.s.storeFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap := st.Snapshot()
+	f := crimeFilter{status: req.Status, difficulty: int(req.Difficulty), position: req.Position, member: int(req.MemberId)}
+	crimes := snap.Active
+	if !req.Active {
+		crimes = st.Index().Crimes(req.Since)
+	}
+	crimes, err = sortBy(f.apply(crimes), req.Sort, crimeSorts)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	names := memberNames(snap.Members)
+	resp := &tornocv1.CrimesResponse{FetchedAt: fetchedAt(snap.FetchedAt), Total: int32(len(crimes))}
+	for _, c := range page(crimes, int(req.Offset), int(req.Limit)) {
+		resp.Crimes = append(resp.Crimes, protoCrime(c, names))
+	}
+	return resp, nil
+}
+
+// StreamEvents sends what /events does: the crimes missed since
+// last_crime_id, then those of each run as it completes.
+func (g grpcService) StreamEvents(req *tornocv1.StreamEventsRequest, stream grpc.ServerStreamingServer[tornocv1.Crime]) error {
+	st, err := g.s.storeFor(stream.Context())
+	if err != nil {
+		return err
+	}
+	updates, unsubscribe := st.Subscribe()
+	defer unsubscribe()
+
+	send := func(crimes []torn.Crime) error {
+		names := memberNames(st.Snapshot().Members)
+		for _, c := range crimes {
+			if err := stream.Send(protoCrime(c, names)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if req.LastCrimeId != 0 {
+		if err := send(missedCrimes(st.Snapshot().Crimes, int(req.LastCrimeId))); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case crimes := <-updates:
+			if err := send(crimes); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-g.s.closing:
+			return nil
+		}
+	}
+}
+
+// fetchedAt is the Unix time of a snapshot, 0 before the first run.
+func fetchedAt(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func memberNames(members []torn.Member) map[int]string {
+	names := make(map[int]string, len(members))
+	for _, m := range members {
+		names[m.ID] = m.Name
+	}
+	return names
+}
+
+func protoCrime(c torn.Crime, names map[int]string) *tornocv1.Crime {
+	pc := &tornocv1.Crime{Id: int64(c.ID), Name: c.Name, Difficulty: int32(c.Difficulty), Status: c.Status, ReadyAt: c.ReadyAt, ExecutedAt: c.ExecutedAt, ExpiredAt: c.ExpiredAt}
+	if c.Rewards != nil {
+		pc.Money, pc.Respect = c.Rewards.Money, int32(c.Rewards.Respect)
+	}
+	for _, slot := range c.Slots {
+		pc.Slots = append(pc.Slots, &tornocv1.Slot{Position: slot.Position, MemberId: int64(slot.User.ID), MemberName: names[slot.User.ID], Cpr: int32(slot.CheckpointPassRate), Outcome: slot.User.Outcome})
+	}
+	return pc
+}
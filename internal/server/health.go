@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// unhealthyFailures is how many runs in a row may fail before /healthz
+// reports the process as unhealthy.
+const unhealthyFailures = 3
+
+type healthResponse struct {
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	LastRun     string `json:"last_run,omitempty"`
+	LastSuccess string `json:"last_success,omitempty"`
+	Failures    int    `json:"consecutive_failures"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// handleHealthz is a liveness check: it fails with 503 once several runs in a
+// row have failed or the last success is older than the store's MaxAge, so an
+// orchestrator restarts a wedged instance. With authentication enabled, only
+// callers with credentials see the last error.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	h := s.store.Health()
+	resp := healthResponse{Status: "ok", Failures: h.Failures}
+	if s.authenticated(r) {
+		resp.LastError = h.LastError
+	}
+	if !h.LastRun.IsZero() {
+		resp.LastRun = h.LastRun.UTC().Format(time.RFC3339)
+	}
+	if !h.LastSuccess.IsZero() {
+		resp.LastSuccess = h.LastSuccess.UTC().Format(time.RFC3339)
+	}
+
+	// before the first success, the age counts from when the server started
+	since := h.LastSuccess
+	if since.IsZero() {
+		since = s.started
+	}
+	switch {
+	case h.Failures >= unhealthyFailures:
+		resp.Status, resp.Reason = "unhealthy", "the last runs failed"
+	case h.MaxAge > 0 && time.Since(since) > h.MaxAge:
+		resp.Status, resp.Reason = "unhealthy", "no successful run for "+time.Since(since).Round(time.Second).String()
+	}
+	if resp.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, resp)
+}
+
+// handleReadyz is a readiness check: it fails with 503 until a run has
+// fetched the data the other endpoints serve.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	snap := s.store.Snapshot()
+	if snap.FetchedAt.IsZero() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		writeJSON(w, healthResponse{Status: "not ready", Reason: "no data fetched yet"})
+		return
+	}
+	writeJSON(w, healthResponse{Status: "ready", LastSuccess: snap.FetchedAt.UTC().Format(time.RFC3339)})
+}
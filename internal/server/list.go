@@ -0,0 +1,198 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/store"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// The orders lists can be sorted in, by the name of the sort parameter. A
+// name prefixed with "-" reverses it; ties keep the list's default order.
+var (
+	memberSorts = map[string]func(a, b torn.Member) int{
+		"name":        func(a, b torn.Member) int { return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) },
+		"id":          func(a, b torn.Member) int { return cmp.Compare(a.ID, b.ID) },
+		"last_action": func(a, b torn.Member) int { return cmp.Compare(a.LastAction.Timestamp, b.LastAction.Timestamp) },
+	}
+	rateSorts = map[string]func(a, b store.Rate) int{
+		"difficulty":  func(a, b store.Rate) int { return cmp.Compare(a.Difficulty, b.Difficulty) },
+		"position":    func(a, b store.Rate) int { return cmp.Compare(a.Position, b.Position) },
+		"cpr":         func(a, b store.Rate) int { return cmp.Compare(a.Rate, b.Rate) },
+		"executed_at": func(a, b store.Rate) int { return cmp.Compare(a.ExecutedAt, b.ExecutedAt) },
+	}
+	crimeSorts = map[string]func(a, b torn.Crime) int{
+		"executed_at": func(a, b torn.Crime) int { return cmp.Compare(a.ExecutedAt, b.ExecutedAt) },
+		"difficulty":  func(a, b torn.Crime) int { return cmp.Compare(a.Difficulty, b.Difficulty) },
+		"name":        func(a, b torn.Crime) int { return cmp.Compare(a.Name, b.Name) },
+		"id":          func(a, b torn.Crime) int { return cmp.Compare(a.ID, b.ID) },
+	}
+)
+
+// sortBy sorts a copy of items, which may be shared, by the order spec
+// names; an empty spec keeps them as they are.
+func sortBy[T any](items []T, spec string, orders map[string]func(a, b T) int) ([]T, error) {
+	if spec == "" {
+		return items, nil
+	}
+	name, desc := strings.CutPrefix(spec, "-")
+	order, ok := orders[name]
+	if !ok {
+		names := make([]string, 0, len(orders))
+		for n := range orders {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return nil, fmt.Errorf("can't sort by %q, only by %s", name, strings.Join(names, ", "))
+	}
+	items = slices.Clone(items)
+	slices.SortStableFunc(items, func(a, b T) int {
+		if desc {
+			return order(b, a)
+		}
+		return order(a, b)
+	})
+	return items, nil
+}
+
+// page is the items after the first offset, at most limit of them unless
+// that is 0.
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[max(offset, 0):]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// crimeFilter narrows a list of crimes; zero fields match every crime.
+type crimeFilter struct {
+	status     string
+	difficulty int
+	// position matches crimes with a slot of it, and member those the
+	// member took part in
+	position string
+	member   int
+	// since keeps the crimes executed at or after it, in Unix seconds
+	since int64
+}
+
+func (f crimeFilter) apply(crimes []torn.Crime) []torn.Crime {
+	if f == (crimeFilter{}) {
+		return crimes
+	}
+	var kept []torn.Crime
+	for _, c := range crimes {
+		if f.status != "" && !strings.EqualFold(c.Status, f.status) || f.difficulty != 0 && c.Difficulty != f.difficulty || c.ExecutedAt < f.since {
+			continue
+		}
+		if (f.position == "" || slices.ContainsFunc(c.Slots, func(s torn.Slot) bool { return strings.EqualFold(s.Position, f.position) })) &&
+			(f.member == 0 || slices.ContainsFunc(c.Slots, func(s torn.Slot) bool { return s.User.ID == f.member })) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// memberFilter narrows a list of members; zero fields match every member.
+type memberFilter struct {
+	id int
+	// name matches ignoring case
+	name string
+	inOC *bool
+}
+
+func (f memberFilter) apply(members []torn.Member) []torn.Member {
+	var kept []torn.Member
+	for _, m := range members {
+		if f.id != 0 && m.ID != f.id || f.name != "" && !strings.EqualFold(m.Name, f.name) || f.inOC != nil && m.IsInOC != *f.inOC {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// parseMemberQuery reads a memberFilter from the id, name and in_oc
+// parameters.
+func parseMemberQuery(q url.Values) (memberFilter, error) {
+	f := memberFilter{name: q.Get("name")}
+	var err error
+	if f.id, err = queryInt(q, "id"); err != nil {
+		return f, err
+	}
+	if v := q.Get("in_oc"); v != "" {
+		inOC, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("in_oc must be true or false")
+		}
+		f.inOC = &inOC
+	}
+	return f, nil
+}
+
+// rateFilter narrows a member's rates; zero fields match every rate.
+type rateFilter struct {
+	difficulty int
+	position   string
+	// since keeps the rates observed at or after it, in Unix seconds
+	since int64
+}
+
+func (f rateFilter) apply(rates []store.Rate) []store.Rate {
+	if f == (rateFilter{}) {
+		return rates
+	}
+	var kept []store.Rate
+	for _, r := range rates {
+		if f.difficulty != 0 && r.Difficulty != f.difficulty || f.position != "" && !strings.EqualFold(r.Position, f.position) || r.ExecutedAt < f.since {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// listQuery is the paging, sorting and filtering of an HTTP list endpoint,
+// from the offset, limit, sort, position, difficulty and since parameters.
+type listQuery struct {
+	offset, limit int
+	sort          string
+	rates         rateFilter
+	// members narrows the members of endpoints listing them
+	members memberFilter
+}
+
+func parseListQuery(q url.Values) (listQuery, error) {
+	l := listQuery{sort: q.Get("sort"), rates: rateFilter{position: q.Get("position")}}
+	var since int
+	for name, v := range map[string]*int{"offset": &l.offset, "limit": &l.limit, "difficulty": &l.rates.difficulty, "since": &since} {
+		var err error
+		if *v, err = queryInt(q, name); err != nil {
+			return l, err
+		}
+	}
+	l.rates.since = int64(since)
+	return l, nil
+}
+
+// queryInt is a non-negative integer parameter, 0 when it is absent.
+func queryInt(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
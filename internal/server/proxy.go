@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SetBasePath serves every endpoint under prefix, such as /oc, for a reverse
+// proxy that forwards a path of its site without stripping it. Requests
+// outside it are not found.
+func (s *Server) SetBasePath(prefix string) {
+	s.basePath = strings.TrimSuffix(prefix, "/")
+}
+
+// EnableCORS lets pages on origins, such as https://tools.example.com, call
+// the endpoints from a browser; "*" allows any origin.
+func (s *Server) EnableCORS(origins []string) {
+	s.corsOrigins = origins
+}
+
+// TrustProxy takes the client address, scheme, host and path prefix from the
+// X-Forwarded-For, -Proto, -Host and -Prefix headers a reverse proxy sets. As
+// anyone can send them, only a server the proxy alone can reach should.
+func (s *Server) TrustProxy() {
+	s.trustProxy = true
+}
+
+// baseKey is the request context key of the external URL of the server that
+// serves a request.
+type baseKey struct{}
+
+// baseURL is the URL the client reached the server at, such as
+// https://example.com/oc/f/main, without a trailing slash.
+func baseURL(r *http.Request) string {
+	if base, ok := r.Context().Value(baseKey{}).(string); ok {
+		return base
+	}
+	return "http://" + r.Host
+}
+
+// front applies what sits in front of the endpoints: the proxy headers, CORS
+// and the base path. It reports whether it answered the request itself.
+func (s *Server) front(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, bool) {
+	scheme, host, prefix, client := "http", r.Host, "", ""
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if s.trustProxy {
+		client = forwarded(r, "X-Forwarded-For", false)
+		if v := forwarded(r, "X-Forwarded-Proto", true); v == "http" || v == "https" {
+			scheme = v
+		}
+		if v := forwarded(r, "X-Forwarded-Host", true); v != "" {
+			host = v
+		}
+		prefix = strings.TrimSuffix(forwarded(r, "X-Forwarded-Prefix", true), "/")
+	}
+	if s.corsOrigins != nil && s.cors(w, r) {
+		return w, r, true
+	}
+	r = r.WithContext(context.WithValue(r.Context(), baseKey{}, scheme+"://"+host+prefix))
+	if client != "" {
+		r.RemoteAddr = client
+	}
+	if prefix != "" {
+		w = redirectWriter{w, prefix}
+	}
+	if s.basePath == "" {
+		return w, r, false
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, s.basePath)
+	switch {
+	case !ok || rest != "" && rest[0] != '/':
+		http.NotFound(w, r)
+		return w, r, true
+	case rest == "":
+		http.Redirect(w, r, s.basePath+"/", http.StatusMovedPermanently)
+		return w, r, true
+	}
+	w, r = mounted(w, r, s.basePath)
+	return w, r, false
+}
+
+// forwarded is the value of a proxy header: the first of a list, or with
+// first false the last, which a single proxy appended itself.
+func forwarded(r *http.Request, name string, first bool) string {
+	v := r.Header.Get(name)
+	if !first {
+		if values := r.Header.Values(name); len(values) > 0 {
+			v = values[len(values)-1]
+		}
+		if i := strings.LastIndexByte(v, ','); i >= 0 {
+			v = v[i+1:]
+		}
+	} else if i := strings.IndexByte(v, ','); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// mount serves h under prefix, which it strips from the paths of requests and
+// adds to the URLs of redirects and baseURL.
+func mount(prefix string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(mounted(w, r, prefix))
+	})
+}
+
+// mounted is the response writer and request of a handler serving under
+// prefix, with prefix already stripped from the path.
+func mounted(w http.ResponseWriter, r *http.Request, prefix string) (http.ResponseWriter, *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), baseKey{}, baseURL(r)+prefix))
+	u := *r.URL
+	u.Path = strings.TrimPrefix(u.Path, prefix)
+	u.RawPath = strings.TrimPrefix(u.RawPath, prefix)
+	r.URL = &u
+	return redirectWriter{w, prefix}, r
+}
+
+// redirectWriter prefixes the paths redirects lead to, which handlers build
+// from the path they see.
+type redirectWriter struct {
+	http.ResponseWriter
+	prefix string
+}
+
+func (w redirectWriter) WriteHeader(code int) {
+	if code >= 300 && code < 400 {
+		if loc := w.Header().Get("Location"); strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+			w.Header().Set("Location", w.prefix+loc)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush lets the event stream through.
+func (w redirectWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w redirectWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// cors answers the CORS headers of requests from the allowed origins, and
+// reports whether the request was a preflight it answered.
+func (s *Server) cors(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	switch {
+	case slices.Contains(s.corsOrigins, origin):
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	case slices.Contains(s.corsOrigins, "*"):
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	default:
+		return false
+	}
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After")
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	w.Header().Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
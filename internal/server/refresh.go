@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// refreshCooldown is how soon after one refresh another is accepted, so a
+// leaked token can't hammer the Torn API.
+const refreshCooldown = time.Minute
+
+// refresher passes on-demand refreshes to the run loop.
+type refresher struct {
+	mu   sync.Mutex
+	last time.Time
+	ch   chan struct{}
+}
+
+// Refreshes receives a value for every refresh asked for with POST
+// /api/refresh; one waits while a run is in progress.
+func (s *Server) Refreshes() <-chan struct{} {
+	return s.refresh.ch
+}
+
+// handleRefresh asks for a run outside the schedule. As it spends API calls,
+// it is only served once auth is enabled, and at most once per
+// refreshCooldown.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		http.Error(w, "refreshing needs SERVER_AUTH_TOKENS or SERVER_BASIC_AUTH", http.StatusForbidden)
+		return
+	}
+	s.refresh.mu.Lock()
+	defer s.refresh.mu.Unlock()
+	if wait := refreshCooldown - time.Since(s.refresh.last); wait > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "refreshed too recently", http.StatusTooManyRequests)
+		return
+	}
+	s.refresh.last = time.Now()
+	slog.Info("Refresh requested over HTTP", "client", r.RemoteAddr)
+	select {
+	case s.refresh.ch <- struct{}{}:
+	default:
+		// already asked for
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, `{"status":"queued"}`)
+}
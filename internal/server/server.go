@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/mnuck/torn-oc-history/internal/metrics"
+	"github.com/mnuck/torn-oc-history/internal/store"
+	tornocv1 "github.com/mnuck/torn-oc-history/proto/tornoc/v1"
+)
+
+// Server exposes the data held in the store over HTTP.
+type Server struct {
+	store *store.Store
+	mux   *http.ServeMux
+	http  *http.Server
+	// grpc serves the gRPC API
+	grpc *grpc.Server
+	// started is when the server was created, for health checks before the
+	// first run completes
+	started time.Time
+	// closing is closed on Shutdown, ending the event streams that would
+	// otherwise hold it up
+	closing chan struct{}
+	// discordKey, slackSecret and answer serve the chat commands, once
+	// enabled
+	discordKey  ed25519.PublicKey
+	slackSecret string
+	answer      Answerer
+	// auth is the credentials requests must present, nil to serve anyone
+	auth *auth
+	// factions are the servers of the other factions, by name
+	factions map[string]*Server
+	refresh  *refresher
+	// basePath, corsOrigins and trustProxy are how the server sits behind
+	// a reverse proxy and is called from other sites
+	basePath    string
+	corsOrigins []string
+	trustProxy  bool
+}
+
+func New(st *store.Store) *Server {
+	s := &Server{store: st, mux: http.NewServeMux(), started: time.Now(), closing: make(chan struct{}), refresh: &refresher{ch: make(chan struct{}, 1)}}
+	s.http = &http.Server{Handler: s}
+	s.http.RegisterOnShutdown(func() { close(s.closing) })
+	s.grpc = grpc.NewServer(grpc.UnaryInterceptor(s.grpcUnaryAuth), grpc.StreamInterceptor(s.grpcStreamAuth))
+	tornocv1.RegisterTornOCServer(s.grpc, grpcService{s: s})
+	s.routes()
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /grafana/{$}", s.scoped("grafana", s.handleGrafanaTest))
+	s.mux.HandleFunc("POST /grafana/search", s.scoped("grafana", s.handleGrafanaSearch))
+	s.mux.HandleFunc("POST /grafana/query", s.scoped("grafana", s.handleGrafanaQuery))
+	s.mux.HandleFunc("GET /feed.atom", s.scoped("feeds", s.handleFeed))
+	s.mux.HandleFunc("GET /calendar.ics", s.scoped("feeds", s.handleCalendar))
+	s.mux.HandleFunc("GET /events", s.scoped("events", s.handleEvents))
+	s.mux.HandleFunc("GET /dashboard/", s.scoped("dashboard", dashboardFiles().ServeHTTP))
+	s.mux.HandleFunc("GET /dashboard/data.json", s.scoped("dashboard", s.handleDashboardData))
+	s.mux.HandleFunc("GET /graphql", s.scoped("graphql", s.handleGraphQL))
+	s.mux.HandleFunc("POST /graphql", s.scoped("graphql", s.handleGraphQL))
+	s.mux.HandleFunc("GET /graphql/schema.graphql", s.scoped("graphql", s.handleGraphQLSchema))
+	s.mux.HandleFunc("POST /api/refresh", s.scoped("refresh", s.handleRefresh))
+	s.mux.HandleFunc("POST /discord/interactions", s.handleDiscordInteraction)
+	s.mux.HandleFunc("POST /slack/command", s.handleSlackCommand)
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	s.mux.HandleFunc("GET /metrics", s.scoped("metrics", s.handleMetrics))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w, r, done := s.front(w, r)
+	if !done {
+		s.mux.ServeHTTP(w, r)
+	}
+}
+
+// ListenAndServe blocks serving HTTP on addr until Shutdown.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("HTTP server listening", "addr", addr)
+	return s.http.Serve(ln)
+}
+
+// Shutdown stops accepting connections and waits, until ctx is done, for the
+// requests being served to finish.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.http.Shutdown(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+	return err
+}
+
+// handleMetrics serves the daemon's own metrics to Prometheus.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metrics.Write(w); err != nil {
+		slog.Error("write metrics", "error", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("encode response", "error", err)
+	}
+}
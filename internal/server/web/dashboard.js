@@ -0,0 +1,149 @@
+// Renders /dashboard/data.json: the CPR matrix with filters and sortable
+// columns, the members not in an OC, and the crimes executed per week.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+let data = { members: [], weeks: [] };
+let sortKey = "name", sortAsc = true;
+
+function el(tag, attrs, ...children) {
+  const e = document.createElement(tag);
+  Object.assign(e, attrs);
+  e.append(...children);
+  return e;
+}
+
+function profile(m) {
+  return el("a", { href: "https://www.torn.com/profiles.php?XID=" + m.id, target: "_blank" }, m.name);
+}
+
+function band(rate) {
+  if (rate >= data.cpr_high) return "high";
+  if (rate >= data.cpr_low) return "mid";
+  return "low";
+}
+
+function columnsOf(members) {
+  const seen = new Map();
+  for (const m of members) {
+    for (const r of m.rates) seen.set(r.difficulty + "|" + r.position, r);
+  }
+  return [...seen.values()]
+    .map((r) => ({ key: r.difficulty + "|" + r.position, difficulty: r.difficulty, position: r.position }))
+    .sort((a, b) => a.difficulty - b.difficulty || a.position.localeCompare(b.position));
+}
+
+function filtered() {
+  const name = $("name").value.trim().toLowerCase();
+  const diff = $("difficulty").value, pos = $("position").value;
+  return data.members
+    .filter((m) => !name || m.name.toLowerCase().includes(name) || String(m.id) === name)
+    .filter((m) => !$("notinoc").checked || !m.in_oc)
+    .map((m) => ({
+      ...m,
+      rates: m.rates.filter((r) => (!diff || String(r.difficulty) === diff) && (!pos || r.position === pos)),
+    }))
+    .filter((m) => (!diff && !pos) || m.rates.length > 0);
+}
+
+function renderMatrix() {
+  const members = filtered();
+  const cols = columnsOf(members);
+  const rate = (m, key) => m.rates.find((r) => r.difficulty + "|" + r.position === key);
+  const value = (m) => {
+    if (sortKey === "name") return m.name.toLowerCase();
+    const r = rate(m, sortKey);
+    return r ? r.rate : -1;
+  };
+  members.sort((a, b) => {
+    const x = value(a), y = value(b);
+    return (x < y ? -1 : x > y ? 1 : 0) * (sortAsc ? 1 : -1);
+  });
+
+  const head = el("tr", {});
+  const th = (label, key) => {
+    const cell = el("th", { title: "Sort" }, label);
+    if (key === sortKey) cell.className = "sorted" + (sortAsc ? " asc" : "");
+    cell.onclick = () => {
+      sortAsc = key === sortKey ? !sortAsc : key === "name";
+      sortKey = key;
+      renderMatrix();
+    };
+    return cell;
+  };
+  head.append(th("Member", "name"));
+  for (const c of cols) head.append(th(`D${c.difficulty} ${c.position}`, c.key));
+
+  const rows = members.map((m) => {
+    const tr = el("tr", {}, el("td", {}, profile(m)));
+    for (const c of cols) {
+      const r = rate(m, c.key);
+      if (!r) {
+        tr.append(el("td", {}));
+        continue;
+      }
+      const td = el("td", { className: "cpr " + band(r.rate),
+        title: `${r.crime_name}, ${new Date(r.executed_at * 1000).toLocaleString()}` }, r.rate + "%");
+      if (r.prev_rate != null && r.prev_rate !== r.rate) {
+        const up = r.rate > r.prev_rate;
+        td.append(" ", el("span", { className: up ? "up" : "down", title: `was ${r.prev_rate}%` }, up ? "▲" : "▼"));
+      }
+      tr.append(td);
+    }
+    return tr;
+  });
+  $("matrix").replaceChildren(head, ...rows);
+}
+
+function renderIdle() {
+  const idle = data.members.filter((m) => !m.in_oc);
+  $("notinoc-count").textContent = `(${idle.length})`;
+  $("idle").replaceChildren(
+    el("tr", {}, el("th", {}, "Member"), el("th", {}, "Last action"), el("th", {}, "Positions with a CPR")),
+    ...idle.map((m) => el("tr", {}, el("td", {}, profile(m)), el("td", {}, m.last_action || ""), el("td", {}, String(m.rates.length)))),
+  );
+}
+
+function renderWeeks() {
+  const svg = $("weeks"), ns = "http://www.w3.org/2000/svg";
+  const height = 140, bar = 14;
+  const top = Math.max(1, ...data.weeks.map((w) => w.successful + w.failed));
+  svg.setAttribute("width", Math.max(1, data.weeks.length) * bar);
+  svg.replaceChildren();
+  data.weeks.forEach((w, i) => {
+    let y = height;
+    for (const [n, cls] of [[w.successful, "ok"], [w.failed, "fail"]]) {
+      const h = (n / top) * height;
+      y -= h;
+      const rect = document.createElementNS(ns, "rect");
+      for (const [k, v] of Object.entries({ x: i * bar, y, width: bar - 2, height: h, class: cls })) rect.setAttribute(k, v);
+      const title = document.createElementNS(ns, "title");
+      title.textContent = `Week of ${w.week}: ${w.successful} successful, ${w.failed} failed`;
+      rect.append(title);
+      svg.append(rect);
+    }
+  });
+}
+
+function fillOptions(select, values, label) {
+  select.replaceChildren(el("option", { value: "" }, select.firstChild.textContent),
+    ...values.map((v) => el("option", { value: String(v) }, label(v))));
+}
+
+async function load() {
+  const resp = await fetch("data.json");
+  data = await resp.json();
+  $("label").textContent = data.label ? `[${data.label}]` : "";
+  $("fetched").textContent = data.fetched_at
+    ? "Data from " + new Date(data.fetched_at).toLocaleString()
+    : "No data fetched yet";
+  const cols = columnsOf(data.members);
+  fillOptions($("difficulty"), [...new Set(cols.map((c) => c.difficulty))], (d) => `Difficulty ${d}`);
+  fillOptions($("position"), [...new Set(cols.map((c) => c.position))].sort(), (p) => p);
+  renderMatrix();
+  renderIdle();
+  renderWeeks();
+}
+
+for (const id of ["name", "difficulty", "position", "notinoc"]) $(id).addEventListener("input", renderMatrix);
+load().catch((err) => { $("fetched").textContent = "Failed to load the data: " + err; });
@@ -0,0 +1,70 @@
+package sheets
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// BackupPrefix starts the title of every backup tab.
+const BackupPrefix = "Backup_"
+
+// BackupSheets duplicates each existing sheet in titles to a tab named
+// "Backup_<stamp> <title>" and deletes the oldest backups of that sheet so at
+// most keep remain. stamp must sort chronologically and contain no spaces.
+// Sheets that don't exist yet are skipped.
+func (c *Client) BackupSheets(ctx context.Context, spreadsheetID string, titles []string, stamp string, keep int) error {
+	ss, err := retry(ctx, c.reads, func() (*sheets.Spreadsheet, error) {
+		return c.service.Spreadsheets.Get(spreadsheetID).Fields("sheets.properties(sheetId,title)").Context(ctx).Do()
+	})
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]int64, len(ss.Sheets))
+	for _, sh := range ss.Sheets {
+		ids[sh.Properties.Title] = sh.Properties.SheetId
+	}
+
+	var requests []*sheets.Request
+	for _, title := range titles {
+		id, ok := ids[title]
+		if !ok {
+			continue
+		}
+		requests = append(requests, &sheets.Request{
+			DuplicateSheet: &sheets.DuplicateSheetRequest{
+				SourceSheetId:    id,
+				NewSheetName:     BackupPrefix + stamp + " " + title,
+				InsertSheetIndex: int64(len(ss.Sheets)),
+			},
+		})
+
+		// existing backups of this sheet, newest first; the new one counts toward keep
+		var backups []string
+		for t := range ids {
+			if _, of, ok := backupOf(t); ok && of == title {
+				backups = append(backups, t)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+		for i, t := range backups {
+			if i >= keep-1 {
+				requests = append(requests, &sheets.Request{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: ids[t]}})
+			}
+		}
+	}
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
+
+// backupOf splits the title of a backup tab into its stamp and the title of
+// the sheet it backs up; ok is false for tabs that aren't backups.
+func backupOf(t string) (stamp, title string, ok bool) {
+	rest, ok := strings.CutPrefix(t, BackupPrefix)
+	if !ok {
+		return "", "", false
+	}
+	stamp, title, ok = strings.Cut(rest, " ")
+	return stamp, title, ok && stamp != ""
+}
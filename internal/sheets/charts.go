@@ -0,0 +1,126 @@
+package sheets
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// Block is a rectangle of cells on a named sheet, with zero-based indexes and
+// exclusive ends. An EndRow of 0 leaves the block open downwards.
+type Block struct {
+	Sheet            string
+	StartRow, EndRow int64
+	StartCol, EndCol int64
+}
+
+// Chart describes a chart anchored on a sheet.
+type Chart struct {
+	Title string
+	// Kind is "histogram" (one series per Series block) or "line" (Domain on
+	// the x-axis, each Series block a line; blocks include a header row).
+	Kind                 string
+	Domain               Block
+	Series               []Block
+	XTitle, YTitle       string
+	BucketSize           float64 // histogram only
+	AnchorRow, AnchorCol int64
+}
+
+// ReplaceCharts deletes every chart on the given sheet and adds the new ones,
+// so charts can be regenerated on each run without piling up.
+func (c *Client) ReplaceCharts(ctx context.Context, spreadsheetID, title string, charts []Chart) error {
+	ss, err := retry(ctx, c.reads, func() (*sheets.Spreadsheet, error) {
+		return c.service.Spreadsheets.Get(spreadsheetID).
+			Fields("sheets(properties(sheetId,title),charts(chartId))").
+			Context(ctx).
+			Do()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get spreadsheet: %w", err)
+	}
+	ids := make(map[string]int64, len(ss.Sheets))
+	var requests []*sheets.Request
+	for _, sh := range ss.Sheets {
+		ids[sh.Properties.Title] = sh.Properties.SheetId
+		if sh.Properties.Title != title {
+			continue
+		}
+		for _, ch := range sh.Charts {
+			requests = append(requests, &sheets.Request{
+				DeleteEmbeddedObject: &sheets.DeleteEmbeddedObjectRequest{ObjectId: ch.ChartId},
+			})
+		}
+	}
+	target, ok := ids[title]
+	if !ok {
+		return fmt.Errorf("sheet %q not found", title)
+	}
+
+	grid := func(b Block) (*sheets.ChartData, error) {
+		id, ok := ids[b.Sheet]
+		if !ok {
+			return nil, fmt.Errorf("sheet %q not found", b.Sheet)
+		}
+		return &sheets.ChartData{SourceRange: &sheets.ChartSourceRange{Sources: []*sheets.GridRange{{
+			SheetId:          id,
+			StartRowIndex:    b.StartRow,
+			EndRowIndex:      b.EndRow,
+			StartColumnIndex: b.StartCol,
+			EndColumnIndex:   b.EndCol,
+		}}}}, nil
+	}
+
+	for _, ch := range charts {
+		spec := &sheets.ChartSpec{Title: ch.Title}
+		switch ch.Kind {
+		case "histogram":
+			hist := &sheets.HistogramChartSpec{LegendPosition: "NO_LEGEND", BucketSize: ch.BucketSize}
+			for _, b := range ch.Series {
+				data, err := grid(b)
+				if err != nil {
+					return err
+				}
+				hist.Series = append(hist.Series, &sheets.HistogramSeries{Data: data})
+			}
+			spec.HistogramChart = hist
+		case "line":
+			domain, err := grid(ch.Domain)
+			if err != nil {
+				return err
+			}
+			basic := &sheets.BasicChartSpec{
+				ChartType:      "LINE",
+				LegendPosition: "BOTTOM_LEGEND",
+				HeaderCount:    1,
+				Axis: []*sheets.BasicChartAxis{
+					{Position: "BOTTOM_AXIS", Title: ch.XTitle},
+					{Position: "LEFT_AXIS", Title: ch.YTitle},
+				},
+				Domains: []*sheets.BasicChartDomain{{Domain: domain}},
+			}
+			for _, b := range ch.Series {
+				data, err := grid(b)
+				if err != nil {
+					return err
+				}
+				basic.Series = append(basic.Series, &sheets.BasicChartSeries{Series: data, TargetAxis: "LEFT_AXIS"})
+			}
+			spec.BasicChart = basic
+		default:
+			return fmt.Errorf("unknown chart kind %q", ch.Kind)
+		}
+
+		requests = append(requests, &sheets.Request{
+			AddChart: &sheets.AddChartRequest{Chart: &sheets.EmbeddedChart{
+				Spec: spec,
+				Position: &sheets.EmbeddedObjectPosition{OverlayPosition: &sheets.OverlayPosition{
+					AnchorCell: &sheets.GridCoordinate{SheetId: target, RowIndex: ch.AnchorRow, ColumnIndex: ch.AnchorCol},
+				}},
+			}},
+		})
+	}
+
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
@@ -3,28 +3,101 @@ package sheets
 import (
 	"context"
 	"fmt"
+	"os"
 
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 type Client struct {
 	service *sheets.Service
+	drive   *drive.Service
+	// Sheets API read and write requests are paced separately, as Google
+	// counts them against separate quotas; Drive calls are not paced.
+	reads, writes *quota
 }
 
-func NewClient(ctx context.Context, credentialsFile string) (*Client, error) {
-	service, err := sheets.NewService(ctx, option.WithCredentialsFile(credentialsFile))
+// CredentialsFromEnv picks how to authenticate to Google: the service account
+// JSON in GOOGLE_CREDENTIALS_JSON, else the file named by
+// GOOGLE_APPLICATION_CREDENTIALS, else the Google account signed in with
+// Login, else defaultFile if it exists, else Application Default Credentials
+// (gcloud login, GCE/GKE metadata server or workload identity). It returns the
+// client options and a description of the source for logging.
+func CredentialsFromEnv(defaultFile string) ([]option.ClientOption, string, error) {
+	if creds := os.Getenv("GOOGLE_CREDENTIALS_JSON"); creds != "" {
+		return []option.ClientOption{option.WithAuthCredentialsJSON(option.ServiceAccount, []byte(creds))}, "GOOGLE_CREDENTIALS_JSON", nil
+	}
+	if file := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); file != "" {
+		// picked up by the default credentials lookup
+		return nil, file, nil
+	}
+	clientFile, tokenFile := OAuthFilesFromEnv()
+	if _, err := os.Stat(tokenFile); err == nil {
+		opt, err := userCredentials(clientFile, tokenFile)
+		if err != nil {
+			return nil, "", err
+		}
+		return []option.ClientOption{opt}, tokenFile, nil
+	}
+	if _, err := os.Stat(defaultFile); err == nil {
+		return []option.ClientOption{option.WithCredentialsFile(defaultFile)}, defaultFile, nil
+	}
+	return nil, "application default credentials", nil
+}
+
+// OAuthFilesFromEnv returns the OAuth client file (GOOGLE_OAUTH_CLIENT,
+// default oauth_client.json) and token file (GOOGLE_OAUTH_TOKEN, default
+// DefaultTokenFile) used by Login and CredentialsFromEnv.
+func OAuthFilesFromEnv() (clientFile, tokenFile string) {
+	clientFile, tokenFile = os.Getenv("GOOGLE_OAUTH_CLIENT"), os.Getenv("GOOGLE_OAUTH_TOKEN")
+	if clientFile == "" {
+		clientFile = "oauth_client.json"
+	}
+	if tokenFile == "" {
+		tokenFile = DefaultTokenFile
+	}
+	return clientFile, tokenFile
+}
+
+// NewClient creates a client authenticated with opts, typically from
+// CredentialsFromEnv.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	service, err := sheets.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sheets service: %w", err)
 	}
+	// read-only access lets CopySpreadsheet copy templates shared with us
+	driveService, err := drive.NewService(ctx, append(opts, option.WithScopes(drive.DriveFileScope, drive.DriveReadonlyScope))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create drive service: %w", err)
+	}
 
 	return &Client{
 		service: service,
+		drive:   driveService,
+		reads:   newQuota("read"),
+		writes:  newQuota("write"),
 	}, nil
 }
 
 func (c *Client) ReadSheet(ctx context.Context, spreadsheetID, range_ string) ([][]interface{}, error) {
-	resp, err := c.service.Spreadsheets.Values.Get(spreadsheetID, range_).Context(ctx).Do()
+	resp, err := retry(ctx, c.reads, func() (*sheets.ValueRange, error) {
+		return c.service.Spreadsheets.Values.Get(spreadsheetID, range_).Context(ctx).Do()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet: %w", err)
+	}
+
+	return resp.Values, nil
+}
+
+// ReadFormulas reads a range returning formulas rather than their results, and
+// numbers as numbers, so contents can be compared with what was written.
+func (c *Client) ReadFormulas(ctx context.Context, spreadsheetID, range_ string) ([][]interface{}, error) {
+	resp, err := retry(ctx, c.reads, func() (*sheets.ValueRange, error) {
+		return c.service.Spreadsheets.Values.Get(spreadsheetID, range_).ValueRenderOption("FORMULA").Context(ctx).Do()
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to read sheet: %w", err)
 	}
@@ -37,11 +110,13 @@ func (c *Client) AppendRows(ctx context.Context, spreadsheetID, range_ string, r
 		Values: rows,
 	}
 
-	_, err := c.service.Spreadsheets.Values.Append(spreadsheetID, range_, valueRange).
-		ValueInputOption("RAW").
-		InsertDataOption("INSERT_ROWS").
-		Context(ctx).
-		Do()
+	_, err := retry(ctx, c.writes, func() (*sheets.AppendValuesResponse, error) {
+		return c.service.Spreadsheets.Values.Append(spreadsheetID, range_, valueRange).
+			ValueInputOption("RAW").
+			InsertDataOption("INSERT_ROWS").
+			Context(ctx).
+			Do()
+	})
 	if err != nil {
 		return fmt.Errorf("failed to append rows: %w", err)
 	}
@@ -54,10 +129,31 @@ func (c *Client) UpdateRange(ctx context.Context, spreadsheetID, range_ string,
 		Values: values,
 	}
 
-	_, err := c.service.Spreadsheets.Values.Update(spreadsheetID, range_, valueRange).
-		ValueInputOption("RAW").
-		Context(ctx).
-		Do()
+	_, err := retry(ctx, c.writes, func() (*sheets.UpdateValuesResponse, error) {
+		return c.service.Spreadsheets.Values.Update(spreadsheetID, range_, valueRange).
+			ValueInputOption("RAW").
+			Context(ctx).
+			Do()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update range: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateFormulas writes values as if typed by a user, so formulas are evaluated.
+func (c *Client) UpdateFormulas(ctx context.Context, spreadsheetID, range_ string, values [][]interface{}) error {
+	valueRange := &sheets.ValueRange{
+		Values: values,
+	}
+
+	_, err := retry(ctx, c.writes, func() (*sheets.UpdateValuesResponse, error) {
+		return c.service.Spreadsheets.Values.Update(spreadsheetID, range_, valueRange).
+			ValueInputOption("USER_ENTERED").
+			Context(ctx).
+			Do()
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update range: %w", err)
 	}
@@ -66,12 +162,170 @@ func (c *Client) UpdateRange(ctx context.Context, spreadsheetID, range_ string,
 }
 
 func (c *Client) ClearRange(ctx context.Context, spreadsheetID, range_ string) error {
-	_, err := c.service.Spreadsheets.Values.Clear(spreadsheetID, range_, &sheets.ClearValuesRequest{}).
-		Context(ctx).
-		Do()
+	_, err := retry(ctx, c.writes, func() (*sheets.ClearValuesResponse, error) {
+		return c.service.Spreadsheets.Values.Clear(spreadsheetID, range_, &sheets.ClearValuesRequest{}).
+			Context(ctx).
+			Do()
+	})
 	if err != nil {
 		return fmt.Errorf("failed to clear range: %w", err)
 	}
 
 	return nil
 }
+
+// EnsureSheet adds a sheet (tab) with the given title if the spreadsheet does not have one yet.
+func (c *Client) EnsureSheet(ctx context.Context, spreadsheetID, title string) error {
+	return c.EnsureSheets(ctx, spreadsheetID, []string{title})
+}
+
+// EnsureSheets adds every listed sheet (tab) that the spreadsheet does not have
+// yet, in a single batch update.
+func (c *Client) EnsureSheets(ctx context.Context, spreadsheetID string, titles []string) error {
+	have, err := c.SheetTitles(ctx, spreadsheetID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(have))
+	for _, title := range have {
+		existing[title] = true
+	}
+
+	var requests []*sheets.Request
+	for _, title := range titles {
+		if existing[title] {
+			continue
+		}
+		existing[title] = true
+		requests = append(requests, &sheets.Request{
+			AddSheet: &sheets.AddSheetRequest{Properties: &sheets.SheetProperties{Title: title}},
+		})
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	if err := c.BatchUpdate(ctx, spreadsheetID, requests); err != nil {
+		return fmt.Errorf("failed to add sheets: %w", err)
+	}
+	return nil
+}
+
+// SheetTitles returns the titles of the spreadsheet's sheets (tabs) in order.
+func (c *Client) SheetTitles(ctx context.Context, spreadsheetID string) ([]string, error) {
+	ss, err := retry(ctx, c.reads, func() (*sheets.Spreadsheet, error) {
+		return c.service.Spreadsheets.Get(spreadsheetID).Fields("sheets.properties.title").Context(ctx).Do()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spreadsheet: %w", err)
+	}
+	titles := make([]string, len(ss.Sheets))
+	for i, sh := range ss.Sheets {
+		titles[i] = sh.Properties.Title
+	}
+	return titles, nil
+}
+
+// CanEdit reports whether the authenticated account may edit the spreadsheet,
+// as opposed to only viewing or commenting on it.
+func (c *Client) CanEdit(ctx context.Context, spreadsheetID string) (bool, error) {
+	f, err := retry(ctx, nil, func() (*drive.File, error) {
+		return c.drive.Files.Get(spreadsheetID).Fields("capabilities/canEdit").SupportsAllDrives(true).Context(ctx).Do()
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get file capabilities: %w", err)
+	}
+	return f.Capabilities != nil && f.Capabilities.CanEdit, nil
+}
+
+// BatchClearRanges clears several ranges in a single call.
+func (c *Client) BatchClearRanges(ctx context.Context, spreadsheetID string, ranges []string) error {
+	_, err := retry(ctx, c.writes, func() (*sheets.BatchClearValuesResponse, error) {
+		return c.service.Spreadsheets.Values.BatchClear(spreadsheetID, &sheets.BatchClearValuesRequest{Ranges: ranges}).
+			Context(ctx).
+			Do()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to batch clear ranges: %w", err)
+	}
+
+	return nil
+}
+
+// RangeValues is the data destined for one range in a batch update.
+type RangeValues struct {
+	Range  string
+	Values [][]interface{}
+	// UserEntered parses values as if typed by a user, so formulas are evaluated.
+	UserEntered bool
+}
+
+// BatchUpdateRanges writes several ranges with one call per value input option.
+func (c *Client) BatchUpdateRanges(ctx context.Context, spreadsheetID string, data []RangeValues) error {
+	raw := &sheets.BatchUpdateValuesRequest{ValueInputOption: "RAW"}
+	entered := &sheets.BatchUpdateValuesRequest{ValueInputOption: "USER_ENTERED"}
+	for _, d := range data {
+		req := raw
+		if d.UserEntered {
+			req = entered
+		}
+		req.Data = append(req.Data, &sheets.ValueRange{Range: d.Range, Values: d.Values})
+	}
+
+	for _, req := range []*sheets.BatchUpdateValuesRequest{raw, entered} {
+		if len(req.Data) == 0 {
+			continue
+		}
+		_, err := retry(ctx, c.writes, func() (*sheets.BatchUpdateValuesResponse, error) {
+			return c.service.Spreadsheets.Values.BatchUpdate(spreadsheetID, req).
+				Context(ctx).
+				Do()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to batch update ranges: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// CreateSpreadsheet creates a new spreadsheet with the given title and tabs and
+// returns its ID and URL.
+func (c *Client) CreateSpreadsheet(ctx context.Context, title string, tabs []string) (string, string, error) {
+	ss := &sheets.Spreadsheet{Properties: &sheets.SpreadsheetProperties{Title: title}}
+	for _, tab := range tabs {
+		ss.Sheets = append(ss.Sheets, &sheets.Sheet{Properties: &sheets.SheetProperties{Title: tab}})
+	}
+
+	created, err := retry(ctx, c.writes, func() (*sheets.Spreadsheet, error) {
+		return c.service.Spreadsheets.Create(ss).Context(ctx).Do()
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create spreadsheet: %w", err)
+	}
+	return created.SpreadsheetId, created.SpreadsheetUrl, nil
+}
+
+// CopySpreadsheet copies the spreadsheet templateID, with its tabs,
+// formatting and charts, to a new spreadsheet with the given title. It returns
+// the new spreadsheet's ID and URL.
+func (c *Client) CopySpreadsheet(ctx context.Context, templateID, title string) (string, string, error) {
+	copied, err := retry(ctx, nil, func() (*drive.File, error) {
+		return c.drive.Files.Copy(templateID, &drive.File{Name: title}).Fields("id", "webViewLink").Context(ctx).Do()
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to copy template spreadsheet: %w", err)
+	}
+	return copied.Id, copied.WebViewLink, nil
+}
+
+// Share grants email the given Drive role ("reader" or "writer") on the spreadsheet.
+func (c *Client) Share(ctx context.Context, spreadsheetID, email, role string) error {
+	perm := &drive.Permission{Type: "user", Role: role, EmailAddress: email}
+	_, err := retry(ctx, nil, func() (*drive.Permission, error) {
+		return c.drive.Permissions.Create(spreadsheetID, perm).Context(ctx).Do()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to share spreadsheet with %s: %w", email, err)
+	}
+	return nil
+}
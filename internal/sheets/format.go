@@ -0,0 +1,306 @@
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+type Color struct {
+	Red, Green, Blue float64
+}
+
+func (c Color) api() *sheets.Color {
+	return &sheets.Color{Red: c.Red, Green: c.Green, Blue: c.Blue}
+}
+
+// Threshold colours numeric cells whose value is at least Min.
+type Threshold struct {
+	Min   float64
+	Color Color
+}
+
+// sheetInfo fetches the properties and conditional formats of the sheet with the given title.
+func (c *Client) sheetInfo(ctx context.Context, spreadsheetID, title string) (*sheets.Sheet, error) {
+	ss, err := retry(ctx, c.reads, func() (*sheets.Spreadsheet, error) {
+		return c.service.Spreadsheets.Get(spreadsheetID).
+			Fields("sheets(properties(sheetId,title),conditionalFormats)").
+			Context(ctx).
+			Do()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spreadsheet: %w", err)
+	}
+	for _, sh := range ss.Sheets {
+		if sh.Properties.Title == title {
+			return sh, nil
+		}
+	}
+	return nil, fmt.Errorf("sheet %q not found", title)
+}
+
+// BatchUpdate applies raw spreadsheet update requests in a single call.
+func (c *Client) BatchUpdate(ctx context.Context, spreadsheetID string, requests []*sheets.Request) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	req := &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}
+	_, err := retry(ctx, c.writes, func() (*sheets.BatchUpdateSpreadsheetResponse, error) {
+		return c.service.Spreadsheets.BatchUpdate(spreadsheetID, req).Context(ctx).Do()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to batch update: %w", err)
+	}
+	return nil
+}
+
+// SetThresholds replaces the conditional formatting of columns [startCol,
+// endCol), from startRow down, with colour bands: each threshold colours values
+// at or above its Min (the highest matching threshold wins) and below colours
+// values under the lowest threshold. Blank and non-numeric cells are left
+// uncoloured. Rules on other ranges are left untouched.
+func (c *Client) SetThresholds(ctx context.Context, spreadsheetID, title string, startRow, startCol, endCol int64, thresholds []Threshold, below Color) error {
+	sh, err := c.sheetInfo(ctx, spreadsheetID, title)
+	if err != nil {
+		return err
+	}
+	col := startCol
+	grid := &sheets.GridRange{
+		SheetId:          sh.Properties.SheetId,
+		StartRowIndex:    startRow,
+		StartColumnIndex: startCol,
+		EndColumnIndex:   endCol,
+	}
+
+	var requests []*sheets.Request
+	// delete our previous rules, highest index first so the rest keep their positions
+	for i := len(sh.ConditionalFormats) - 1; i >= 0; i-- {
+		if sameRange(sh.ConditionalFormats[i].Ranges, grid) {
+			requests = append(requests, &sheets.Request{
+				DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{SheetId: grid.SheetId, Index: int64(i)},
+			})
+		}
+	}
+
+	sorted := append([]Threshold(nil), thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Min > sorted[j].Min })
+
+	cell := fmt.Sprintf("%s%d", ColumnLetter(col), startRow+1)
+	var rules []*sheets.ConditionalFormatRule
+	for _, t := range sorted {
+		rules = append(rules, formulaRule(grid, fmt.Sprintf("=AND(ISNUMBER(%s),%s>=%v)", cell, cell, t.Min), t.Color))
+	}
+	if len(sorted) > 0 {
+		lowest := sorted[len(sorted)-1].Min
+		rules = append(rules, formulaRule(grid, fmt.Sprintf("=AND(ISNUMBER(%s),%s<%v)", cell, cell, lowest), below))
+	}
+	for i, rule := range rules {
+		requests = append(requests, &sheets.Request{
+			AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{Rule: rule, Index: int64(i)},
+		})
+	}
+
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
+
+func formulaRule(grid *sheets.GridRange, formula string, color Color) *sheets.ConditionalFormatRule {
+	return &sheets.ConditionalFormatRule{
+		Ranges: []*sheets.GridRange{grid},
+		BooleanRule: &sheets.BooleanRule{
+			Condition: &sheets.BooleanCondition{
+				Type:   "CUSTOM_FORMULA",
+				Values: []*sheets.ConditionValue{{UserEnteredValue: formula}},
+			},
+			Format: &sheets.CellFormat{BackgroundColor: color.api()},
+		},
+	}
+}
+
+func sameRange(ranges []*sheets.GridRange, g *sheets.GridRange) bool {
+	if len(ranges) != 1 {
+		return false
+	}
+	r := ranges[0]
+	return r.SheetId == g.SheetId && r.StartRowIndex == g.StartRowIndex && r.EndRowIndex == g.EndRowIndex &&
+		r.StartColumnIndex == g.StartColumnIndex && r.EndColumnIndex == g.EndColumnIndex
+}
+
+// StyleHeaderRow bolds and shades the header row cells [startCol, endCol) and
+// freezes every row up to and including it.
+func (c *Client) StyleHeaderRow(ctx context.Context, spreadsheetID, title string, row, startCol, endCol int64, background Color) error {
+	sh, err := c.sheetInfo(ctx, spreadsheetID, title)
+	if err != nil {
+		return err
+	}
+	sheetID := sh.Properties.SheetId
+
+	requests := []*sheets.Request{
+		{
+			UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+				Properties: &sheets.SheetProperties{
+					SheetId:        sheetID,
+					GridProperties: &sheets.GridProperties{FrozenRowCount: row + 1},
+				},
+				Fields: "gridProperties.frozenRowCount",
+			},
+		},
+		{
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					SheetId:          sheetID,
+					StartRowIndex:    row,
+					EndRowIndex:      row + 1,
+					StartColumnIndex: startCol,
+					EndColumnIndex:   endCol,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						BackgroundColor: background.api(),
+						TextFormat:      &sheets.TextFormat{Bold: true},
+					},
+				},
+				Fields: "userEnteredFormat(backgroundColor,textFormat.bold)",
+			},
+		},
+	}
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
+
+// SetNotes sets the notes of a block of cells starting at (startRow, startCol),
+// one string per cell; an empty string removes the cell's note.
+func (c *Client) SetNotes(ctx context.Context, spreadsheetID, title string, startRow, startCol int64, notes [][]string) error {
+	sh, err := c.sheetInfo(ctx, spreadsheetID, title)
+	if err != nil {
+		return err
+	}
+
+	var rows []*sheets.RowData
+	for _, r := range notes {
+		row := &sheets.RowData{}
+		for _, note := range r {
+			row.Values = append(row.Values, &sheets.CellData{Note: note})
+		}
+		rows = append(rows, row)
+	}
+	requests := []*sheets.Request{{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start:  &sheets.GridCoordinate{SheetId: sh.Properties.SheetId, RowIndex: startRow, ColumnIndex: startCol},
+			Rows:   rows,
+			Fields: "note",
+		},
+	}}
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
+
+// SizeColumns sets the pixel widths of consecutive columns starting at
+// startCol and auto-resizes the remaining columns up to endCol to fit their
+// contents.
+func (c *Client) SizeColumns(ctx context.Context, spreadsheetID, title string, startCol, endCol int64, widths []int64) error {
+	sh, err := c.sheetInfo(ctx, spreadsheetID, title)
+	if err != nil {
+		return err
+	}
+	sheetID := sh.Properties.SheetId
+
+	var requests []*sheets.Request
+	col := startCol
+	for _, w := range widths {
+		if col >= endCol {
+			break
+		}
+		requests = append(requests, &sheets.Request{
+			UpdateDimensionProperties: &sheets.UpdateDimensionPropertiesRequest{
+				Range:      &sheets.DimensionRange{SheetId: sheetID, Dimension: "COLUMNS", StartIndex: col, EndIndex: col + 1},
+				Properties: &sheets.DimensionProperties{PixelSize: w},
+				Fields:     "pixelSize",
+			},
+		})
+		col++
+	}
+	if col < endCol {
+		requests = append(requests, &sheets.Request{
+			AutoResizeDimensions: &sheets.AutoResizeDimensionsRequest{
+				Dimensions: &sheets.DimensionRange{SheetId: sheetID, Dimension: "COLUMNS", StartIndex: col, EndIndex: endCol},
+			},
+		})
+	}
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
+
+// SetNumberFormat applies a number format (e.g. type "PERCENT" with pattern
+// "0%") to columns [startCol, endCol) from startRow down.
+func (c *Client) SetNumberFormat(ctx context.Context, spreadsheetID, title string, startRow, startCol, endCol int64, formatType, pattern string) error {
+	return c.SetRowsNumberFormat(ctx, spreadsheetID, title, startRow, 0, startCol, endCol, formatType, pattern)
+}
+
+// SetRowsNumberFormat is SetNumberFormat for rows [startRow, endRow) only,
+// such as rows just appended; an endRow of 0 means to the bottom.
+func (c *Client) SetRowsNumberFormat(ctx context.Context, spreadsheetID, title string, startRow, endRow, startCol, endCol int64, formatType, pattern string) error {
+	sh, err := c.sheetInfo(ctx, spreadsheetID, title)
+	if err != nil {
+		return err
+	}
+	requests := []*sheets.Request{{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range: &sheets.GridRange{
+				SheetId:          sh.Properties.SheetId,
+				StartRowIndex:    startRow,
+				EndRowIndex:      endRow,
+				StartColumnIndex: startCol,
+				EndColumnIndex:   endCol,
+			},
+			Cell: &sheets.CellData{
+				UserEnteredFormat: &sheets.CellFormat{NumberFormat: &sheets.NumberFormat{Type: formatType, Pattern: pattern}},
+			},
+			Fields: "userEnteredFormat.numberFormat",
+		},
+	}}
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
+
+// SerialTime converts a Unix timestamp to a spreadsheet date-time serial
+// number (days since 1899-12-30, UTC).
+func SerialTime(unix int64) float64 {
+	return float64(unix)/86400 + 25569
+}
+
+// SetDropdowns replaces the data validation of column col, from startRow
+// down, with one dropdown per row offering that row's options. Rows with no
+// options get no dropdown. Values outside the list are allowed but flagged, so
+// users can still type a name in.
+func (c *Client) SetDropdowns(ctx context.Context, spreadsheetID, title string, startRow, col int64, options [][]string) error {
+	sh, err := c.sheetInfo(ctx, spreadsheetID, title)
+	if err != nil {
+		return err
+	}
+	id := sh.Properties.SheetId
+
+	// a nil rule clears the validation of previous runs
+	requests := []*sheets.Request{{
+		SetDataValidation: &sheets.SetDataValidationRequest{
+			Range: &sheets.GridRange{SheetId: id, StartRowIndex: startRow, StartColumnIndex: col, EndColumnIndex: col + 1},
+		},
+	}}
+	for i, opts := range options {
+		if len(opts) == 0 {
+			continue
+		}
+		var values []*sheets.ConditionValue
+		for _, o := range opts {
+			values = append(values, &sheets.ConditionValue{UserEnteredValue: o})
+		}
+		row := startRow + int64(i)
+		requests = append(requests, &sheets.Request{
+			SetDataValidation: &sheets.SetDataValidationRequest{
+				Range: &sheets.GridRange{SheetId: id, StartRowIndex: row, EndRowIndex: row + 1, StartColumnIndex: col, EndColumnIndex: col + 1},
+				Rule: &sheets.DataValidationRule{
+					Condition:    &sheets.BooleanCondition{Type: "ONE_OF_LIST", Values: values},
+					ShowCustomUi: true,
+				},
+			},
+		})
+	}
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
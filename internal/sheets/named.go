@@ -0,0 +1,68 @@
+package sheets
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// NamedRanges returns the top-left cell of every named range in the
+// spreadsheet as a sheet-qualified A1 reference, keyed by name.
+func (c *Client) NamedRanges(ctx context.Context, spreadsheetID string) (map[string]string, error) {
+	ss, err := retry(ctx, c.reads, func() (*sheets.Spreadsheet, error) {
+		return c.service.Spreadsheets.Get(spreadsheetID).
+			Fields("namedRanges,sheets.properties(sheetId,title)").
+			Context(ctx).
+			Do()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spreadsheet: %w", err)
+	}
+	titles := make(map[int64]string, len(ss.Sheets))
+	for _, sh := range ss.Sheets {
+		titles[sh.Properties.SheetId] = sh.Properties.Title
+	}
+
+	ranges := make(map[string]string, len(ss.NamedRanges))
+	for _, nr := range ss.NamedRanges {
+		title, ok := titles[nr.Range.SheetId]
+		if !ok {
+			continue
+		}
+		ref := ColumnLetter(nr.Range.StartColumnIndex) + fmt.Sprint(nr.Range.StartRowIndex+1)
+		ranges[nr.Name] = QuoteSheet(title) + "!" + ref
+	}
+	return ranges, nil
+}
+
+// AddNamedRange names the top-left cell of an A1 range, adding its sheet if
+// the spreadsheet does not have it yet.
+func (c *Client) AddNamedRange(ctx context.Context, spreadsheetID, name, range_ string) error {
+	title := SheetName(range_)
+	if title == "" {
+		return fmt.Errorf("range %q does not name a sheet", range_)
+	}
+	if err := c.EnsureSheet(ctx, spreadsheetID, title); err != nil {
+		return err
+	}
+	sh, err := c.sheetInfo(ctx, spreadsheetID, title)
+	if err != nil {
+		return err
+	}
+	row, col := StartCell(range_)
+	return c.BatchUpdate(ctx, spreadsheetID, []*sheets.Request{{
+		AddNamedRange: &sheets.AddNamedRangeRequest{
+			NamedRange: &sheets.NamedRange{
+				Name: name,
+				Range: &sheets.GridRange{
+					SheetId:          sh.Properties.SheetId,
+					StartRowIndex:    row,
+					EndRowIndex:      row + 1,
+					StartColumnIndex: col,
+					EndColumnIndex:   col + 1,
+				},
+			},
+		},
+	}})
+}
@@ -0,0 +1,129 @@
+package sheets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// DefaultTokenFile stores the refresh token saved by Login.
+const DefaultTokenFile = "token.json"
+
+// oauthConfig reads an OAuth client ("Desktop app" client JSON downloaded
+// from the Google Cloud console) for the scopes the client needs.
+func oauthConfig(clientFile string) (*oauth2.Config, error) {
+	data, err := os.ReadFile(clientFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OAuth client: %w", err)
+	}
+	cfg, err := google.ConfigFromJSON(data, sheets.SpreadsheetsScope, drive.DriveFileScope, drive.DriveReadonlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OAuth client: %w", err)
+	}
+	return cfg, nil
+}
+
+// Login runs the interactive OAuth consent flow for the client in clientFile:
+// it prints a URL to open in a browser, waits for Google to redirect back to a
+// temporary local listener and saves the resulting refresh token to
+// tokenFile, so later runs act as the signed-in Google account.
+func Login(ctx context.Context, clientFile, tokenFile string) error {
+	cfg, err := oauthConfig(clientFile)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen for OAuth redirect: %w", err)
+	}
+	cfg.RedirectURL = "http://" + ln.Addr().String() + "/"
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	state := hex.EncodeToString(b)
+	verifier := oauth2.GenerateVerifier()
+
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			http.Error(w, "unexpected state", http.StatusBadRequest)
+			return
+		}
+		if e := q.Get("error"); e != "" {
+			http.Error(w, "sign-in failed: "+e, http.StatusBadRequest)
+			select {
+			case errs <- fmt.Errorf("authorization denied: %s", e):
+			default:
+			}
+			return
+		}
+		fmt.Fprintln(w, "Signed in to torn-oc-history; you can close this tab.")
+		select {
+		case codes <- q.Get("code"):
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	url := cfg.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier))
+	fmt.Println("Open this URL in a browser and sign in with the Google account that owns the spreadsheet:")
+	fmt.Println(url)
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	tok, err := cfg.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	if tok.RefreshToken == "" {
+		return errors.New("no refresh token returned; remove the app's access from your Google account and try again")
+	}
+	data, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(tokenFile, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// userCredentials returns a client option acting as the user who signed in
+// with Login. Access tokens are refreshed from the saved refresh token.
+func userCredentials(clientFile, tokenFile string) (option.ClientOption, error) {
+	cfg, err := oauthConfig(clientFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token: %w", err)
+	}
+	var tok oauth2.Token
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return nil, fmt.Errorf("failed to parse token %s: %w", tokenFile, err)
+	}
+	return option.WithTokenSource(cfg.TokenSource(context.Background(), &tok)), nil
+}
@@ -0,0 +1,60 @@
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// ProtectColumns protects columns [startCol, endCol) of a sheet from startRow
+// down, labelled with description. With warningOnly, editors are warned before
+// changing the cells but may go ahead; otherwise only the spreadsheet owner
+// and this client can edit them. Protections left on the sheet by previous
+// runs, those whose description starts with marker and that start at the
+// same cell, are replaced whatever their width.
+func (c *Client) ProtectColumns(ctx context.Context, spreadsheetID, title string, startRow, startCol, endCol int64, marker, description string, warningOnly bool) error {
+	ss, err := retry(ctx, c.reads, func() (*sheets.Spreadsheet, error) {
+		return c.service.Spreadsheets.Get(spreadsheetID).
+			Fields("sheets(properties(sheetId,title),protectedRanges(protectedRangeId,description,range))").
+			Context(ctx).
+			Do()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get spreadsheet: %w", err)
+	}
+	var sh *sheets.Sheet
+	for _, s := range ss.Sheets {
+		if s.Properties.Title == title {
+			sh = s
+		}
+	}
+	if sh == nil {
+		return fmt.Errorf("sheet %q not found", title)
+	}
+
+	var requests []*sheets.Request
+	for _, pr := range sh.ProtectedRanges {
+		if strings.HasPrefix(pr.Description, marker) && pr.Range != nil && pr.Range.StartRowIndex == startRow && pr.Range.StartColumnIndex == startCol {
+			requests = append(requests, &sheets.Request{
+				DeleteProtectedRange: &sheets.DeleteProtectedRangeRequest{ProtectedRangeId: pr.ProtectedRangeId},
+			})
+		}
+	}
+	requests = append(requests, &sheets.Request{
+		AddProtectedRange: &sheets.AddProtectedRangeRequest{
+			ProtectedRange: &sheets.ProtectedRange{
+				Description: description,
+				WarningOnly: warningOnly,
+				Range: &sheets.GridRange{
+					SheetId:          sh.Properties.SheetId,
+					StartRowIndex:    startRow,
+					StartColumnIndex: startCol,
+					EndColumnIndex:   endCol,
+				},
+			},
+		},
+	})
+	return c.BatchUpdate(ctx, spreadsheetID, requests)
+}
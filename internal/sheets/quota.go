@@ -0,0 +1,73 @@
+package sheets
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultQuota is how many read and, separately, write requests the client
+// sends per quotaWindow: Google's default per-user limit of 60 a minute.
+const DefaultQuota = 100
+
+const quotaWindow = 100 * time.Second
+
+// quota paces one kind of request so that no more than limit are sent in any
+// quotaWindow, instead of sending them all and backing off on 429s.
+type quota struct {
+	name  string
+	mu    sync.Mutex
+	limit int
+	sent  []time.Time // send times within the last window, oldest first
+}
+
+func newQuota(name string) *quota {
+	return &quota{name: name, limit: DefaultQuota}
+}
+
+// wait blocks until another request fits in the quota and records it. A nil
+// quota or a limit of 0 never waits.
+func (q *quota) wait(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+	for {
+		q.mu.Lock()
+		if q.limit <= 0 {
+			q.mu.Unlock()
+			return nil
+		}
+		now := time.Now()
+		expired := 0
+		for expired < len(q.sent) && now.Sub(q.sent[expired]) >= quotaWindow {
+			expired++
+		}
+		q.sent = q.sent[expired:]
+		if len(q.sent) < q.limit {
+			q.sent = append(q.sent, now)
+			q.mu.Unlock()
+			return nil
+		}
+		delay := quotaWindow - now.Sub(q.sent[0])
+		q.mu.Unlock()
+
+		slog.Debug("pacing Google API calls to stay under quota", "quota", q.name, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// SetQuota changes how many read and how many write requests the client sends
+// per 100 seconds; 0 disables pacing. Raise it if your project has a higher
+// Sheets API quota.
+func (c *Client) SetQuota(requests int) {
+	for _, q := range []*quota{c.reads, c.writes} {
+		q.mu.Lock()
+		q.limit = requests
+		q.mu.Unlock()
+	}
+}
@@ -0,0 +1,111 @@
+package sheets
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var a1Pattern = regexp.MustCompile(`^[A-Za-z]*[0-9]*(:[A-Za-z]*[0-9]*)?$`)
+
+// SheetName returns the sheet (tab) title referenced by an A1 range such as
+// "History!A1" or "'OC History'!A1:G". A range without "!" that is not itself
+// an A1 reference names a whole sheet. It returns "" when the range does not
+// name a sheet.
+func SheetName(range_ string) string {
+	name := range_
+	if i := strings.LastIndex(range_, "!"); i >= 0 {
+		name = range_[:i]
+	} else if a1Pattern.MatchString(range_) {
+		return ""
+	}
+	if len(name) >= 2 && name[0] == '\'' && name[len(name)-1] == '\'' {
+		name = strings.ReplaceAll(name[1:len(name)-1], "''", "'")
+	}
+	return name
+}
+
+// ValidateA1 reports what is wrong with an A1 range, such as a missing cell
+// reference after "!" or unbalanced quotes around the sheet title.
+func ValidateA1(range_ string) error {
+	if range_ == "" {
+		return fmt.Errorf("empty range")
+	}
+	i := strings.LastIndex(range_, "!")
+	if i < 0 {
+		// a cell reference, or a sheet title on its own
+		return nil
+	}
+	sheet, ref := range_[:i], range_[i+1:]
+	if sheet == "" {
+		return fmt.Errorf("range %q has no sheet title before !", range_)
+	}
+	if strings.HasPrefix(sheet, "'") != strings.HasSuffix(sheet, "'") || sheet == "'" {
+		return fmt.Errorf("range %q has unbalanced quotes around the sheet title", range_)
+	}
+	if ref == "" || !a1Pattern.MatchString(ref) {
+		return fmt.Errorf("range %q has no valid cell reference after !, e.g. A1 or A1:G", range_)
+	}
+	m := cellPattern.FindStringSubmatch(ref)
+	if len(m[1]) > 3 {
+		return fmt.Errorf("range %q: column %s is beyond the last column of a sheet", range_, m[1])
+	}
+	return nil
+}
+
+var cellPattern = regexp.MustCompile(`^([A-Za-z]*)([0-9]*)`)
+
+// StartCell returns the zero-based row and column of the top-left cell of an
+// A1 range. Ranges naming a whole sheet or whole columns start at row 0 and
+// column 0 as appropriate.
+func StartCell(range_ string) (row, col int64) {
+	ref := range_
+	if i := strings.LastIndex(range_, "!"); i >= 0 {
+		ref = range_[i+1:]
+	} else if !a1Pattern.MatchString(range_) {
+		return 0, 0
+	}
+	m := cellPattern.FindStringSubmatch(ref)
+	for _, ch := range strings.ToUpper(m[1]) {
+		col = col*26 + int64(ch-'A'+1)
+	}
+	if col > 0 {
+		col--
+	}
+	for _, ch := range m[2] {
+		row = row*10 + int64(ch-'0')
+	}
+	if row > 0 {
+		row--
+	}
+	return row, col
+}
+
+// ColumnLetter converts a zero-based column index to its A1 letters.
+func ColumnLetter(col int64) string {
+	s := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		s = string(rune('A'+(col-1)%26)) + s
+	}
+	return s
+}
+
+// QuoteSheet quotes a sheet title for use in an A1 range.
+func QuoteSheet(title string) string {
+	return "'" + strings.ReplaceAll(title, "'", "''") + "'"
+}
+
+// GridA1 builds an A1 range for the zero-based, inclusive block of cells
+// starting at (startRow, startCol). A negative endRow leaves the range open
+// downwards. An empty sheet refers to the first sheet.
+func GridA1(sheet string, startRow, startCol, endRow, endCol int64) string {
+	ref := fmt.Sprintf("%s%d:%s", ColumnLetter(startCol), startRow+1, ColumnLetter(endCol))
+	if endRow >= 0 {
+		ref += strconv.FormatInt(endRow+1, 10)
+	}
+	if sheet == "" {
+		return ref
+	}
+	return QuoteSheet(sheet) + "!" + ref
+}
@@ -0,0 +1,80 @@
+package sheets
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	maxAttempts = 5
+	baseBackoff = time.Second
+	maxBackoff  = time.Minute
+)
+
+// retry runs call, retrying quota (429) and unavailable (503) errors with
+// exponential backoff. A Retry-After header on the error takes precedence
+// over the computed delay. Every attempt is paced by q, which may be nil.
+func retry[T any](ctx context.Context, q *quota, call func() (T, error)) (T, error) {
+	backoff := baseBackoff
+	for attempt := 1; ; attempt++ {
+		if err := q.wait(ctx); err != nil {
+			var zero T
+			return zero, err
+		}
+		v, err := call()
+		if err == nil || attempt == maxAttempts {
+			return v, err
+		}
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || (apiErr.Code != http.StatusTooManyRequests && apiErr.Code != http.StatusServiceUnavailable) {
+			return v, err
+		}
+
+		delay := backoff
+		if d, ok := retryAfter(apiErr.Header); ok {
+			delay = d
+		}
+		slog.Warn("retrying Google API call", "status", apiErr.Code, "attempt", attempt, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return v, ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// IsRateLimited reports whether err is a Google API quota error that was still
+// failing after the retries.
+func IsRateLimited(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
+
+// IsAPIError reports whether err is an error response from a Google API.
+func IsAPIError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr)
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// Rate is a member's latest pass rate at a difficulty and position, from the
+// most recently executed crime they filled it in.
+type Rate struct {
+	Difficulty int
+	Position   string
+	Rate       int
+	// PrevRate is the rate of the observation before, nil if there was none.
+	PrevRate   *int
+	ExecutedAt int64
+	CrimeName  string
+}
+
+// Index arranges the completed crimes of a snapshot for the queries of the
+// server's endpoints, so they don't scan the whole history per request. It is
+// built once per Update, shared, and must not be modified.
+type Index struct {
+	// crimes are the completed crimes, newest first.
+	crimes []torn.Crime
+	// byMember holds, for each member, the positions in crimes of those
+	// they took part in.
+	byMember map[int][]int
+	// rates are each member's latest rates, by difficulty and position
+	rates map[int][]Rate
+	// rated are the IDs of the members with rates, in order.
+	rated []int
+}
+
+// Index returns the index of the current snapshot, building it on the first
+// call after an Update.
+func (s *Store) Index() *Index {
+	s.mu.RLock()
+	x := s.index
+	s.mu.RUnlock()
+	if x != nil {
+		return x
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index == nil {
+		s.index = buildIndex(s.snap.Crimes)
+	}
+	return s.index
+}
+
+func buildIndex(all []torn.Crime) *Index {
+	x := &Index{byMember: make(map[int][]int), rates: make(map[int][]Rate)}
+	for _, c := range all {
+		if c.ExecutedAt != 0 {
+			x.crimes = append(x.crimes, c)
+		}
+	}
+	sort.SliceStable(x.crimes, func(i, j int) bool { return x.crimes[i].ExecutedAt > x.crimes[j].ExecutedAt })
+
+	type key struct {
+		member, difficulty int
+		position           string
+	}
+	latest := make(map[key]*Rate)
+	for i, c := range x.crimes {
+		for _, slot := range c.Slots {
+			if ids := x.byMember[slot.User.ID]; len(ids) == 0 || ids[len(ids)-1] != i {
+				x.byMember[slot.User.ID] = append(ids, i)
+			}
+			k := key{slot.User.ID, c.Difficulty, slot.Position}
+			r := latest[k]
+			// newest first, so the first is the latest and the second the
+			// one before, unless executed at the same moment
+			switch {
+			case r == nil:
+				latest[k] = &Rate{Difficulty: c.Difficulty, Position: slot.Position, Rate: slot.CheckpointPassRate, ExecutedAt: c.ExecutedAt, CrimeName: c.Name}
+			case r.PrevRate == nil && c.ExecutedAt < r.ExecutedAt:
+				prev := slot.CheckpointPassRate
+				r.PrevRate = &prev
+			}
+		}
+	}
+
+	for k, r := range latest {
+		x.rates[k.member] = append(x.rates[k.member], *r)
+	}
+	for id, r := range x.rates {
+		sort.Slice(r, func(i, j int) bool {
+			if r[i].Difficulty != r[j].Difficulty {
+				return r[i].Difficulty < r[j].Difficulty
+			}
+			return r[i].Position < r[j].Position
+		})
+		x.rated = append(x.rated, id)
+	}
+	sort.Ints(x.rated)
+	return x
+}
+
+// Crimes returns the completed crimes executed at or after since, newest
+// first.
+func (x *Index) Crimes(since int64) []torn.Crime {
+	n := sort.Search(len(x.crimes), func(i int) bool { return x.crimes[i].ExecutedAt < since })
+	return x.crimes[:n]
+}
+
+// MemberCrimes returns the completed crimes the member took part in, newest
+// first.
+func (x *Index) MemberCrimes(id int) []torn.Crime {
+	ids := x.byMember[id]
+	crimes := make([]torn.Crime, len(ids))
+	for i, n := range ids {
+		crimes[i] = x.crimes[n]
+	}
+	return crimes
+}
+
+// Rates returns the member's latest pass rates, by difficulty and position.
+func (x *Index) Rates(id int) []Rate {
+	return x.rates[id]
+}
+
+// RatedMembers returns the IDs of the members with any rates, in order.
+func (x *Index) RatedMembers() []int {
+	return x.rated
+}
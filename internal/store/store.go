@@ -0,0 +1,155 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// Snapshot is the faction data fetched by the most recent successful run.
+type Snapshot struct {
+	Members   []torn.Member
+	Crimes    []torn.Crime
+	Active    []torn.Crime
+	FetchedAt time.Time
+	// Label is the --label of the run that fetched the data, if any.
+	Label string
+	// CPRLow and CPRHigh are the --cpr-low and --cpr-high bands that colour
+	// pass rates.
+	CPRLow, CPRHigh int
+}
+
+// Health describes the recent runs, for health checks.
+type Health struct {
+	LastRun     time.Time
+	LastSuccess time.Time
+	// Failures counts the runs that failed since the last success.
+	Failures  int
+	LastError string
+	// MaxAge is how old the last success may be before the process counts
+	// as wedged; 0 disables the check.
+	MaxAge time.Duration
+}
+
+// Store holds the latest snapshot for concurrent readers such as the HTTP server.
+type Store struct {
+	mu     sync.RWMutex
+	snap   Snapshot
+	health Health
+	// subs receive the crimes each Update adds
+	subs map[chan []torn.Crime]struct{}
+	// index is built from snap on demand, nil until then
+	index *Index
+}
+
+func New() *Store {
+	return &Store{subs: make(map[chan []torn.Crime]struct{})}
+}
+
+// subscriberBuffer is how many updates a slow subscriber may fall behind
+// before further ones are dropped for it.
+const subscriberBuffer = 16
+
+func (s *Store) Update(members []torn.Member, crimes []torn.Crime) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the first snapshot is the baseline, not news
+	var added []torn.Crime
+	if !s.snap.FetchedAt.IsZero() && len(s.subs) > 0 {
+		seen := make(map[int]bool, len(s.snap.Crimes))
+		for _, c := range s.snap.Crimes {
+			seen[c.ID] = true
+		}
+		for _, c := range crimes {
+			if !seen[c.ID] {
+				added = append(added, c)
+			}
+		}
+		sort.Slice(added, func(i, j int) bool { return added[i].ExecutedAt < added[j].ExecutedAt })
+	}
+	s.snap.Members = members
+	s.snap.Crimes = crimes
+	s.snap.FetchedAt = time.Now()
+	s.index = nil
+	if len(added) == 0 {
+		return
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- added:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the newly completed crimes of each
+// Update, oldest first, and a function that ends the subscription.
+func (s *Store) Subscribe() (<-chan []torn.Crime, func()) {
+	ch := make(chan []torn.Crime, subscriberBuffer)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}
+}
+
+// SetActive replaces the recruiting/planning crimes.
+func (s *Store) SetActive(active []torn.Crime) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Active = active
+}
+
+// SetLabel records the label of the run that fetched the snapshot.
+func (s *Store) SetLabel(label string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Label = label
+}
+
+// SetCPRBands records the pass rate bands of the run that fetched the
+// snapshot.
+func (s *Store) SetCPRBands(low, high int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.CPRLow, s.snap.CPRHigh = low, high
+}
+
+// RecordRun records the outcome of a run: errMsg is empty for a success.
+func (s *Store) RecordRun(errMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health.LastRun = time.Now()
+	if errMsg == "" {
+		s.health.LastSuccess = s.health.LastRun
+		s.health.Failures = 0
+		s.health.LastError = ""
+		return
+	}
+	s.health.Failures++
+	s.health.LastError = errMsg
+}
+
+// SetMaxAge sets Health.MaxAge.
+func (s *Store) SetMaxAge(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health.MaxAge = d
+}
+
+func (s *Store) Health() Health {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.health
+}
+
+func (s *Store) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snap
+}
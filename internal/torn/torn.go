@@ -0,0 +1,403 @@
+package torn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const DefaultBaseURL = "https://api.torn.com/v2"
+
+type Member struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	IsInOC     bool   `json:"is_in_oc"`
+	LastAction struct {
+		Status    string `json:"status"`
+		Timestamp int64  `json:"timestamp"`
+		Relative  string `json:"relative"`
+	} `json:"last_action"`
+}
+
+type MembersResponse struct {
+	Members []Member `json:"members"`
+}
+
+type SlotUser struct {
+	ID      int    `json:"id"`
+	Outcome string `json:"outcome"`
+}
+
+type Slot struct {
+	Position           string   `json:"position"`
+	User               SlotUser `json:"user"`
+	CheckpointPassRate int      `json:"checkpoint_pass_rate"`
+	// ItemRequirement is the item the slot needs, if any
+	ItemRequirement *ItemRequirement `json:"item_requirement"`
+}
+
+// ItemRequirement is an item a slot needs for its crime to be executed.
+// IsAvailable reports whether the member in the slot has it.
+type ItemRequirement struct {
+	ID          int  `json:"id"`
+	IsReusable  bool `json:"is_reusable"`
+	IsAvailable bool `json:"is_available"`
+}
+
+type Rewards struct {
+	Money   int64 `json:"money"`
+	Respect int   `json:"respect"`
+}
+
+type Crime struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Difficulty int      `json:"difficulty"`
+	Status     string   `json:"status"`
+	ReadyAt    int64    `json:"ready_at"`
+	ExecutedAt int64    `json:"executed_at"`
+	ExpiredAt  int64    `json:"expired_at"`
+	Slots      []Slot   `json:"slots"`
+	Rewards    *Rewards `json:"rewards"`
+}
+
+type CrimesResponse struct {
+	Crimes []Crime `json:"crimes"`
+}
+
+type FactionBasic struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Tag  string `json:"tag"`
+}
+
+type FactionBasicResponse struct {
+	Basic FactionBasic `json:"basic"`
+}
+
+// Client talks to the Torn v2 API with a single API key.
+type Client struct {
+	BaseURL string
+	Key     string
+	// MaxPages and MaxCrimes cap how much FetchCrimes fetches per category,
+	// newest first; 0 is no limit.
+	MaxPages, MaxCrimes int
+	// Progress, if set, is called by FetchCrimes after each page and once
+	// more when the category is complete.
+	Progress func(Progress)
+	// Trace, if set, is called as each API request starts, with the request's
+	// name and attributes (never the key); the function it returns is called
+	// with the request's error when it ends.
+	Trace func(name string, attrs map[string]any) func(error)
+}
+
+// Progress reports how far FetchCrimes has got through a category.
+type Progress struct {
+	Category      string
+	Pages, Crimes int
+	Done          bool
+}
+
+func NewClient(key string) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Key: key}
+}
+
+func (c *Client) FetchMembers() ([]Member, error) {
+	url := fmt.Sprintf("%s/faction/members", c.BaseURL)
+	var mr MembersResponse
+	if err := c.get("torn faction/members", nil, url, &mr); err != nil {
+		return nil, err
+	}
+	return mr.Members, nil
+}
+
+func (c *Client) FetchFactionBasic() (FactionBasic, error) {
+	url := fmt.Sprintf("%s/faction/basic", c.BaseURL)
+	var br FactionBasicResponse
+	if err := c.get("torn faction/basic", nil, url, &br); err != nil {
+		return FactionBasic{}, err
+	}
+	return br.Basic, nil
+}
+
+// FetchAllCrimes fetches every completed faction crime.
+func (c *Client) FetchAllCrimes() ([]Crime, error) {
+	return c.FetchCrimes("completed")
+}
+
+// FetchActiveCrimes fetches crimes that are still recruiting or planning.
+func (c *Client) FetchActiveCrimes() ([]Crime, error) {
+	var active []Crime
+	for _, cat := range []string{"recruiting", "planning"} {
+		crimes, err := c.FetchCrimes(cat)
+		if err != nil {
+			return nil, err
+		}
+		active = append(active, crimes...)
+	}
+	return active, nil
+}
+
+// FetchCrimes fetches every page of faction crimes in the given category, up
+// to MaxPages pages and MaxCrimes crimes. When limited, the newest crimes are
+// the ones fetched.
+func (c *Client) FetchCrimes(cat string) ([]Crime, error) {
+	return c.FetchCrimesFrom(cat, 0, nil, nil)
+}
+
+// FetchCrimesFrom is FetchCrimes resumed at offset, with the crimes before it
+// already fetched in have. checkpoint, if set, is called after each page but
+// the last with the offset of the next page and the crimes so far, so an
+// interrupted fetch can be resumed from there.
+func (c *Client) FetchCrimesFrom(cat string, offset int, have []Crime, checkpoint func(next int, crimes []Crime)) ([]Crime, error) {
+	all := have
+	err := c.fetchPages(cat, offset, len(have), func(page []Crime, next int, last bool) {
+		all = append(all, page...)
+		if !last && checkpoint != nil {
+			checkpoint(next, all)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// EachCrimesPage is FetchCrimes for callers that can fold each page into
+// what they build as it arrives: fn is called with every page, which is not
+// kept, so only a page of crimes is held in memory at a time.
+func (c *Client) EachCrimesPage(cat string, fn func(page []Crime)) error {
+	return c.fetchPages(cat, 0, 0, func(page []Crime, _ int, _ bool) { fn(page) })
+}
+
+// fetchPages fetches the pages of crimes in the given category from offset
+// on, have crimes having been fetched before it, up to MaxPages pages and
+// MaxCrimes crimes. fn is called with each page, the offset of the next and
+// whether it is the last.
+func (c *Client) fetchPages(cat string, offset, have int, fn func(page []Crime, next int, last bool)) error {
+	const pageSize = 100
+	total := have
+
+	sortOrder := ""
+	if c.MaxPages > 0 || c.MaxCrimes > 0 {
+		sortOrder = "DESC"
+	}
+	for page := offset/pageSize + 1; ; page++ {
+		crimes, err := c.FetchCrimesPage(cat, sortOrder, offset)
+		if err != nil {
+			c.report(Progress{Category: cat, Pages: page - 1, Crimes: total, Done: true})
+			return err
+		}
+
+		last := len(crimes) < pageSize || page == c.MaxPages
+		if c.MaxCrimes > 0 && total+len(crimes) >= c.MaxCrimes {
+			crimes = crimes[:max(c.MaxCrimes-total, 0)]
+			last = true
+		}
+		total += len(crimes)
+		offset += pageSize
+		c.report(Progress{Category: cat, Pages: page, Crimes: total, Done: last})
+		fn(crimes, offset, last)
+		if last {
+			return nil
+		}
+	}
+}
+
+func (c *Client) report(p Progress) {
+	if c.Progress != nil {
+		c.Progress(p)
+	}
+}
+
+// FetchCrimesPage fetches a single page of faction crimes in the given category.
+// An empty sort leaves the API default ordering.
+func (c *Client) FetchCrimesPage(cat, sortOrder string, offset int) ([]Crime, error) {
+	url := fmt.Sprintf("%s/faction/crimes?cat=%s&offset=%d", c.BaseURL, cat, offset)
+	if sortOrder != "" {
+		url += "&sort=" + sortOrder
+	}
+	var cr CrimesResponse
+	if err := c.get("torn faction/crimes", map[string]any{"torn.category": cat, "torn.offset": offset}, url, &cr); err != nil {
+		return nil, err
+	}
+	return cr.Crimes, nil
+}
+
+// FetchCrimesSince fetches, newest first, up to a page of the crimes in the
+// given category from the Unix time from on. It is a single request, for
+// cheaply telling whether there is anything new.
+func (c *Client) FetchCrimesSince(cat string, from int64) ([]Crime, error) {
+	url := fmt.Sprintf("%s/faction/crimes?cat=%s&from=%d&sort=DESC", c.BaseURL, cat, from)
+	var cr CrimesResponse
+	if err := c.get("torn faction/crimes", map[string]any{"torn.category": cat, "torn.from": from}, url, &cr); err != nil {
+		return nil, err
+	}
+	return cr.Crimes, nil
+}
+
+// get fetches url into v, traced as name.
+func (c *Client) get(name string, attrs map[string]any, url string, v any) error {
+	if c.Trace == nil {
+		return getJSON(c.Key, url, v)
+	}
+	end := c.Trace(name, attrs)
+	err := getJSON(c.Key, url, v)
+	end(err)
+	return err
+}
+
+// getJSON fetches url with key into v. The key goes in the Authorization
+// header rather than the URL, and errors name the URL without its query, so
+// neither the key nor the request parameters end up in logs, health checks
+// or notifications.
+func getJSON(key, rawURL string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return redactURL(err)
+	}
+	req.Header.Set("Authorization", "ApiKey "+key)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return redactURL(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &Error{Code: ErrTooManyRequests, Message: resp.Status}
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("bad status: %s: %s", resp.Status, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// Torn reports errors with a 200 and an error object instead of the data
+	var e struct {
+		Error *Error `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error != nil {
+		return e.Error
+	}
+	return json.Unmarshal(body, v)
+}
+
+// redactURL drops the query from the URL of a *url.Error, keeping the
+// operation, the host the API labels go by, the path and the cause.
+func redactURL(err error) error {
+	var ue *url.Error
+	if !errors.As(err, &ue) {
+		return err
+	}
+	redacted := *ue
+	if u, perr := url.Parse(ue.URL); perr == nil {
+		u.RawQuery, u.User = "", nil
+		redacted.URL = u.String()
+	} else {
+		redacted.URL = "(unparsable URL)"
+	}
+	return &redacted
+}
+
+// Torn API error codes the tool reacts to. See
+// https://www.torn.com/api.html#errors for the full list.
+const (
+	ErrKeyEmpty        = 1
+	ErrIncorrectKey    = 2
+	ErrTooManyRequests = 5
+	ErrKeyOwnerJailed  = 10
+	ErrKeyInactive     = 13
+	ErrAccessTooLow    = 16
+	ErrKeyPaused       = 18
+)
+
+// Error is an error object returned by the Torn API.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("torn API error %d: %s", e.Code, e.Message)
+}
+
+// IsAuthError reports whether the API key was rejected or lacks access.
+func IsAuthError(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Code {
+	case ErrKeyEmpty, ErrIncorrectKey, ErrKeyOwnerJailed, ErrKeyInactive, ErrAccessTooLow, ErrKeyPaused:
+		return true
+	}
+	return false
+}
+
+// IsRateLimited reports whether Torn refused the request for exceeding the
+// key's request limit.
+func IsRateLimited(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == ErrTooManyRequests
+}
+
+// crimeFields and slotFields are the keys of the crimes payload the reports
+// depend on; Crime and Slot decode them.
+var (
+	crimeFields = []string{"id", "name", "difficulty", "status", "executed_at", "slots"}
+	slotFields  = []string{"position", "user", "checkpoint_pass_rate"}
+)
+
+// CheckCrimesSchema fetches the newest page of completed crimes as plain JSON
+// and returns the fields the reports rely on that are missing from it, so a
+// change to the payload is noticed instead of decoding to zero values. A
+// faction without completed crimes can't be checked and reports nothing.
+func (c *Client) CheckCrimesSchema() ([]string, error) {
+	url := fmt.Sprintf("%s/faction/crimes?cat=completed&offset=0", c.BaseURL)
+	var raw struct {
+		Crimes []map[string]json.RawMessage `json:"crimes"`
+	}
+	if err := c.get("torn faction/crimes schema", nil, url, &raw); err != nil {
+		return nil, err
+	}
+
+	missing := make(map[string]bool)
+	for _, crime := range raw.Crimes {
+		for _, f := range crimeFields {
+			if _, ok := crime[f]; !ok {
+				missing["crimes[]."+f] = true
+			}
+		}
+		var slots []map[string]json.RawMessage
+		if err := json.Unmarshal(crime["slots"], &slots); err != nil {
+			continue
+		}
+		for _, slot := range slots {
+			for _, f := range slotFields {
+				if _, ok := slot[f]; !ok {
+					missing["crimes[].slots[]."+f] = true
+				}
+			}
+		}
+	}
+
+	// in field order rather than map order
+	var fields []string
+	for _, f := range crimeFields {
+		if missing["crimes[]."+f] {
+			fields = append(fields, "crimes[]."+f)
+		}
+	}
+	for _, f := range slotFields {
+		if missing["crimes[].slots[]."+f] {
+			fields = append(fields, "crimes[].slots[]."+f)
+		}
+	}
+	return fields, nil
+}
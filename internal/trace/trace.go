@@ -0,0 +1,264 @@
+// Package trace records each run as a trace of timed spans and exports it to
+// an OpenTelemetry collector over OTLP/HTTP with JSON encoding.
+package trace
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exportTimeout bounds how long exporting a trace may take.
+const exportTimeout = 10 * time.Second
+
+// Tracer collects the spans of traces and exports each trace when its root
+// span ends. A nil *Tracer records nothing.
+type Tracer struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	client   *http.Client
+}
+
+// FromEnv returns a tracer configured by the standard OpenTelemetry exporter
+// variables: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT (the full URL) or
+// OTEL_EXPORTER_OTLP_ENDPOINT (to which /v1/traces is added),
+// OTEL_EXPORTER_OTLP_HEADERS (k=v,k2=v2) and OTEL_SERVICE_NAME. It is nil
+// when neither endpoint is set.
+func FromEnv() *Tracer {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	if p := os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"); p != "" && p != "http/json" {
+		slog.Warn("Only the http/json OTLP protocol is supported; sending JSON", "OTEL_EXPORTER_OTLP_PROTOCOL", p)
+	}
+	t := &Tracer{
+		endpoint: endpoint,
+		headers:  make(map[string]string),
+		service:  os.Getenv("OTEL_SERVICE_NAME"),
+		client:   &http.Client{Timeout: exportTimeout},
+	}
+	if t.service == "" {
+		t.service = "torn-oc-history"
+	}
+	for _, kv := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			t.headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return t
+}
+
+// Span is a timed operation within a trace. A nil *Span records nothing, so
+// callers needn't check whether tracing is on.
+type Span struct {
+	tracer     *Tracer
+	trace      *traceData
+	id, parent string
+	name       string
+	start, end time.Time
+	attrs      map[string]any
+	err        error
+}
+
+// traceData holds the spans of one trace until its root ends.
+type traceData struct {
+	id    string
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// Start begins the root span of a new trace.
+func (t *Tracer) Start(name string) *Span {
+	if t == nil {
+		return nil
+	}
+	td := &traceData{id: randomID(16)}
+	return td.add(&Span{tracer: t, trace: td, id: randomID(8), name: name, start: time.Now()})
+}
+
+// Child begins a span within s.
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.trace.add(&Span{tracer: s.tracer, trace: s.trace, id: randomID(8), parent: s.id, name: name, start: time.Now()})
+}
+
+func (td *traceData) add(s *Span) *Span {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	td.spans = append(td.spans, s)
+	return s
+}
+
+// Set records an attribute of the span: a string, bool, int, int64 or
+// float64.
+func (s *Span) Set(key string, value any) *Span {
+	if s == nil {
+		return nil
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = make(map[string]any)
+	}
+	s.attrs[key] = value
+	return s
+}
+
+// End ends the span, as failed if err is not nil. Ending the root span
+// exports the trace.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	s.end, s.err = time.Now(), err
+	s.trace.mu.Unlock()
+	if s.parent == "" {
+		if err := s.tracer.export(s.trace); err != nil {
+			slog.Warn("Failed to export trace", "endpoint", s.tracer.endpoint, "error", err)
+		}
+	}
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// The OTLP JSON encoding of ExportTraceServiceRequest.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpSpan struct {
+		TraceID      string          `json:"traceId"`
+		SpanID       string          `json:"spanId"`
+		ParentSpanID string          `json:"parentSpanId,omitempty"`
+		Name         string          `json:"name"`
+		Kind         int             `json:"kind"`
+		Start        string          `json:"startTimeUnixNano"`
+		End          string          `json:"endTimeUnixNano"`
+		Attributes   []otlpAttribute `json:"attributes,omitempty"`
+		Status       otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// OTLP span kind and status codes.
+const (
+	kindInternal = 1
+	statusOK     = 1
+	statusError  = 2
+)
+
+func attribute(key string, v any) otlpAttribute {
+	var value map[string]any
+	switch v := v.(type) {
+	case bool:
+		value = map[string]any{"boolValue": v}
+	case int:
+		value = map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		value = map[string]any{"doubleValue": v}
+	default:
+		value = map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+	return otlpAttribute{Key: key, Value: value}
+}
+
+func (t *Tracer) export(td *traceData) error {
+	td.mu.Lock()
+	var spans []otlpSpan
+	for _, s := range td.spans {
+		end := s.end
+		if end.IsZero() {
+			// a span left open by an early return ends with the trace
+			end = time.Now()
+		}
+		span := otlpSpan{
+			TraceID: td.id, SpanID: s.id, ParentSpanID: s.parent, Name: s.name, Kind: kindInternal,
+			Start:  strconv.FormatInt(s.start.UnixNano(), 10),
+			End:    strconv.FormatInt(end.UnixNano(), 10),
+			Status: otlpStatus{Code: statusOK},
+		}
+		if s.err != nil {
+			span.Status = otlpStatus{Code: statusError, Message: s.err.Error()}
+		}
+		for k, v := range s.attrs {
+			span.Attributes = append(span.Attributes, attribute(k, v))
+		}
+		spans = append(spans, span)
+	}
+	td.mu.Unlock()
+
+	scope := otlpScopeSpans{Spans: spans}
+	scope.Scope.Name = "torn-oc-history"
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{attribute("service.name", t.service)}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
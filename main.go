@@ -2,347 +2,1106 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
+	"net/url"
 	"os"
-	"sort"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
-	sheetspkg "torn-oc-history/internal/sheets"
+	"github.com/mnuck/torn-oc-history/internal/discord"
+	"github.com/mnuck/torn-oc-history/internal/lock"
+	"github.com/mnuck/torn-oc-history/internal/metrics"
+	"github.com/mnuck/torn-oc-history/internal/notify"
+	"github.com/mnuck/torn-oc-history/internal/server"
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+	"github.com/mnuck/torn-oc-history/internal/store"
+	"github.com/mnuck/torn-oc-history/internal/systemd"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+	"github.com/mnuck/torn-oc-history/internal/trace"
 )
 
-type Member struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	IsInOC     bool   `json:"is_in_oc"`
-	LastAction struct {
-		Status    string `json:"status"`
-		Timestamp int64  `json:"timestamp"`
-		Relative  string `json:"relative"`
-	} `json:"last_action"`
-}
-
-type MembersResponse struct {
-	Members []Member `json:"members"`
-}
+// shutdownTimeout bounds how long HTTP requests being served may delay exit.
+const shutdownTimeout = 5 * time.Second
 
-type SlotUser struct {
-	ID      int    `json:"id"`
-	Outcome string `json:"outcome"`
-}
+// sheetHeader names the columns written by buildSheetRows.
+var sheetHeader = []interface{}{"Member", "ID", "Last Seen", "Difficulty", "Position", "CPR", "Executed At"}
 
-type Slot struct {
-	Position           string   `json:"position"`
-	User               SlotUser `json:"user"`
-	CheckpointPassRate int      `json:"checkpoint_pass_rate"`
-}
-
-type Crime struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	Difficulty int    `json:"difficulty"`
-	ExecutedAt int64  `json:"executed_at"`
-	Slots      []Slot `json:"slots"`
-}
+// Indexes of the CPR and Executed At columns in sheetHeader.
+const (
+	cprColumn      = 5
+	executedColumn = 6
+)
 
-type CrimesResponse struct {
-	Crimes []Crime `json:"crimes"`
+// buildSheetRows flattens the report into one row per member/difficulty/position
+// under a header row, so the sheet can be sorted and filtered natively. Members
+// without OC history get a single row with the OC columns left empty. Member
+// names link to their profiles, so the rows must be written with UserEntered.
+func buildSheetRows(report Report) [][]interface{} {
+	rows := [][]interface{}{sheetHeader}
+	for _, mr := range report.Members {
+		m := mr.Member
+		name := profileLink(m)
+		lastSeen := fmt.Sprintf("%s (%s)", m.LastAction.Status, m.LastAction.Relative)
+		if len(mr.Difficulties) == 0 {
+			rows = append(rows, []interface{}{name, m.ID, lastSeen, "", "", "", ""})
+			continue
+		}
+		for _, dr := range mr.Difficulties {
+			for _, pr := range dr.Positions {
+				var rate, executed interface{} = "", ""
+				if pr.Rate != 0 {
+					rate = cprValue(pr.Rate)
+					executed = serialTime(pr.ExecutedAt)
+				}
+				rows = append(rows, []interface{}{name, m.ID, lastSeen, dr.Difficulty, pr.Position, rate, executed})
+			}
+		}
+	}
+	return rows
 }
 
-// Store most recent checkpoint pass rate for a member at a given difficulty/position.
-type RateInfo struct {
-	Rate       int
-	ExecutedAt int64
+// buildSheetNotes returns the provenance note for the CPR cell of every row
+// written by buildSheetRows, excluding the header.
+func buildSheetNotes(report Report) [][]string {
+	var notes [][]string
+	for _, mr := range report.Members {
+		if len(mr.Difficulties) == 0 {
+			notes = append(notes, []string{""})
+			continue
+		}
+		for _, dr := range mr.Difficulties {
+			for _, pr := range dr.Positions {
+				notes = append(notes, []string{provenance(pr.RateInfo)})
+			}
+		}
+	}
+	return notes
 }
 
-// key hierarchy: memberID -> difficulty -> position -> RateInfo
-type MemberStats map[int]map[int]map[string]RateInfo
+// runApp runs the report as configured by o: once, or every o.Interval, and
+// serving HTTP endpoints when o.Listen is set.
+func runApp(ctx context.Context, o *options) {
+	filter, err := validateOptions(o)
+	if err != nil {
+		slog.Error("Invalid flags", "error", err)
+		os.Exit(1)
+	}
 
-// generateReportLines assembles the human-readable report lines that are printed to stdout.
-// The same lines are written into Google Sheets when --output=sheets.
-func generateReportLines(selected map[int]Member, stats MemberStats) []string {
-	var lines []string
-	lines = append(lines, fmt.Sprintf("Report generated at: %s", time.Now().Format(time.RFC3339)))
+	// one daemon per spreadsheet, so interval loops don't fight over it
+	var pidFile *lock.Lock
+	if path := pidFilePath(o); path != "" && (o.repeating() || o.Listen != "") {
+		if pidFile, err = acquirePIDFile(path); err != nil {
+			slog.Error("Refusing to start a second instance", "error", err)
+			os.Exit(1)
+		}
+		slog.Debug("Wrote PID file", "path", path)
+	}
 
-	type memberEntry struct {
-		ID   int
-		Name string
+	var sheetsClient *sheetspkg.Client
+	if o.Output == "sheets" && !o.DryRun {
+		opts, source, err := sheetspkg.CredentialsFromEnv("credentials.json") // file placed alongside binary
+		if err != nil {
+			slog.Error("Failed to load Google credentials", "error", err)
+			os.Exit(1)
+		}
+		slog.Debug("Using Google credentials", "source", source)
+		sheetsClient, err = sheetspkg.NewClient(ctx, opts...)
+		if err != nil {
+			slog.Error("Failed to create sheets client", "error", err)
+			os.Exit(1)
+		}
+		sheetsClient.SetQuota(o.SheetsQuota)
 	}
-	entries := make([]memberEntry, 0, len(selected))
-	for id, m := range selected {
-		entries = append(entries, memberEntry{ID: id, Name: m.Name})
+
+	var discordHook *discord.Webhook
+	if o.Output == "discord" && !o.DryRun {
+		discordHook = &discord.Webhook{URL: getRequiredEnv("DISCORD_WEBHOOK_URL")}
 	}
-	sort.Slice(entries, func(i, j int) bool {
-		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
-	})
 
-	for _, entry := range entries {
-		m := selected[entry.ID]
-		// blank line before each member block
-		lines = append(lines, "")
-		lines = append(lines, fmt.Sprintf("Member: %s (%d) - Last seen: %s (%s)", m.Name, m.ID, m.LastAction.Status, m.LastAction.Relative))
+	apiKey := getRequiredEnv("TORN_API_KEY")
+	tornClient := newTornClient(apiKey)
+	slog.Debug("Starting", "version", versionString())
+	checkCrimesSchema(tornClient)
 
-		memberStats, ok := stats[entry.ID]
-		if !ok {
-			lines = append(lines, "  No historical OC participation recorded.")
-			continue
+	// either report can live in a spreadsheet of its own, e.g. a sharable chase list
+	spreadsheetID := os.Getenv("SPREADSHEET_ID")
+	nocSpreadsheet := os.Getenv("SPREADSHEET_ID_NOC")
+	allSpreadsheet := os.Getenv("SPREADSHEET_ID_ALL")
+	if o.Output == "sheets" && spreadsheetID == "" {
+		var ranges []string
+		if nocSpreadsheet == "" && !o.RawOnly {
+			ranges = append(ranges, o.NocRange)
 		}
-
-		// sort difficulties
-		diffs := make([]int, 0, len(memberStats))
-		for d := range memberStats {
-			diffs = append(diffs, d)
-		}
-		sort.Ints(diffs)
-		for _, d := range diffs {
-			lines = append(lines, fmt.Sprintf("  Difficulty %d:", d))
-			positions := memberStats[d]
-			// sort positions alphabetically
-			var posNames []string
-			for p := range positions {
-				posNames = append(posNames, p)
-			}
-			sort.Strings(posNames)
-			for _, p := range posNames {
-				st := positions[p]
-				if st.Rate == 0 {
-					lines = append(lines, fmt.Sprintf("    %-15s %s", p, "-"))
-				} else {
-					t := time.Unix(st.ExecutedAt, 0)
-					lines = append(lines, fmt.Sprintf("    %-15s %3d%% (executed_at %s)", p, st.Rate, t.Format(time.RFC3339)))
-				}
+		if allSpreadsheet == "" && !o.RawOnly {
+			ranges = append(ranges, o.AllRange)
+		}
+		for _, r := range []string{o.LogRange, o.SummaryRange, o.ChartsRange, o.PlannerRange, o.RawRange, o.AboutRange, o.AuditRange} {
+			if r != "" {
+				ranges = append(ranges, r)
+			}
+		}
+		if len(ranges) > 0 && o.DryRun {
+			spreadsheetID = "(new spreadsheet)"
+		} else if len(ranges) > 0 {
+			spreadsheetID, err = createSpreadsheet(ctx, sheetsClient, tornClient, ranges)
+			if err != nil {
+				slog.Error("Failed to create spreadsheet", "error", err)
+				os.Exit(1)
 			}
 		}
 	}
-	return lines
-}
+	if nocSpreadsheet == "" {
+		nocSpreadsheet = spreadsheetID
+	}
+	if allSpreadsheet == "" {
+		allSpreadsheet = spreadsheetID
+	}
 
-// NEW FUNCTION TO BUILD SHEET ROWS
-func buildSheetRows(selected map[int]Member, stats MemberStats) [][]interface{} {
-	lines := generateReportLines(selected, stats)
-	rows := make([][]interface{}, len(lines))
-	for i, line := range lines {
-		rows[i] = []interface{}{line}
+	// with --watch, runs only follow changes
+	var watch *watcher
+	if o.Watch > 0 {
+		watch = &watcher{}
 	}
-	return rows
-}
 
-func main() {
-	setupEnvironment()
-	ctx := context.Background()
-	// Command-line flags
-	outputDest := flag.String("output", "stdout", "output destination: stdout or sheets")
-	allFlag := flag.Bool("all", false, "Generate report for all faction members")
-	bothFlag := flag.Bool("both", false, "Generate both reports (all members and those not in OC)")
-	nocRange := flag.String("range-noc", "History!A1", "Spreadsheet range for members not in OC")
-	allRange := flag.String("range-all", "HistoryAll!A1", "Spreadsheet range for all members report")
-	interval := flag.Duration("interval", 0, "Repeat execution at this interval (e.g. 5m). 0 runs once")
-	flag.Parse()
-
-	if *bothFlag && *allFlag {
-		slog.Error("--all and --both cannot be used together")
+	alerts := newAlerter(notify.FromEnv(), alertLookback(o), expiredSincePath(apiKey), o.AlertAfter, o.DryRun)
+
+	// SIGINT and SIGTERM let the run in progress finish its API calls and
+	// sheet writes, then exit; a second signal exits straight away
+	shutdown, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-shutdown.Done()
+		stop()
+		slog.Warn("Shutting down after the run in progress, if any; signal again to stop immediately")
+	}()
+
+	st := store.New()
+	st.SetMaxAge(o.healthMaxAge())
+	factions, err := loadFactions(splitList(o.Factions))
+	if err != nil {
+		slog.Error("Failed to load the --factions profiles", "error", err)
 		os.Exit(1)
 	}
-
-	var sheetsClient *sheetspkg.Client
-	if *outputDest == "sheets" {
-		credsFile := "credentials.json" // credentials placed alongside binary
-		var err error
-		sheetsClient, err = sheetspkg.NewClient(ctx, credsFile)
-		if err != nil {
-			slog.Error("Failed to create sheets client", "error", err)
+	srv := server.New(st)
+	serverErr := make(chan error, 2)
+	if o.Listen != "" {
+		if err := setupDiscordBot(ctx, srv, st, o.DryRun); err != nil {
+			slog.Error("Failed to set up the Discord bot", "error", err)
 			os.Exit(1)
 		}
-	} else if *outputDest != "stdout" {
-		slog.Error("--output must be either 'stdout' or 'sheets'")
-		os.Exit(1)
+		setupSlack(srv, st)
+		if err := setupAuth(srv); err != nil {
+			slog.Error("Failed to set up server authentication", "error", err)
+			os.Exit(1)
+		}
+		serveFactions(srv, factions, o)
+		setupProxy(srv, o)
+		go func() {
+			serverErr <- srv.ListenAndServe(o.Listen)
+		}()
+		if o.GRPCListen != "" {
+			go func() {
+				serverErr <- srv.ListenAndServeGRPC(o.GRPCListen)
+			}()
+		}
+	}
+	exit := func() {
+		if o.Listen != "" {
+			c, cancel := context.WithTimeout(ctx, shutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(c); err != nil {
+				slog.Error("Server shutdown", "error", err)
+			}
+		}
+		sdNotify("STOPPING=1")
+		if pidFile != nil {
+			pidFile.Release()
+		}
+		slog.Info("Stopped")
+		os.Exit(exitOK)
 	}
 
-	apiKey := getRequiredEnv("TORN_API_KEY")
-	baseURL := "https://api.torn.com/v2"
+	// each run is a trace when an OTLP endpoint is configured; runSpan is the
+	// root span of the run in progress, nil otherwise. Either way the time
+	// each step takes goes into the run summary of curRun.
+	tracer := trace.FromEnv()
+	var runSpan *trace.Span
+	var curRun *runInfo
+	tornClient.Trace = func(name string, attrs map[string]any) func(error) {
+		s := runSpan.Child(name)
+		for k, v := range attrs {
+			s.Set(k, v)
+		}
+		start := time.Now()
+		return func(err error) {
+			s.End(redactErr(err))
+			curRun.timed(name, time.Since(start))
+		}
+	}
+	traced := func(name string, f func() error) error {
+		s := runSpan.Child(name)
+		start := time.Now()
+		err := f()
+		s.End(redactErr(err))
+		curRun.timed(name, time.Since(start))
+		return err
+	}
 
-	runReports := func() {
-		members, err := fetchMembers(baseURL, apiKey)
-		if err != nil {
-			slog.Error("fetch members", "error", err)
-			return
+	runReports := func(info *runInfo) (err error) {
+		// kept until the run finishes, so an interrupted one is resumed
+		var state *runState
+		if !o.DryRun && o.Explain == 0 {
+			state = loadRunState(o.StateFile)
+		}
+		defer func() {
+			if err == nil {
+				state.clear()
+			}
+		}()
+
+		// the members, completed crimes and active crimes are fetched at once
+		var (
+			members                          []torn.Member
+			crimes, active                   []torn.Crime
+			membersErr, crimesErr, activeErr error
+			fetches                          sync.WaitGroup
+		)
+		// the completed crimes are only kept for the outputs listing them;
+		// otherwise each page is folded into the stats and dropped, which
+		// keeps the memory use of years of history down to the stats
+		keepCrimes := o.Explain != 0 || state != nil || o.Listen != "" || o.StaticDir != "" ||
+			(o.Output == "sheets" && (o.ChartsRange != "" || o.RawRange != ""))
+		var statsAll MemberStats
+		fetches.Go(func() {
+			defer reportPanic()
+			members, membersErr = tornClient.FetchMembers()
+		})
+		fetches.Go(func() {
+			defer reportPanic()
+			switch {
+			case o.Explain != 0:
+				crimes, crimesErr = tornClient.FetchAllCrimes()
+			case keepCrimes:
+				crimes, crimesErr = fetchCompleted(tornClient, state)
+			default:
+				statsAll = make(MemberStats)
+				crimesErr = tornClient.EachCrimesPage("completed", func(page []torn.Crime) {
+					for _, c := range page {
+						statsAll.Add(c)
+						info.recordCrime(c)
+					}
+					checkCatalog(page...)
+				})
+			}
+		})
+		needActive := o.Explain == 0 && (o.Listen != "" || (o.Output == "sheets" && o.PlannerRange != "") || (alerts != nil && o.ExpiryAlert > 0))
+		if needActive {
+			fetches.Go(func() {
+				defer reportPanic()
+				active, activeErr = tornClient.FetchActiveCrimes()
+			})
+		}
+		fetches.Wait()
+		switch {
+		case membersErr != nil:
+			return fmt.Errorf("fetch members: %w", membersErr)
+		case crimesErr != nil:
+			return fmt.Errorf("fetch crimes: %w", crimesErr)
+		case activeErr != nil:
+			return fmt.Errorf("fetch active crimes: %w", activeErr)
+		}
+		info.Members = len(members)
+		if keepCrimes {
+			info.recordCrimes(crimes)
+			checkCatalog(crimes...)
+		}
+		checkCatalog(active...)
+		if o.Explain != 0 {
+			explainMember(os.Stdout, o.Explain, members, crimes)
+			return nil
 		}
 
-		selectedAll := make(map[int]Member)
+		watch.saw(members, info.NewestAt)
+		st.Update(members, crimes)
+		st.SetLabel(info.Label)
+		st.SetCPRBands(cprLow, cprHigh)
+		if needActive {
+			st.SetActive(active)
+		}
+
+		if keepCrimes {
+			traced("build stats", func() error {
+				statsAll = buildStats(crimes)
+				return nil
+			})
+		}
+
+		// finish follows the outputs: the static dashboard and the alerts,
+		// which don't depend on any members being selected for the reports
+		finish := func() {
+			if o.StaticDir != "" {
+				if o.DryRun {
+					fmt.Printf("Would write the dashboard to %s\n", o.StaticDir)
+				} else if err := traced("write static dashboard", func() error { return server.WriteDashboard(o.StaticDir, st) }); err != nil {
+					info.fail("write static dashboard", err, "dir", o.StaticDir)
+				} else {
+					slog.Info("Wrote the static dashboard", "dir", o.StaticDir)
+				}
+			}
+
+			alerts.newMembers(ctx, members, statsAll)
+			alerts.expiringCrimes(ctx, active, members, statsAll, o.ExpiryAlert)
+		}
+
+		selectedAll := make(map[int]torn.Member)
 		for _, m := range members {
 			selectedAll[m.ID] = m
 		}
 
-		selectedNoOC := make(map[int]Member)
+		selectedNoOC := make(map[int]torn.Member)
 		for _, m := range members {
 			if !m.IsInOC {
 				selectedNoOC[m.ID] = m
 			}
 		}
 
-		var selected map[int]Member
-		if *bothFlag {
+		var selected map[int]torn.Member
+		if len(filter.ids) > 0 {
+			selected = make(map[int]torn.Member)
+			for _, id := range filter.ids {
+				if m, ok := selectedAll[id]; ok {
+					selected[id] = m
+				} else {
+					slog.Warn("Member from --members is not in the faction", "id", id)
+				}
+			}
+		} else if o.Both {
 			selected = selectedNoOC // used for empty check only
-		} else if *allFlag {
+		} else if o.All {
 			selected = selectedAll
 		} else {
 			selected = selectedNoOC
 		}
 
-		if len(selected) == 0 && !*bothFlag {
-			fmt.Println("No matching faction members found.")
-			return
+		if len(selected) == 0 && !o.Both && o.Output != "none" {
+			if !quiet {
+				fmt.Println("No matching faction members found.")
+			}
+			finish()
+			return nil
 		}
 
-		crimes, err := fetchAllCrimes(baseURL, apiKey)
-		if err != nil {
-			slog.Error("fetch crimes", "error", err)
-			return
+		type namedReport struct {
+			Title       string
+			Spreadsheet string
+			Range       string
+			Report      Report
+		}
+		var reports []namedReport
+		if o.Both {
+			reports = []namedReport{
+				{"Members not in OC", nocSpreadsheet, o.NocRange, buildReport(selectedNoOC, statsAll)},
+				{"All Members", allSpreadsheet, o.AllRange, buildReport(selectedAll, statsAll)},
+			}
+		} else if o.All {
+			reports = []namedReport{{"All Members", allSpreadsheet, o.AllRange, buildReport(selected, statsAll)}}
+		} else if len(filter.ids) > 0 {
+			reports = []namedReport{{"Selected members", nocSpreadsheet, o.NocRange, buildReport(selected, statsAll)}}
+		} else {
+			reports = []namedReport{{"Members not in OC", nocSpreadsheet, o.NocRange, buildReport(selected, statsAll)}}
+		}
+		for i := range reports {
+			reports[i].Report = filter.apply(reports[i].Report)
 		}
 
-		statsAll := make(MemberStats)
-		for _, crime := range crimes {
-			for _, slot := range crime.Slots {
-				uid := slot.User.ID
-				if _, ok := statsAll[uid]; !ok {
-					statsAll[uid] = make(map[int]map[string]RateInfo)
+		switch o.Output {
+		case "stdout":
+			if quiet {
+				break
+			}
+			for i, r := range reports {
+				if o.Both {
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Printf("=== %s ===\n", r.Title)
 				}
-				if _, ok := statsAll[uid][crime.Difficulty]; !ok {
-					statsAll[uid][crime.Difficulty] = make(map[string]RateInfo)
+				traced("print report", func() error {
+					printReport(r.Report, o.Format, useColor(o.Color))
+					return nil
+				})
+			}
+		case "discord":
+			sheetURL := spreadsheetURL(spreadsheetID)
+			for _, r := range reports {
+				if o.DryRun {
+					previewDiscord(os.Stdout, discordMessages(o.DiscordMode, r.Title, r.Report, sheetURL))
+					continue
 				}
-				if _, ok := statsAll[uid][crime.Difficulty][slot.Position]; !ok {
-					statsAll[uid][crime.Difficulty][slot.Position] = RateInfo{}
+				if state.done("discord " + r.Title) {
+					slog.Info("Report was sent to Discord before the run was interrupted", "report", r.Title)
+					continue
 				}
-				st := statsAll[uid][crime.Difficulty][slot.Position]
-				if crime.ExecutedAt > st.ExecutedAt {
-					st.Rate = slot.CheckpointPassRate
-					st.ExecutedAt = crime.ExecutedAt
-					statsAll[uid][crime.Difficulty][slot.Position] = st
+				var sent int
+				err := traced("send discord", func() (err error) {
+					sent, err = sendDiscord(ctx, discordHook, o.DiscordMode, r.Title, r.Report, sheetURL)
+					return err
+				})
+				if err != nil {
+					info.fail("send report to Discord", err, "report", r.Title)
+				} else {
+					slog.Info("Sent report to Discord", "report", r.Title, "messages", sent)
+					info.wrote("discord", sent)
+					state.markDone("discord " + r.Title)
 				}
 			}
-		}
+		case "sheets":
+			// reports sharing a spreadsheet are written together
+			var ids []string
+			groups := make(map[string][]namedReport)
+			if o.RawOnly {
+				reports = nil
+			}
+			for _, r := range reports {
+				if _, ok := groups[r.Spreadsheet]; !ok {
+					ids = append(ids, r.Spreadsheet)
+				}
+				groups[r.Spreadsheet] = append(groups[r.Spreadsheet], r)
+			}
 
-		if *bothFlag {
-			if *outputDest == "stdout" {
-				fmt.Println("=== Members not in OC ===")
-				printReport(selectedNoOC, statsAll)
-				fmt.Println("\n=== All Members ===")
-				printReport(selectedAll, statsAll)
-			} else {
-				spreadsheetID := getRequiredEnv("SPREADSHEET_ID")
-				rowsNoOC := buildSheetRows(selectedNoOC, statsAll)
-				if err := sheetsClient.ClearRange(ctx, spreadsheetID, *nocRange); err != nil {
-					slog.Error("clear not-in-OC sheet", "error", err)
+			// writeGroup writes the report tabs of one spreadsheet, returning the
+			// CPR blocks the summary and charts can refer to
+			writeGroup := func(id string) (blocks []cprBlock) {
+				group := groups[id]
+				var writes []sheetspkg.RangeValues
+				var tabs []difficultyTab
+				for _, r := range group {
+					if o.SplitDifficulty {
+						for _, tab := range buildDifficultyTabs(r.Report, sheetspkg.SheetName(r.Range), statsAll, filter) {
+							tabs = append(tabs, tab)
+							writes = append(writes, tab.RangeValues)
+						}
+					} else {
+						writes = append(writes, sheetspkg.RangeValues{Range: r.Range, Values: buildSheetRows(r.Report), UserEntered: true})
+					}
+				}
+				if o.DryRun {
+					previewWrites(os.Stdout, id, "write", writes, !o.DiffWrites)
+					return nil
 				}
-				if err := sheetsClient.UpdateRange(ctx, spreadsheetID, *nocRange, rowsNoOC); err != nil {
-					slog.Error("write not-in-OC sheet", "error", err)
+				write := writeSheets
+				var backupErr error
+				if o.DiffWrites {
+					write = writeSheetsDiff
+				} else if o.Backups > 0 {
+					var titles []string
+					for _, w := range writes {
+						if name := sheetspkg.SheetName(w.Range); name != "" {
+							titles = append(titles, name)
+						}
+					}
+					stamp := info.StartedAt.UTC().Format("20060102-150405")
+					backupErr = sheetsClient.BackupSheets(ctx, id, titles, stamp, o.Backups)
+				}
+				// the summary and charts can only refer to tabs in their own spreadsheet
+				local := id == spreadsheetID
+				if backupErr != nil {
+					// don't clear tabs we could not back up
+					info.failWith(exitSheetsWrite, "back up sheets", backupErr, "spreadsheet", id)
+				} else if err := traced("write sheets", func() error {
+					return timedSheetWrite(func() error { return write(ctx, sheetsClient, id, writes) })
+				}); err != nil {
+					info.failWith(exitSheetsWrite, "write sheets", err, "spreadsheet", id)
+				} else if o.SplitDifficulty {
+					for _, tab := range tabs {
+						slog.Info("Wrote difficulty tab to Google Sheet", "spreadsheet", id, "range", tab.Range, "rows", len(tab.Values))
+						info.wrote(tab.Range, len(tab.Values))
+						if !o.DataOnly {
+							formatTable(ctx, sheetsClient, id, tab.Range, matrixColumns+tab.Positions, matrixColumns, matrixColumns+tab.Positions)
+							setCPRNotes(ctx, sheetsClient, id, tab.Range, matrixColumns, tab.Notes)
+						}
+						protectTable(ctx, sheetsClient, id, tab.Range, matrixColumns+tab.Positions)
+						if b, ok := newCPRBlock(sheetspkg.SheetName(tab.Range), tab.Range, matrixColumns, matrixColumns+tab.Positions); ok && local {
+							blocks = append(blocks, b)
+						}
+					}
 				} else {
-					slog.Info("Wrote NOT_IN_OC report to Google Sheet", "rows", len(rowsNoOC))
+					for i, w := range writes {
+						slog.Info("Wrote report to Google Sheet", "spreadsheet", id, "range", w.Range, "rows", len(w.Values))
+						info.wrote(w.Range, len(w.Values))
+						if !o.DataOnly {
+							formatReportSheet(ctx, sheetsClient, id, w.Range)
+							setCPRNotes(ctx, sheetsClient, id, w.Range, cprColumn, buildSheetNotes(group[i].Report))
+						}
+						protectTable(ctx, sheetsClient, id, w.Range, len(sheetHeader))
+						if b, ok := newCPRBlock(group[i].Title, w.Range, cprColumn, cprColumn+1); ok && local {
+							blocks = append(blocks, b)
+						}
+					}
 				}
+				return blocks
+			}
 
-				rowsAll := buildSheetRows(selectedAll, statsAll)
-				if err := sheetsClient.ClearRange(ctx, spreadsheetID, *allRange); err != nil {
-					slog.Error("clear ALL sheet", "error", err)
+			// the report tabs (then the summary and charts, which refer to
+			// them), planner, raw data and history log are written at once;
+			// a dry run previews them in order
+			reportTabs := func() {
+				groupBlocks := make([][]cprBlock, len(ids))
+				var groupWrites sync.WaitGroup
+				for i, id := range ids {
+					if o.DryRun {
+						groupBlocks[i] = writeGroup(id)
+					} else {
+						groupWrites.Go(func() {
+							defer reportPanic()
+							groupBlocks[i] = writeGroup(id)
+						})
+					}
 				}
-				if err := sheetsClient.UpdateRange(ctx, spreadsheetID, *allRange, rowsAll); err != nil {
-					slog.Error("write ALL sheet", "error", err)
-				} else {
-					slog.Info("Wrote ALL report to Google Sheet", "rows", len(rowsAll))
+				groupWrites.Wait()
+				var blocks []cprBlock
+				for _, b := range groupBlocks {
+					blocks = append(blocks, b...)
+				}
+
+				if o.SummaryRange != "" {
+					rows := buildSummaryRows(buildReport(selectedAll, statsAll), info, blocks)
+					if o.DryRun {
+						previewWrites(os.Stdout, spreadsheetID, "write", []sheetspkg.RangeValues{{Range: o.SummaryRange, Values: rows}}, true)
+					} else if err := traced("write summary", func() error { return writeSummary(ctx, sheetsClient, spreadsheetID, o.SummaryRange, rows) }); err != nil {
+						info.fail("write summary", err)
+					} else {
+						info.wrote(o.SummaryRange, len(rows))
+					}
+				}
+				if o.ChartsRange != "" {
+					if o.DryRun {
+						previewWrites(os.Stdout, spreadsheetID, "write", []sheetspkg.RangeValues{{Range: o.ChartsRange, Values: buildCrimesPerWeek(crimes)}}, true)
+					} else if err := traced("write charts", func() error { return writeCharts(ctx, sheetsClient, spreadsheetID, o.ChartsRange, crimes, blocks) }); err != nil {
+						info.fail("write charts", err)
+					}
 				}
 			}
-		} else {
-			if *outputDest == "stdout" {
-				printReport(selected, statsAll)
-			} else {
-				spreadsheetID := getRequiredEnv("SPREADSHEET_ID")
-				rows := buildSheetRows(selected, statsAll)
-				targetRange := *nocRange
-				if *allFlag {
-					targetRange = *allRange
+			planner := func() {
+				if o.PlannerRange != "" {
+					items, err := loadItemHolders(itemsFile)
+					if err != nil {
+						info.fail("read items", err)
+					}
+					slots := buildOpenSlots(active, members, statsAll, items)
+					if o.DryRun {
+						rows, _ := buildPlannerRows(slots, nil)
+						previewWrites(os.Stdout, spreadsheetID, "write", []sheetspkg.RangeValues{{Range: o.PlannerRange, Values: rows}}, true)
+					} else if err := traced("write planner", func() error { return writePlanner(ctx, sheetsClient, spreadsheetID, o.PlannerRange, slots) }); err != nil {
+						info.fail("write planner", err)
+					} else {
+						slog.Info("Wrote planner", "slots", len(slots))
+						info.wrote(o.PlannerRange, len(slots))
+					}
 				}
-				if err := sheetsClient.ClearRange(ctx, spreadsheetID, targetRange); err != nil {
-					slog.Error("clear sheet", "error", err)
+			}
+			rawData := func() {
+				if o.RawRange != "" {
+					raw := []sheetspkg.RangeValues{{Range: o.RawRange, Values: buildRawRows(crimes, members)}}
+					if o.DryRun {
+						previewWrites(os.Stdout, spreadsheetID, "write", raw, true)
+					} else if err := traced("write raw data", func() error { return writeSheets(ctx, sheetsClient, spreadsheetID, raw) }); err != nil {
+						info.fail("write raw data", err)
+					} else {
+						slog.Info("Wrote raw data", "range", o.RawRange, "rows", len(raw[0].Values))
+						info.wrote(o.RawRange, len(raw[0].Values))
+						if !o.DataOnly {
+							formatRawSheet(ctx, sheetsClient, spreadsheetID, o.RawRange)
+						}
+						protectTable(ctx, sheetsClient, spreadsheetID, o.RawRange, len(rawHeader))
+					}
 				}
-				if err := sheetsClient.UpdateRange(ctx, spreadsheetID, targetRange, rows); err != nil {
-					slog.Error("write sheet", "error", err)
+			}
+			historyLog := func() {
+				if o.LogRange != "" {
+					rows := buildHistoryLogRows(buildReport(selectedAll, statsAll), info.Label)
+					if o.DryRun {
+						previewWrites(os.Stdout, spreadsheetID, "append", []sheetspkg.RangeValues{{Range: o.LogRange, Values: rows}}, false)
+					} else if state.done("history log") {
+						slog.Info("History log rows were appended before the run was interrupted")
+					} else if err := traced("append history log", func() error { return appendHistoryLog(ctx, sheetsClient, spreadsheetID, o.LogRange, rows) }); err != nil {
+						info.fail("append history log", err)
+					} else {
+						slog.Info("Appended history log rows", "rows", len(rows))
+						info.wrote(o.LogRange, len(rows))
+						state.markDone("history log")
+					}
+				}
+			}
+			var sinks sync.WaitGroup
+			for _, sink := range []func(){reportTabs, planner, rawData, historyLog} {
+				if o.DryRun {
+					sink()
 				} else {
-					slog.Info("Wrote report to Google Sheet", "rows", len(rows))
+					sinks.Go(func() {
+						defer reportPanic()
+						sink()
+					})
+				}
+			}
+			sinks.Wait()
+		}
+
+		finish()
+		return nil
+	}
+
+	// range flags as given, so named ranges are looked up again on every run
+	targets := []*string{&o.NocRange, &o.AllRange, &o.LogRange, &o.SummaryRange, &o.ChartsRange, &o.PlannerRange, &o.RawRange, &o.AboutRange, &o.AuditRange}
+	targetSpreadsheets := []string{nocSpreadsheet, allSpreadsheet, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID}
+	specs := make([]string, len(targets))
+	for i, t := range targets {
+		specs[i] = *t
+	}
+
+	runOnce := func(trigger string) *runInfo {
+		info := newRunInfo()
+		info.Trigger = trigger
+		curRun = info
+		if o.LockFile != "" {
+			l, err := lock.Acquire(o.LockFile, o.LockWait)
+			if errors.Is(err, lock.ErrLocked) {
+				slog.Warn("Another instance holds the lock file; skipping this run", "lock_file", o.LockFile)
+				info.Code = exitLocked
+				return info
+			} else if err != nil {
+				info.failWith(exitError, "lock", err)
+				return info
+			}
+			defer l.Release()
+		}
+		if o.Output == "sheets" && o.DryRun {
+			// named ranges can't be looked up without the API
+			for i, t := range targets {
+				*t = fallbackRange(specs[i])
+			}
+		} else if o.Output == "sheets" {
+			for i, t := range targets {
+				resolved, err := resolveTargets(ctx, sheetsClient, targetSpreadsheets[i], specs[i:i+1])
+				if err != nil {
+					info.failWith(exitSheetsWrite, "resolve named ranges", err)
+					alerts.runDone(ctx, info)
+					return info
 				}
+				*t = resolved[0]
+			}
+		}
+		if err := runReports(info); err != nil {
+			info.failWith(exitError, "run reports", err)
+		}
+		if o.Output == "sheets" && o.AboutRange != "" && o.DryRun {
+			previewWrites(os.Stdout, spreadsheetID, "write", []sheetspkg.RangeValues{{Range: o.AboutRange, Values: buildAboutRows(info)}}, true)
+		} else if o.Output == "sheets" && o.AboutRange != "" {
+			about := []sheetspkg.RangeValues{{Range: o.AboutRange, Values: buildAboutRows(info)}}
+			if err := traced("write about block", func() error { return writeSheets(ctx, sheetsClient, spreadsheetID, about) }); err != nil {
+				slog.Error("write about block", "error", err)
+			} else {
+				info.wrote(o.AboutRange, len(about[0].Values))
 			}
 		}
+		alerts.runDone(ctx, info)
+		alerts.expiredCrimes(ctx, tornClient)
+		if quiet {
+			fmt.Println(info.summary())
+		}
+		return info
+	}
+
+	var streak errorStreak
+	// run records each run's outcome for /healthz; a run skipped for the
+	// lock is neither a success nor a failure
+	run := func(trigger string) *runInfo {
+		runSpan = tracer.Start("run")
+		info := runOnce(trigger)
+		var runErr error
+		if len(info.Errors) > 0 {
+			runErr = errors.New(info.Errors[0])
+		}
+		runSpan.Set("crimes", info.Crimes).Set("exit_code", info.Code).Set("output", o.Output)
+		if info.Label != "" {
+			runSpan.Set("label", info.Label)
+		}
+		runSpan.End(redactErr(runErr))
+		info.logSummary()
+		if !o.DryRun {
+			streak.runDone(info)
+		}
+		// the audit log records every run, skipped and failed ones too
+		if !o.DryRun && (o.AuditFile != "" || (o.Output == "sheets" && o.AuditRange != "")) {
+			entry := newAuditEntry(info)
+			if o.AuditFile != "" {
+				if err := appendAuditFile(o.AuditFile, entry); err != nil {
+					slog.Error("append to audit file", "path", o.AuditFile, "error", err)
+				}
+			}
+			if o.Output == "sheets" && o.AuditRange != "" {
+				if err := appendAuditRow(ctx, sheetsClient, spreadsheetID, fallbackRange(o.AuditRange), entry); err != nil {
+					slog.Error("append audit log", "error", err)
+				}
+			}
+		}
+		// the watchdog is only fed while runs make progress, so systemd
+		// restarts a scheduler that keeps failing or has stalled
+		if info.Code == exitOK || info.Code == exitPartial || info.Code == exitLocked {
+			sdNotify("WATCHDOG=1")
+		}
+		sdNotify("STATUS=" + info.summary())
+		refreshFactions(factions)
+		if info.Code == exitLocked {
+			return info
+		}
+		metrics.RecordRun(info.StartedAt, info.Crimes, len(info.Errors) == 0)
+		if len(info.Errors) > 0 {
+			st.RecordRun(redact(info.Errors[0]))
+		} else {
+			st.RecordRun("")
+		}
+		return info
+	}
+
+	// first run; a --schedule waits for its first time unless there is an
+	// HTTP server to fill
+	var first *runInfo
+	if cronSchedule == nil || o.Listen != "" {
+		trigger := "startup"
+		if !o.repeating() && o.Listen == "" {
+			trigger = "once"
+		}
+		info := run(trigger)
+		first = info
+		if !o.repeating() && o.Listen == "" {
+			os.Exit(info.Code)
+		}
+		if shutdown.Err() != nil {
+			exit()
+		}
+	}
+
+	// reload applies a changed config file between runs. Settings the process
+	// was set up around can't change; on any error the previous ones stay.
+	reload := func() error {
+		prev := *o
+		restore, err := reloadFlags()
+		if err != nil {
+			return err
+		}
+		if o.Output != prev.Output || o.Listen != prev.Listen || o.GRPCListen != prev.GRPCListen || o.Factions != prev.Factions ||
+			o.BasePath != prev.BasePath || o.CORSOrigins != prev.CORSOrigins || o.TrustProxy != prev.TrustProxy || o.DryRun != prev.DryRun || !o.repeating() {
+			restore()
+			return errors.New("--output, --listen, --grpc-listen, --factions, --base-path, --cors-origins, --trust-proxy and --dry-run can't change, nor repeated runs be turned off, without a restart")
+		}
+		f, err := validateOptions(o)
+		if err == nil && o.Output == "discord" && !o.DryRun && os.Getenv("DISCORD_WEBHOOK_URL") == "" {
+			err = errors.New("DISCORD_WEBHOOK_URL environment variable is required")
+		}
+		if err != nil {
+			restore()
+			validateOptions(o)
+			return err
+		}
+
+		filter = f
+		for i, t := range targets {
+			specs[i] = *t
+		}
+		if id := os.Getenv("SPREADSHEET_ID"); id != "" {
+			spreadsheetID = id
+		}
+		nocSpreadsheet = getEnvWithDefault("SPREADSHEET_ID_NOC", spreadsheetID)
+		allSpreadsheet = getEnvWithDefault("SPREADSHEET_ID_ALL", spreadsheetID)
+		targetSpreadsheets = []string{nocSpreadsheet, allSpreadsheet, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID, spreadsheetID}
+		if discordHook != nil {
+			discordHook = &discord.Webhook{URL: os.Getenv("DISCORD_WEBHOOK_URL")}
+		}
+		next := newAlerter(notify.FromEnv(), alertLookback(o), expiredSincePath(apiKey), o.AlertAfter, o.DryRun)
+		if alerts != nil && next != nil {
+			// don't announce what has been announced already
+			next.knownMembers, next.expiredSince, next.expiring = alerts.knownMembers, alerts.expiredSince, alerts.expiring
+			next.failures, next.failingSince = alerts.failures, alerts.failingSince
+		}
+		alerts = next
+		if sheetsClient != nil {
+			sheetsClient.SetQuota(o.SheetsQuota)
+		}
+		st.SetMaxAge(o.healthMaxAge())
+		if o.Watch == 0 {
+			watch = nil
+		} else if watch == nil {
+			watch = &watcher{}
+		}
+		return nil
+	}
+
+	if wd := systemd.WatchdogInterval(); wd > 0 && o.every() > 0 && wd < o.every() {
+		slog.Warn("The systemd watchdog is shorter than --interval; it will restart the service between runs", "WatchdogSec", wd, "interval", o.every())
 	}
+	sdNotify("READY=1")
 
-	// first run
-	runReports()
+	if o.repeating() {
+		timer := newRunTimer(o.every(), cronSchedule)
+		if first != nil {
+			// a failed first run backs off the second
+			if timer.record(first); timer.failures > 0 {
+				timer.reset(o.every(), cronSchedule)
+			}
+		}
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for {
+			select {
+			case <-timer.C:
+				if watch != nil {
+					changed, err := watch.changed(tornClient)
+					if err != nil {
+						// a full run reports the failure, and backs off
+						slog.Warn("Failed to check for changes; running anyway", "error", err)
+					} else if !changed {
+						slog.Debug("Nothing changed; not running")
+						// the data served is as fresh as the check
+						st.RecordRun("")
+						sdNotify("WATCHDOG=1")
+						timer.next()
+						break
+					}
+				}
+				trigger := "interval"
+				if watch != nil {
+					trigger = "watch"
+				} else if cronSchedule != nil {
+					trigger = "schedule"
+				}
+				info := run(trigger)
+				if info.Code != exitOK && info.Code != exitPartial {
+					// what changed must be run for again
+					watch.forget()
+				}
+				timer.record(info)
+				timer.next()
+			case <-hup:
+				interval, spec, dailyAt, watchEvery := o.Interval, o.Schedule, o.DailyAt, o.Watch
+				if err := reload(); err != nil {
+					slog.Error("Failed to reload configuration; keeping the previous settings", "error", err)
+					continue
+				}
+				slog.Info("Reloaded configuration")
+				if o.Interval != interval || o.Schedule != spec || o.DailyAt != dailyAt || o.Watch != watchEvery {
+					timer.reset(o.every(), cronSchedule)
+				}
+			case <-srv.Refreshes():
+				// the schedule carries on as it was
+				run("refresh")
+			case err := <-serverErr:
+				slog.Error("Server stopped", "error", err)
+				os.Exit(1)
+			case <-shutdown.Done():
+				exit()
+			}
+			// a signal during the run ends the loop before the next one
+			if shutdown.Err() != nil {
+				exit()
+			}
+		}
+	}
 
-	if *interval > 0 {
-		ticker := time.NewTicker(*interval)
-		defer ticker.Stop()
-		for range ticker.C {
-			runReports()
+	if o.Listen != "" {
+		// keep serving the last snapshot; with no runs to watch, the
+		// watchdog is fed for as long as the process is up
+		if wd := systemd.WatchdogInterval(); wd > 0 {
+			go func() {
+				for range time.Tick(wd / 2) {
+					sdNotify("WATCHDOG=1")
+				}
+			}()
+		}
+		for {
+			select {
+			case <-srv.Refreshes():
+				run("refresh")
+			case err := <-serverErr:
+				slog.Error("Server stopped", "error", err)
+				os.Exit(1)
+			case <-shutdown.Done():
+				exit()
+			}
+			if shutdown.Err() != nil {
+				exit()
+			}
 		}
 	}
 }
 
-func fetchMembers(baseURL, key string) ([]Member, error) {
-	url := fmt.Sprintf("%s/faction/members?key=%s", baseURL, key)
-	resp, err := http.Get(url)
+// setupAuth protects the server's endpoints when SERVER_AUTH_TOKENS or
+// SERVER_BASIC_AUTH is set.
+func setupAuth(srv *server.Server) error {
+	tokens, err := server.ParseTokens(os.Getenv("SERVER_AUTH_TOKENS"))
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("SERVER_AUTH_TOKENS: %w", err)
+	}
+	var user, password string
+	if basic := os.Getenv("SERVER_BASIC_AUTH"); basic != "" {
+		var ok bool
+		if user, password, ok = strings.Cut(basic, ":"); !ok || user == "" || password == "" {
+			return errors.New("SERVER_BASIC_AUTH must be user:password")
+		}
+	}
+	if len(tokens) == 0 && user == "" {
+		return nil
 	}
-	defer resp.Body.Close()
+	srv.EnableAuth(tokens, user, password)
+	slog.Info("Server endpoints need authentication", "tokens", len(tokens), "basic_auth", user != "")
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("bad status: %s: %s", resp.Status, string(body))
+// setupProxy sets the server up for the reverse proxy in front of it and
+// the sites calling it.
+func setupProxy(srv *server.Server, o *options) {
+	if o.BasePath != "" {
+		srv.SetBasePath(o.BasePath)
+	}
+	if origins := splitList(o.CORSOrigins); len(origins) > 0 {
+		srv.EnableCORS(origins)
 	}
+	if o.TrustProxy {
+		srv.TrustProxy()
+	}
+}
 
-	var mr MembersResponse
-	if err := json.NewDecoder(resp.Body).Decode(&mr); err != nil {
-		return nil, err
+// sdNotify tells systemd the state of the service, when run as a Type=notify
+// unit.
+func sdNotify(state string) {
+	if err := systemd.Notify(state); err != nil {
+		slog.Debug("systemd notification failed", "state", state, "error", err)
 	}
-	return mr.Members, nil
 }
 
-func fetchAllCrimes(baseURL, key string) ([]Crime, error) {
-	const pageSize = 100
-	offset := 0
-	var all []Crime
+// timedSheetWrite runs a write of report tabs, recording how long it took.
+func timedSheetWrite(write func() error) error {
+	start := time.Now()
+	err := write()
+	metrics.SheetWrite(time.Since(start))
+	return err
+}
 
-	for {
-		url := fmt.Sprintf("%s/faction/crimes?key=%s&cat=completed&offset=%d", baseURL, key, offset)
-		resp, err := http.Get(url)
-		if err != nil {
-			return nil, err
+// validateOptions checks flag combinations and values, setting columnWidths
+// and returning the report filter.
+func validateOptions(o *options) (reportFilter, error) {
+	if o.Both && o.All {
+		return reportFilter{}, errors.New("--all and --both cannot be used together")
+	}
+	repeats := 0
+	for _, set := range []bool{o.Interval > 0, o.Schedule != "", o.DailyAt != "", o.Watch > 0} {
+		if set {
+			repeats++
 		}
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			return nil, fmt.Errorf("bad status: %s: %s", resp.Status, string(body))
+	}
+	if repeats > 1 {
+		return reportFilter{}, errors.New("only one of --interval, --schedule, --daily-at and --watch can be used")
+	}
+	var err error
+	cronSchedule = nil
+	if o.Schedule != "" {
+		if cronSchedule, err = parseSchedule(o.Schedule); err != nil {
+			return reportFilter{}, fmt.Errorf("--schedule: %w", err)
 		}
-		var cr CrimesResponse
-		if err := json.NewDecoder(resp.Body).Decode(&cr); err != nil {
-			resp.Body.Close()
-			return nil, err
+	}
+	if o.DailyAt != "" {
+		if cronSchedule, err = parseDailyAt(o.DailyAt); err != nil {
+			return reportFilter{}, fmt.Errorf("--daily-at: %w", err)
 		}
-		resp.Body.Close()
-
-		all = append(all, cr.Crimes...)
-		if len(cr.Crimes) < pageSize {
-			break
+	}
+	if runJitter, err = parseJitter(o.Jitter); err != nil {
+		return reportFilter{}, fmt.Errorf("--jitter: %w", err)
+	}
+	if o.AlertAfter < 1 {
+		return reportFilter{}, errors.New("--alert-after must be at least 1")
+	}
+	if o.ExpiryAlert < 0 {
+		return reportFilter{}, errors.New("--expiry-alert cannot be negative")
+	}
+	if o.MaxBackoff < 0 {
+		return reportFilter{}, errors.New("--max-backoff must not be negative")
+	}
+	maxBackoff = o.MaxBackoff
+	if columnWidths, err = parseColumnWidths(o.ColumnWidths); err != nil {
+		return reportFilter{}, fmt.Errorf("--column-widths: %w", err)
+	}
+	if o.RawOnly && o.RawRange == "" {
+		return reportFilter{}, errors.New("--raw-only requires --range-raw")
+	}
+	if protectMode != "off" && protectMode != "warn" && protectMode != "lock" {
+		return reportFilter{}, errors.New("--protect must be one of 'off', 'warn' or 'lock'")
+	}
+	if cprLow > cprHigh {
+		return reportFilter{}, errors.New("--cpr-low must not be greater than --cpr-high")
+	}
+	if o.BasePath != "" && (!strings.HasPrefix(o.BasePath, "/") || strings.ContainsAny(o.BasePath, "?#")) {
+		return reportFilter{}, errors.New("--base-path must be a path such as /oc")
+	}
+	for _, origin := range splitList(o.CORSOrigins) {
+		if u, err := url.Parse(origin); origin != "*" && (err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" || u.Path != "" || u.RawQuery != "") {
+			return reportFilter{}, fmt.Errorf("--cors-origins: %q is neither * nor an origin such as https://tools.example.com", origin)
 		}
-		offset += pageSize
 	}
-	return all, nil
+	if o.Format != "text" && o.Format != "bbcode" && o.Format != "compact" {
+		return reportFilter{}, errors.New("--format must be one of 'text', 'bbcode' or 'compact'")
+	}
+	if o.Explain != 0 && o.Output != "stdout" {
+		return reportFilter{}, errors.New("--explain prints to stdout and cannot be used with --output " + o.Output)
+	}
+	if o.Color != "auto" && o.Color != "always" && o.Color != "never" {
+		return reportFilter{}, errors.New("--color must be one of 'auto', 'always' or 'never'")
+	}
+	if o.Output == "discord" && o.DiscordMode != "plain" && o.DiscordMode != "embed" {
+		return reportFilter{}, errors.New("--discord-mode must be either 'plain' or 'embed'")
+	}
+	// "none" only refreshes the snapshot served over HTTP
+	if o.Output != "stdout" && o.Output != "sheets" && o.Output != "discord" && o.Output != "none" {
+		return reportFilter{}, errors.New("--output must be one of 'stdout', 'sheets', 'discord' or 'none'")
+	}
+	filter, err := parseReportFilter(o.FilterMember, o.FilterPosition, o.FilterDifficulty)
+	if err != nil {
+		return reportFilter{}, fmt.Errorf("--filter-difficulty: %w", err)
+	}
+	if o.Members != "" && (o.All || o.Both) {
+		return reportFilter{}, errors.New("--members cannot be used with --all or --both")
+	}
+	if filter.ids, err = parseMemberIDs(o.Members); err != nil {
+		return reportFilter{}, fmt.Errorf("--members: %w", err)
+	}
+	if _, err := loadItemHolders(itemsFile); err != nil {
+		return reportFilter{}, fmt.Errorf("--items: %w", err)
+	}
+	if err := loadCatalog(); err != nil {
+		return reportFilter{}, fmt.Errorf("--catalog: %w", err)
+	}
+	return filter, nil
 }
 
-func printReport(selected map[int]Member, stats MemberStats) {
-	for _, line := range generateReportLines(selected, stats) {
+func printReport(report Report, format string, color bool) {
+	var lines []string
+	switch {
+	case format == "bbcode":
+		fmt.Print(renderBBCode(report))
+		return
+	case format == "compact":
+		lines = generateCompactLines(report)
+	case color:
+		fmt.Print(renderColor(report))
+		return
+	default:
+		lines = generateReportLines(report)
+	}
+	for _, line := range lines {
 		fmt.Println(line)
 	}
 }
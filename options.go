@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+)
+
+// options holds the settings of a run. Each subcommand registers the flag
+// groups it needs; running without a subcommand registers all of them.
+type options struct {
+	Output      string
+	Format      string
+	DiscordMode string
+	Color       string
+	Explain     int
+	All, Both   bool
+
+	Members                                        string
+	FilterMember, FilterPosition, FilterDifficulty string
+
+	NocRange, AllRange string
+	LogRange           string
+	AuditRange         string
+	SummaryRange       string
+	ChartsRange        string
+	PlannerRange       string
+	RawRange           string
+	AboutRange         string
+	RawOnly            bool
+	SplitDifficulty    bool
+	ColumnWidths       string
+	DataOnly           bool
+	SheetsQuota        int
+	Backups            int
+	DiffWrites         bool
+	DryRun             bool
+
+	Interval time.Duration
+	Schedule string
+	DailyAt  string
+	Watch    time.Duration
+	Jitter   string
+	LockFile string
+	LockWait time.Duration
+	Listen   string
+	PIDFile  string
+	// GRPCListen is the --grpc-listen address of the gRPC API
+	GRPCListen string
+	// Factions are the --factions config profiles served besides
+	Factions string
+	// BasePath, CORSOrigins and TrustProxy set the server up behind a
+	// reverse proxy: --base-path, --cors-origins and --trust-proxy
+	BasePath    string
+	CORSOrigins string
+	TrustProxy  bool
+	// AuditFile is the --audit-file of run outcomes, as JSON lines
+	AuditFile string
+	// StaticDir is the --static-dir the dashboard is written to each run
+	StaticDir string
+	// StateFile is the --state-file for resuming interrupted runs
+	StateFile string
+	// AlertAfter is the --alert-after number of failed runs in a row that
+	// raises an alert
+	AlertAfter int
+	// ExpiryAlert is the --expiry-alert time before a crime with open slots
+	// expires that raises an alert
+	ExpiryAlert time.Duration
+	// MaxBackoff is the --max-backoff limit on the wait after failed runs
+	MaxBackoff time.Duration
+	// UnhealthyAfter is the --unhealthy-after age of the last successful run
+	UnhealthyAfter time.Duration
+}
+
+func defaultOptions() *options {
+	return &options{
+		Output:      "stdout",
+		Format:      "text",
+		DiscordMode: "plain",
+		Color:       "auto",
+		NocRange:    "History!A1",
+		AllRange:    "HistoryAll!A1",
+		SheetsQuota: sheetspkg.DefaultQuota,
+		MaxBackoff:  time.Hour,
+		AlertAfter:  1,
+	}
+}
+
+// selectionFlags choose which members are reported and how CPR is banded.
+func (o *options) selectionFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.All, "all", o.All, "Generate report for all faction members")
+	fs.BoolVar(&o.Both, "both", o.Both, "Generate both reports (all members and those not in OC)")
+	fs.StringVar(&o.Members, "members", o.Members, "Report only these members whatever their OC status: comma-separated IDs, or a file of IDs one per line")
+	fs.StringVar(&o.FilterMember, "filter-member", o.FilterMember, "Only report these members: comma-separated IDs or name fragments")
+	fs.StringVar(&o.FilterPosition, "filter-position", o.FilterPosition, "Only report these positions, comma-separated (e.g. Hacker,Picklock)")
+	fs.StringVar(&o.FilterDifficulty, "filter-difficulty", o.FilterDifficulty, "Only report these difficulties, comma-separated (e.g. 7 or 7,8)")
+	fs.IntVar(&cprLow, "cpr-low", cprLow, "CPR below this is flagged red; from here up to --cpr-high is yellow")
+	fs.IntVar(&cprHigh, "cpr-high", cprHigh, "CPR at or above this is flagged green")
+	fs.StringVar(&itemsFile, "items", itemsFile, itemsUsage)
+	fs.StringVar(&catalogFile, "catalog", catalogFile, catalogUsage)
+}
+
+// printFlags control the stdout and Discord renderings of the report.
+func (o *options) printFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Format, "format", o.Format, "stdout report format: text, bbcode or compact (one line per member)")
+	fs.StringVar(&o.DiscordMode, "discord-mode", o.DiscordMode, "Discord message style: plain or embed")
+	fs.StringVar(&o.Color, "color", o.Color, "Colour the text report on stdout: auto (when stdout is a terminal and NO_COLOR is unset), always or never")
+	fs.IntVar(&o.Explain, "explain", o.Explain, "Instead of the report, print for this member ID which crime and slot each pass rate comes from and the older observations it superseded")
+	fs.DurationVar(&staleAfter, "stale-after", staleAfter, "In the coloured report, dim pass rates from crimes executed longer ago than this; 0 disables")
+}
+
+// sheetsFlags control where and how the report is written to Google Sheets.
+func (o *options) sheetsFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.NocRange, "range-noc", o.NocRange, "Spreadsheet range for members not in OC, or @Name[=Sheet!A1] for a named range")
+	fs.StringVar(&o.AllRange, "range-all", o.AllRange, "Spreadsheet range for all members report, or @Name[=Sheet!A1] for a named range")
+	fs.BoolVar(&o.SplitDifficulty, "split-difficulty", o.SplitDifficulty, "Write each report as one member x position matrix tab per difficulty instead of a single tab")
+	fs.StringVar(&o.ColumnWidths, "column-widths", o.ColumnWidths, "Comma-separated pixel widths for the leading columns of each sheet table (e.g. 160,80,180); other columns are auto-resized")
+	fs.BoolVar(&o.DataOnly, "data-only", o.DataOnly, "Only write values to the report tabs, leaving formatting, notes and column widths to the spreadsheet (e.g. one copied from a template)")
+	fs.StringVar(&protectMode, "protect", protectMode, "Protect the report and summary tables from manual edits: off, warn (confirm before editing) or lock")
+	fs.IntVar(&o.SheetsQuota, "sheets-quota", o.SheetsQuota, "Send at most this many Sheets API read requests, and as many writes, per 100 seconds; 0 disables pacing")
+	fs.IntVar(&o.Backups, "backups", o.Backups, "Before clearing a report tab, copy it to a Backup_<timestamp> tab and keep this many backups per tab; 0 disables")
+	fs.BoolVar(&o.DiffWrites, "diff-writes", o.DiffWrites, "Only write sheet cells whose values changed instead of clearing and rewriting each range")
+	fs.StringVar(&o.LogRange, "range-log", o.LogRange, "Spreadsheet range of an append-only per-run history log (e.g. History_Log!A1); empty disables it")
+	fs.StringVar(&o.AuditRange, "range-audit", o.AuditRange, "Spreadsheet range of an append-only audit log with one row per run: time, trigger, outcome, rows written and errors (e.g. Audit!A1); empty disables it")
+	fs.StringVar(&o.SummaryRange, "range-summary", o.SummaryRange, "Spreadsheet range for a one-page summary with faction aggregates and formulas into the report tabs (e.g. Summary!A1); empty disables it")
+	fs.StringVar(&o.ChartsRange, "range-charts", o.ChartsRange, "Spreadsheet range for a crimes-per-week table plus CPR histogram and crimes-per-week charts (e.g. Charts!A1); empty disables it")
+	fs.StringVar(&o.RawRange, "range-raw", o.RawRange, "Spreadsheet range for a normalized raw-data table with one row per member slot of every completed crime (e.g. Raw!A1); empty disables it")
+	fs.BoolVar(&o.RawOnly, "raw-only", o.RawOnly, "With --range-raw, write only the raw-data table and skip the report tabs")
+	fs.StringVar(&o.PlannerRange, "range-planner", o.PlannerRange, "Spreadsheet range for a planner listing open OC slots with dropdowns of eligible members (e.g. Planner!A1); empty disables it")
+	fs.StringVar(&o.AboutRange, "range-about", o.AboutRange, "Spreadsheet range for a block describing the last run (e.g. About!A1); empty disables it")
+	fs.BoolVar(&verifyWrites, "verify-writes", verifyWrites, "Read each sheet range back after writing and warn if its rows or contents differ from what was sent")
+	fs.IntVar(&verifyRetries, "verify-retries", verifyRetries, "With --verify-writes, rewrite a range that did not read back correctly up to this many times")
+}
+
+// dryRunFlag lets a run fetch and compute everything but write nothing.
+func (o *options) dryRunFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&o.DryRun, "dry-run", o.DryRun, "Fetch and compute everything but skip every write (Google Sheets, Discord, notifications, files), printing what would have happened instead")
+}
+
+// scheduleFlags control repeated runs.
+func (o *options) scheduleFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.Interval, "interval", o.Interval, "Repeat execution at this interval (e.g. 5m). 0 runs once")
+	fs.StringVar(&o.Schedule, "schedule", o.Schedule, "Instead of --interval, run at the times of this cron expression, e.g. \"0 18 * * *\" (in --timezone, or prefix TZ=<zone>)")
+	fs.StringVar(&o.DailyAt, "daily-at", o.DailyAt, "Instead of --interval, run once a day at this time, e.g. 18:00 (in --timezone) or \"18:00 TCT\"")
+	fs.DurationVar(&o.Watch, "watch", o.Watch, "Instead of --interval, check this often (e.g. 1m) for newly completed crimes or member changes and only run when there are some")
+	fs.StringVar(&o.Jitter, "jitter", o.Jitter, "Move each repeated run by a random amount up to this much either way, as a duration (30s) or a percentage of the time between runs (10%), so factions on the same schedule don't all call Torn at once")
+	fs.IntVar(&o.AlertAfter, "alert-after", o.AlertAfter, "Push a notification once this many runs in a row have failed, and another when they recover")
+	fs.DurationVar(&o.ExpiryAlert, "expiry-alert", o.ExpiryAlert, "Push a notification when an active crime still has open slots this long (e.g. 12h) before it expires, with the best candidates for them; 0 disables it")
+	fs.DurationVar(&o.MaxBackoff, "max-backoff", o.MaxBackoff, "After runs fail in a row, double the wait before the next for each failure, up to this long; 0 disables backing off")
+	fs.StringVar(&o.PIDFile, "pid-file", o.PIDFile, "With repeated runs or --listen, write the process ID to this file and refuse to start while another instance holds it (default: one per spreadsheet in the temp dir for --output sheets; off disables it)")
+	fs.StringVar(&o.AuditFile, "audit-file", o.AuditFile, "Append each run's outcome (time, trigger, status, rows written per destination, errors) to this file as a JSON line; empty disables it")
+	fs.StringVar(&o.StaticDir, "static-dir", o.StaticDir, "Write the dashboard to this directory after every run as a static site (index.html with its script, stylesheet and data.json) for GitHub Pages or any static host; empty disables it")
+	fs.StringVar(&o.StateFile, "state-file", o.StateFile, "Keep the progress of each run (crimes fetched so far, outputs sent) in this file until it finishes, so a run cut short by a crash is resumed by the next start; empty disables it")
+	fs.StringVar(&o.LockFile, "lock-file", o.LockFile, "Hold a lock on this file during each run so overlapping instances (e.g. from cron) don't race on the spreadsheet; empty disables it")
+	fs.DurationVar(&o.LockWait, "lock-wait", o.LockWait, "With --lock-file, wait up to this long for another instance to finish; 0 skips the run straight away")
+}
+
+// repeating reports whether runs repeat rather than run once.
+func (o *options) repeating() bool {
+	return o.Interval > 0 || o.Schedule != "" || o.DailyAt != "" || o.Watch > 0
+}
+
+// every is the time between runs, or between checks for changes with
+// --watch; 0 with a schedule.
+func (o *options) every() time.Duration {
+	if o.Watch > 0 {
+		return o.Watch
+	}
+	return o.Interval
+}
+
+// healthMaxAge is the --unhealthy-after age, defaulting to three intervals.
+func (o *options) healthMaxAge() time.Duration {
+	if o.UnhealthyAfter > 0 || o.Schedule != "" || o.DailyAt != "" {
+		return o.UnhealthyAfter
+	}
+	return 3 * o.every()
+}
+
+// serverFlags control the HTTP endpoints.
+func (o *options) serverFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Listen, "listen", o.Listen, "Serve HTTP endpoints (e.g. Grafana datasource) on this address, e.g. :8080")
+	fs.StringVar(&o.GRPCListen, "grpc-listen", o.GRPCListen, "Also serve the gRPC API (proto/tornoc/v1/tornoc.proto) on this address, e.g. :9090; needs --listen")
+	fs.StringVar(&o.Factions, "factions", o.Factions, "Also fetch these config profiles (comma-separated) after each run and serve each under /f/<profile>/; needs --listen")
+	fs.StringVar(&o.BasePath, "base-path", o.BasePath, "Serve the HTTP endpoints under this path, e.g. /oc, for a reverse proxy that doesn't strip it")
+	fs.StringVar(&o.CORSOrigins, "cors-origins", o.CORSOrigins, "Let pages on these origins (comma-separated, e.g. https://tools.example.com, or *) call the HTTP endpoints")
+	fs.BoolVar(&o.TrustProxy, "trust-proxy", o.TrustProxy, "Take the client address, scheme, host and path prefix from a reverse proxy's X-Forwarded-* headers")
+	fs.DurationVar(&o.UnhealthyAfter, "unhealthy-after", o.UnhealthyAfter, "Fail /healthz when the last successful run is older than this; 0 is three times --interval (or --watch), and off with --schedule or --daily-at")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/lock"
+)
+
+// pidFilePath is the --pid-file of a repeating or serving process: the path
+// given, or by default one in the temp dir named after the spreadsheets
+// written, so two daemons writing the same spreadsheet collide. "" means
+// none.
+func pidFilePath(o *options) string {
+	switch {
+	case o.PIDFile == "off":
+		return ""
+	case o.PIDFile != "":
+		return o.PIDFile
+	case o.Output != "sheets" || o.DryRun:
+		return ""
+	}
+	var ids []string
+	for _, env := range []string{"SPREADSHEET_ID", "SPREADSHEET_ID_NOC", "SPREADSHEET_ID_ALL"} {
+		ids = append(ids, os.Getenv(env))
+	}
+	key := strings.Join(ids, ",")
+	if key == ",," {
+		// a spreadsheet created at startup is this process's own
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(os.TempDir(), "torn-oc-history-"+hex.EncodeToString(sum[:6])+".pid")
+}
+
+// acquirePIDFile locks path and writes the process ID to it for as long as
+// the process runs, failing if another instance already holds it.
+func acquirePIDFile(path string) (*lock.Lock, error) {
+	l, err := lock.Acquire(path, 0)
+	if errors.Is(err, lock.ErrLocked) {
+		pid, _ := os.ReadFile(path)
+		return nil, fmt.Errorf("another instance (PID %s) holds the PID file %s", strings.TrimSpace(string(pid)), path)
+	}
+	return l, err
+}
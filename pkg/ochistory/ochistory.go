@@ -0,0 +1,49 @@
+// Package ochistory is the pipeline behind torn-oc-history for other Go
+// programs: fetch a faction's members and crimes from the Torn API, keep each
+// member's latest checkpoint pass rate per difficulty and position, and render
+// the report as text, a line per member, or forum BBCode.
+//
+//	c := ochistory.NewClient(key)
+//	members, _ := c.FetchMembers()
+//	crimes, _ := c.FetchAllCrimes()
+//	report := ochistory.BuildReport(members, ochistory.BuildStats(crimes))
+//	out, _ := ochistory.Render(report, ochistory.FormatText)
+//
+// Client, Member, Crime and the other Torn types are those of the binary's
+// own Torn client under internal/, so like them they may change between
+// versions, fields included; pin the module version you build against.
+package ochistory
+
+import "github.com/mnuck/torn-oc-history/internal/torn"
+
+// The Torn API client and the data it returns.
+type (
+	Client   = torn.Client
+	Progress = torn.Progress
+	Member   = torn.Member
+	Crime    = torn.Crime
+	Slot     = torn.Slot
+	SlotUser = torn.SlotUser
+	Rewards  = torn.Rewards
+	// APIError is an error object returned by the Torn API.
+	APIError = torn.Error
+	// ItemRequirement is an item a slot needs for its crime to be executed.
+	ItemRequirement = torn.ItemRequirement
+)
+
+// NewClient returns a client of the Torn v2 API using a key with faction
+// access.
+func NewClient(key string) *Client {
+	return torn.NewClient(key)
+}
+
+// IsAuthError reports whether Torn rejected the API key or it lacks access.
+func IsAuthError(err error) bool {
+	return torn.IsAuthError(err)
+}
+
+// IsRateLimited reports whether Torn refused a request for exceeding the
+// key's request limit.
+func IsRateLimited(err error) bool {
+	return torn.IsRateLimited(err)
+}
@@ -0,0 +1,174 @@
+package ochistory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Format is a way Render lays out a report.
+type Format string
+
+const (
+	// FormatText lists every member's pass rates by difficulty and position.
+	FormatText Format = "text"
+	// FormatCompact has one line per member: best and lowest position and
+	// days since their last OC.
+	FormatCompact Format = "compact"
+	// FormatBBCode is a table per member for a Torn faction forum thread.
+	FormatBBCode Format = "bbcode"
+)
+
+// Options are how timestamps and pass rates are shown.
+type Options struct {
+	// Location and DateFormat render timestamps.
+	Location   *time.Location
+	DateFormat string
+	// Rates from CPRLow are coloured amber in BBCode, from CPRHigh green,
+	// and red below.
+	CPRLow, CPRHigh int
+}
+
+// DefaultOptions are the options of Render: local time, RFC 3339, and the
+// bands 50 and 70.
+var DefaultOptions = Options{Location: time.Local, DateFormat: time.RFC3339, CPRLow: 50, CPRHigh: 70}
+
+// Render lays out the report in format with DefaultOptions.
+func Render(report Report, format Format) (string, error) {
+	return DefaultOptions.Render(report, format)
+}
+
+// Render lays out the report in format.
+func (o Options) Render(report Report, format Format) (string, error) {
+	switch format {
+	case FormatText, "":
+		return strings.Join(o.TextLines(report), "\n") + "\n", nil
+	case FormatCompact:
+		return strings.Join(o.CompactLines(report), "\n") + "\n", nil
+	case FormatBBCode:
+		return o.BBCode(report), nil
+	}
+	return "", fmt.Errorf("unknown report format %q", format)
+}
+
+// FormatTime renders t in the options' zone and format.
+func (o Options) FormatTime(t time.Time) string {
+	if o.Location != nil {
+		t = t.In(o.Location)
+	}
+	return t.Format(o.DateFormat)
+}
+
+// FormatUnix renders a Unix timestamp in the options' zone and format.
+func (o Options) FormatUnix(unix int64) string {
+	return o.FormatTime(time.Unix(unix, 0))
+}
+
+// TextLines are the lines of FormatText.
+func (o Options) TextLines(report Report) []string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("Report generated at: %s", o.FormatTime(report.GeneratedAt)))
+
+	for _, mr := range report.Members {
+		m := mr.Member
+		// blank line before each member block
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("Member: %s (%d) - Last seen: %s (%s)", m.Name, m.ID, m.LastAction.Status, m.LastAction.Relative))
+
+		if len(mr.Difficulties) == 0 {
+			lines = append(lines, "  No historical OC participation recorded.")
+			continue
+		}
+
+		for _, dr := range mr.Difficulties {
+			lines = append(lines, fmt.Sprintf("  Difficulty %d:", dr.Difficulty))
+			for _, pr := range dr.Positions {
+				if pr.Rate == 0 {
+					lines = append(lines, fmt.Sprintf("    %-15s %s", pr.Position, "-"))
+				} else {
+					lines = append(lines, fmt.Sprintf("    %-15s %3d%% (executed_at %s)", pr.Position, pr.Rate, o.FormatUnix(pr.ExecutedAt)))
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// CompactLines are the lines of FormatCompact, for a quick daily glance.
+func (o Options) CompactLines(report Report) []string {
+	width := 0
+	for _, mr := range report.Members {
+		width = max(width, len(fmt.Sprintf("%s (%d)", mr.Member.Name, mr.Member.ID)))
+	}
+	lines := []string{fmt.Sprintf("Report generated at: %s", o.FormatTime(report.GeneratedAt))}
+	for _, mr := range report.Members {
+		m := mr.Member
+		label := fmt.Sprintf("%-*s", width, fmt.Sprintf("%s (%d)", m.Name, m.ID))
+		s := Summarize(mr)
+		if s.Positions == 0 {
+			lines = append(lines, label+"  no OC history")
+			continue
+		}
+		line := fmt.Sprintf("%s  best %3d%% %s D%d, lowest %3d%% %s D%d", label,
+			s.Best.Rate, s.Best.Position, s.BestDiff, s.Worst.Rate, s.Worst.Position, s.WorstDiff)
+		if s.LastExecutedAt > 0 {
+			days := int(report.GeneratedAt.Sub(time.Unix(s.LastExecutedAt, 0)).Hours() / 24)
+			line += fmt.Sprintf(", last OC %dd ago", days)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// BBCode is the report in FormatBBCode, ready to paste into a faction forum
+// thread.
+func (o Options) BBCode(report Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[b]OC History[/b] - generated %s\n", o.FormatTime(report.GeneratedAt))
+
+	for _, mr := range report.Members {
+		m := mr.Member
+		fmt.Fprintf(&b, "\n[b][url=https://www.torn.com/profiles.php?XID=%d]%s[/url][/b] [%d] - Last seen: %s (%s)\n",
+			m.ID, m.Name, m.ID, m.LastAction.Status, m.LastAction.Relative)
+
+		if len(mr.Difficulties) == 0 {
+			b.WriteString("[i]No historical OC participation recorded.[/i]\n")
+			continue
+		}
+
+		b.WriteString("[table]\n[tr][td][b]Difficulty[/b][/td][td][b]Position[/b][/td][td][b]CPR[/b][/td][td][b]Executed[/b][/td][/tr]\n")
+		for _, dr := range mr.Difficulties {
+			for _, pr := range dr.Positions {
+				rate, executed := "-", "-"
+				if pr.Rate != 0 {
+					rate = fmt.Sprintf("[color=%s]%d%%[/color]", o.CPRColor(pr.Rate), pr.Rate)
+					executed = o.FormatUnix(pr.ExecutedAt)
+				}
+				fmt.Fprintf(&b, "[tr][td]%d[/td][td]%s[/td][td]%s[/td][td]%s[/td][/tr]\n", dr.Difficulty, pr.Position, rate, executed)
+			}
+		}
+		b.WriteString("[/table]\n")
+	}
+	return b.String()
+}
+
+// CPRColor is the hex colour of a pass rate's band.
+func (o Options) CPRColor(rate int) string {
+	switch {
+	case rate >= o.CPRHigh:
+		return "#2e7d32"
+	case rate >= o.CPRLow:
+		return "#f9a825"
+	default:
+		return "#c62828"
+	}
+}
+
+// Provenance describes which crime a pass rate came from, or is empty if it
+// came from none.
+func (o Options) Provenance(st RateInfo) string {
+	if st.CrimeID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Crime #%d %s\nExecuted %s", st.CrimeID, st.CrimeName, o.FormatUnix(st.ExecutedAt))
+}
@@ -0,0 +1,91 @@
+package ochistory
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report is the formatter-independent view of a report: members sorted by
+// name, each with their difficulties and positions in display order.
+type Report struct {
+	GeneratedAt time.Time
+	Members     []MemberReport
+}
+
+type MemberReport struct {
+	Member       Member
+	Difficulties []DifficultyReport // empty when the member has no OC history
+}
+
+type DifficultyReport struct {
+	Difficulty int
+	Positions  []PositionReport
+}
+
+type PositionReport struct {
+	Position string
+	RateInfo
+}
+
+// BuildReport reports the members' pass rates in stats, generated now.
+func BuildReport(members []Member, stats MemberStats) Report {
+	report := Report{GeneratedAt: time.Now()}
+
+	for _, m := range members {
+		mr := MemberReport{Member: m}
+		memberStats := stats[m.ID]
+
+		// sort difficulties
+		diffs := make([]int, 0, len(memberStats))
+		for d := range memberStats {
+			diffs = append(diffs, d)
+		}
+		sort.Ints(diffs)
+		for _, d := range diffs {
+			dr := DifficultyReport{Difficulty: d}
+			for p, st := range memberStats[d] {
+				dr.Positions = append(dr.Positions, PositionReport{Position: p, RateInfo: st})
+			}
+			// sort positions alphabetically
+			sort.Slice(dr.Positions, func(i, j int) bool { return dr.Positions[i].Position < dr.Positions[j].Position })
+			mr.Difficulties = append(mr.Difficulties, dr)
+		}
+		report.Members = append(report.Members, mr)
+	}
+	sort.Slice(report.Members, func(i, j int) bool {
+		return strings.ToLower(report.Members[i].Member.Name) < strings.ToLower(report.Members[j].Member.Name)
+	})
+	return report
+}
+
+// MemberSummary is a member's best and worst positions.
+type MemberSummary struct {
+	Best, Worst         PositionReport
+	BestDiff, WorstDiff int
+	Positions           int   // positions with a recorded pass rate
+	LastExecutedAt      int64 // most recent OC the member took part in, 0 if none
+}
+
+// Summarize condenses a member's report into best/worst positions.
+func Summarize(mr MemberReport) MemberSummary {
+	var s MemberSummary
+	for _, dr := range mr.Difficulties {
+		for _, pr := range dr.Positions {
+			if pr.ExecutedAt > s.LastExecutedAt {
+				s.LastExecutedAt = pr.ExecutedAt
+			}
+			if pr.Rate == 0 {
+				continue
+			}
+			if s.Positions == 0 || pr.Rate > s.Best.Rate {
+				s.Best, s.BestDiff = pr, dr.Difficulty
+			}
+			if s.Positions == 0 || pr.Rate < s.Worst.Rate {
+				s.Worst, s.WorstDiff = pr, dr.Difficulty
+			}
+			s.Positions++
+		}
+	}
+	return s
+}
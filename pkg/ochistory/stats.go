@@ -0,0 +1,74 @@
+package ochistory
+
+import "strings"
+
+// RateInfo is the most recent checkpoint pass rate of a member at a
+// difficulty and position, and the crime it came from.
+type RateInfo struct {
+	Rate       int
+	ExecutedAt int64
+	CrimeID    int
+	CrimeName  string
+	// the observation before the most recent one, if any
+	PrevRate       int
+	PrevExecutedAt int64
+	// how many crimes the member was executed in at the difficulty and
+	// position, and how many of those succeeded; expired ones don't count
+	Executed, Successes int
+}
+
+// SuccessRate is the percentage of the crimes executed that succeeded, or
+// -1 without any.
+func (st RateInfo) SuccessRate() int {
+	if st.Executed == 0 {
+		return -1
+	}
+	return st.Successes * 100 / st.Executed
+}
+
+// MemberStats is keyed by member ID, then difficulty, then position.
+type MemberStats map[int]map[int]map[string]RateInfo
+
+// BuildStats keeps, for every member/difficulty/position, the pass rate from
+// the most recently executed crime.
+func BuildStats(crimes []Crime) MemberStats {
+	stats := make(MemberStats)
+	for _, crime := range crimes {
+		stats.Add(crime)
+	}
+	return stats
+}
+
+// Add folds a crime into the stats, so they can be built as pages of crimes
+// arrive without keeping the crimes.
+func (stats MemberStats) Add(crime Crime) {
+	for _, slot := range crime.Slots {
+		uid := slot.User.ID
+		if _, ok := stats[uid]; !ok {
+			stats[uid] = make(map[int]map[string]RateInfo)
+		}
+		if _, ok := stats[uid][crime.Difficulty]; !ok {
+			stats[uid][crime.Difficulty] = make(map[string]RateInfo)
+		}
+		if _, ok := stats[uid][crime.Difficulty][slot.Position]; !ok {
+			stats[uid][crime.Difficulty][slot.Position] = RateInfo{}
+		}
+		st := stats[uid][crime.Difficulty][slot.Position]
+		switch {
+		case strings.EqualFold(crime.Status, "Successful"):
+			st.Executed++
+			st.Successes++
+		case strings.EqualFold(crime.Status, "Failure"):
+			st.Executed++
+		}
+		if crime.ExecutedAt > st.ExecutedAt {
+			st.PrevRate, st.PrevExecutedAt = st.Rate, st.ExecutedAt
+			st.Rate = slot.CheckpointPassRate
+			st.ExecutedAt = crime.ExecutedAt
+			st.CrimeID, st.CrimeName = crime.ID, crime.Name
+		} else if crime.ExecutedAt > st.PrevExecutedAt {
+			st.PrevRate, st.PrevExecutedAt = slot.CheckpointPassRate, crime.ExecutedAt
+		}
+		stats[uid][crime.Difficulty][slot.Position] = st
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// plannerHeader names the columns of the Planner tab.
+var plannerHeader = []interface{}{"Crime", "Crime ID", "Difficulty", "Position", "Status", "Candidate", "CPR", "Candidates", "Item", "Item holders"}
+
+// Indexes of the Crime ID, Position and Candidate columns in plannerHeader.
+const (
+	plannerCrimeColumn     = 1
+	plannerPositionColumn  = 3
+	plannerCandidateColumn = 5
+)
+
+// itemsFile is set by the --items flag: the file of the members who have
+// the items slots require.
+var itemsFile string
+
+const itemsUsage = "File of the members who have the items OC slots require, one item per line: \"<item ID> [name]: <member IDs>\"; the planner flags the slots needing one"
+
+// itemHolders are the items of the --items file by ID.
+type itemHolders map[int]heldItem
+
+type heldItem struct {
+	Name    string
+	Members []int
+}
+
+// loadItemHolders reads the --items file, whose lines each name an item ID,
+// optionally its name, and after a colon the IDs of the members who have one
+// to hand, separated by commas or spaces: "1203 Crowbar: 123, 456". Text
+// after # is a comment. Without a file no member is known to have any item.
+func loadItemHolders(path string) (itemHolders, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	items := make(itemHolders)
+	for n, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		item, holders, ok := strings.Cut(line, ":")
+		fields := strings.Fields(item)
+		if !ok || len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: want \"<item ID> [name]: <member IDs>\"", n+1)
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("line %d: invalid item ID %q", n+1, fields[0])
+		}
+		held := items[id]
+		if name := strings.Join(fields[1:], " "); name != "" {
+			held.Name = name
+		}
+		for _, f := range strings.FieldsFunc(holders, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\r' }) {
+			member, err := strconv.Atoi(f)
+			if err != nil || member <= 0 {
+				return nil, fmt.Errorf("line %d: invalid member ID %q", n+1, f)
+			}
+			if !slices.Contains(held.Members, member) {
+				held.Members = append(held.Members, member)
+			}
+		}
+		items[id] = held
+	}
+	return items, nil
+}
+
+// label is how an item appears in the planner: its name from the --items
+// file and ID, or just the ID.
+func (items itemHolders) label(id int) string {
+	if name := items[id].Name; name != "" {
+		return fmt.Sprintf("%s [%d]", name, id)
+	}
+	return fmt.Sprintf("item %d", id)
+}
+
+func (items itemHolders) has(member, id int) bool {
+	return slices.Contains(items[id].Members, member)
+}
+
+// openSlot is an unfilled slot of a recruiting or planning crime and the
+// members who could fill it, best first.
+type openSlot struct {
+	Crime      torn.Crime
+	Position   string
+	Candidates []candidate
+	// Item is what the slot requires, if anything, and ItemLabel its name
+	Item      *torn.ItemRequirement
+	ItemLabel string
+}
+
+type candidate struct {
+	Member torn.Member
+	Rate   int
+	// how many of the member's crimes at the difficulty and position were
+	// executed, and how many of those succeeded
+	Executed, Successes int
+	// HasItem is whether the --items file lists the member as having the
+	// item the slot requires
+	HasItem bool
+}
+
+// label is how a candidate appears in the Planner dropdowns.
+func (c candidate) label() string {
+	return fmt.Sprintf("%s [%d]", c.Member.Name, c.Member.ID)
+}
+
+func (c candidate) successRate() int {
+	return RateInfo{Executed: c.Executed, Successes: c.Successes}.SuccessRate()
+}
+
+// summary is a candidate with their CPR and, if they have been in crimes at
+// the slot's difficulty and position, how many of those succeeded.
+func (c candidate) summary() string {
+	if c.Executed == 0 {
+		return fmt.Sprintf("%s %d%%", c.label(), c.Rate)
+	}
+	return fmt.Sprintf("%s %d%% (%d/%d succeeded)", c.label(), c.Rate, c.Successes, c.Executed)
+}
+
+// holders lists the candidates who have the slot's item.
+func (s openSlot) holders() []string {
+	var labels []string
+	for _, c := range s.Candidates {
+		if c.HasItem {
+			labels = append(labels, c.label())
+		}
+	}
+	return labels
+}
+
+// buildOpenSlots lists the open slots of the active crimes. A member is a
+// candidate for a slot when they are not in an OC and their last CPR at that
+// difficulty and position is at least --cpr-low. Candidates are ranked by
+// that CPR, then by how often their crimes there succeeded. items tells the
+// candidates who have the item a slot requires.
+func buildOpenSlots(active []torn.Crime, members []torn.Member, stats MemberStats, items itemHolders) []openSlot {
+	var slots []openSlot
+	for _, crime := range active {
+		for _, slot := range crime.Slots {
+			if slot.User.ID != 0 {
+				continue
+			}
+			var cands []candidate
+			for _, m := range members {
+				if m.IsInOC {
+					continue
+				}
+				if ri, ok := stats[m.ID][crime.Difficulty][slot.Position]; ok && ri.Rate >= cprLow {
+					c := candidate{Member: m, Rate: ri.Rate, Executed: ri.Executed, Successes: ri.Successes}
+					c.HasItem = slot.ItemRequirement != nil && items.has(m.ID, slot.ItemRequirement.ID)
+					cands = append(cands, c)
+				}
+			}
+			sort.Slice(cands, func(i, j int) bool {
+				if cands[i].Rate != cands[j].Rate {
+					return cands[i].Rate > cands[j].Rate
+				}
+				if si, sj := cands[i].successRate(), cands[j].successRate(); si != sj {
+					return si > sj
+				}
+				return strings.ToLower(cands[i].Member.Name) < strings.ToLower(cands[j].Member.Name)
+			})
+			open := openSlot{Crime: crime, Position: slot.Position, Candidates: cands, Item: slot.ItemRequirement}
+			if open.Item != nil {
+				open.ItemLabel = items.label(open.Item.ID)
+			}
+			slots = append(slots, open)
+		}
+	}
+	return slots
+}
+
+// plannerKey identifies the row of the n-th open slot with a position in a
+// crime, so choices survive the rows being rewritten.
+func plannerKey(crimeID, position string, n int) string {
+	return fmt.Sprintf("%s/%s/%d", crimeID, position, n)
+}
+
+// buildPlannerRows builds the Planner rows, keeping the candidate chosen in
+// existing (the tab as last written and edited) for slots that are still open
+// and candidates that are still eligible. It also returns each row's dropdown
+// options.
+func buildPlannerRows(slots []openSlot, existing [][]interface{}) ([][]interface{}, [][]string) {
+	chosen := make(map[string]string)
+	seen := make(map[string]int)
+	for r := 1; r < len(existing); r++ {
+		crimeID, position := cellString(existing, r, plannerCrimeColumn), cellString(existing, r, plannerPositionColumn)
+		key := plannerKey(crimeID, position, seen[crimeID+"/"+position])
+		seen[crimeID+"/"+position]++
+		chosen[key] = cellString(existing, r, plannerCandidateColumn)
+	}
+
+	rows := [][]interface{}{plannerHeader}
+	var options [][]string
+	seen = make(map[string]int)
+	for _, s := range slots {
+		crimeID := fmt.Sprint(s.Crime.ID)
+		key := plannerKey(crimeID, s.Position, seen[crimeID+"/"+s.Position])
+		seen[crimeID+"/"+s.Position]++
+
+		var labels []string
+		var pick, rate interface{} = "", ""
+		for _, c := range s.Candidates {
+			labels = append(labels, c.label())
+			if c.label() == chosen[key] {
+				pick, rate = c.label(), cprValue(c.Rate)
+			}
+		}
+		rows = append(rows, []interface{}{s.Crime.Name, s.Crime.ID, s.Crime.Difficulty, s.Position, s.Crime.Status, pick, rate, len(s.Candidates), s.ItemLabel, strings.Join(s.holders(), ", ")})
+		options = append(options, labels)
+	}
+	return rows, options
+}
+
+// writePlanner rewrites the Planner tab with one row per open slot and a
+// dropdown of candidates in each row, keeping choices already made.
+func writePlanner(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, slots []openSlot) error {
+	sheet := sheetspkg.SheetName(targetRange)
+	if sheet == "" {
+		return fmt.Errorf("range %q does not name a sheet", targetRange)
+	}
+	if err := client.EnsureSheet(ctx, spreadsheetID, sheet); err != nil {
+		return fmt.Errorf("ensure sheet: %w", err)
+	}
+	row, col := sheetspkg.StartCell(targetRange)
+	block := sheetspkg.GridA1(sheet, row, col, -1, col+int64(len(plannerHeader))-1)
+	existing, err := client.ReadFormulas(ctx, spreadsheetID, block)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	rows, options := buildPlannerRows(slots, existing)
+	if err := client.ClearRange(ctx, spreadsheetID, block); err != nil {
+		return fmt.Errorf("clear: %w", err)
+	}
+	if err := client.UpdateRange(ctx, spreadsheetID, targetRange, rows); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := client.SetDropdowns(ctx, spreadsheetID, sheet, row+1, col+plannerCandidateColumn, options); err != nil {
+		return fmt.Errorf("set dropdowns: %w", err)
+	}
+	formatTable(ctx, client, spreadsheetID, targetRange, len(plannerHeader), plannerCandidateColumn+1, plannerCandidateColumn+2)
+	return nil
+}
+
+// plannerListed is how many candidates printOpenSlots lists per slot.
+const plannerListed = 5
+
+// printOpenSlots prints each active crime with open slots: when it expires,
+// the members already in it, and its open slots with their best candidates.
+// Items slots need are flagged, on filled slots when the member lacks it and
+// on open ones with the candidates who have it.
+func printOpenSlots(w io.Writer, slots []openSlot, members []torn.Member, items itemHolders) {
+	if len(slots) == 0 {
+		fmt.Fprintln(w, "No open OC slots.")
+		return
+	}
+	names := make(map[int]string, len(members))
+	for _, m := range members {
+		names[m.ID] = m.Name
+	}
+	lastCrime := 0
+	for _, s := range slots {
+		if s.Crime.ID != lastCrime {
+			if lastCrime != 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s [%d] (D%d, %s)\n", s.Crime.Name, s.Crime.ID, s.Crime.Difficulty, s.Crime.Status)
+			if s.Crime.ExpiredAt != 0 {
+				fmt.Fprintf(w, "  Expires: %s\n", formatUnix(s.Crime.ExpiredAt))
+			}
+			var filled []string
+			for _, slot := range s.Crime.Slots {
+				if slot.User.ID == 0 {
+					continue
+				}
+				f := fmt.Sprintf("%s %s [%d] %d%%", slot.Position, names[slot.User.ID], slot.User.ID, slot.CheckpointPassRate)
+				if req := slot.ItemRequirement; req != nil && !req.IsAvailable {
+					f += " MISSING " + items.label(req.ID)
+				}
+				filled = append(filled, f)
+			}
+			if len(filled) == 0 {
+				filled = []string{"nobody yet"}
+			}
+			fmt.Fprintf(w, "  Filled: %s\n", strings.Join(filled, ", "))
+			lastCrime = s.Crime.ID
+		}
+		position := s.Position
+		if s.Item != nil {
+			needs := "NEEDS " + s.ItemLabel
+			if !s.Item.IsReusable {
+				needs += ", used up"
+			}
+			if held := s.holders(); len(held) > 0 {
+				needs += "; held by " + strings.Join(held, ", ")
+			} else {
+				needs += "; no candidate has one"
+			}
+			position += " (" + needs + ")"
+		}
+		if len(s.Candidates) == 0 {
+			fmt.Fprintf(w, "  Open %s: no eligible members\n", position)
+			continue
+		}
+		var listed []string
+		for _, c := range s.Candidates[:min(len(s.Candidates), plannerListed)] {
+			listed = append(listed, c.summary())
+		}
+		if n := len(s.Candidates) - plannerListed; n > 0 {
+			listed = append(listed, fmt.Sprintf("+%d more", n))
+		}
+		fmt.Fprintf(w, "  Open %s: %s\n", position, strings.Join(listed, ", "))
+	}
+}
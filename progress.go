@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// progressMeter shows how far a crime fetch has got on a single, rewritten
+// line, so a multi-minute pagination doesn't look hung. Categories fetched
+// at once share the line.
+type progressMeter struct {
+	w     io.Writer
+	limit int // most crimes a category can yield, 0 if unknown
+
+	mu    sync.Mutex
+	start map[string]time.Time
+}
+
+// newProgressMeter returns a meter on stderr, or nil when stderr is not a
+// terminal or --quiet is set.
+func newProgressMeter() *progressMeter {
+	if quiet {
+		return nil
+	}
+	fi, err := os.Stderr.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	// with --max-pages or --max-crimes the end is known, so there is an ETA
+	limit := maxCrimes
+	if maxPages > 0 && (limit == 0 || maxPages*100 < limit) {
+		limit = maxPages * 100
+	}
+	return &progressMeter{w: os.Stderr, limit: limit, start: make(map[string]time.Time)}
+}
+
+func (m *progressMeter) update(p torn.Progress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p.Done {
+		// clear the line for whatever is logged next
+		fmt.Fprint(m.w, "\r\x1b[K")
+		return
+	}
+	if _, ok := m.start[p.Category]; !ok || p.Pages == 1 {
+		m.start[p.Category] = time.Now()
+	}
+	elapsed := time.Since(m.start[p.Category])
+	line := fmt.Sprintf("Fetching %s crimes: %d pages, %d crimes, %s", p.Category, p.Pages, p.Crimes, elapsed.Round(time.Second))
+	if m.limit > 0 && p.Crimes > 0 && p.Crimes < m.limit {
+		eta := time.Duration(float64(elapsed) / float64(p.Crimes) * float64(m.limit-p.Crimes))
+		line += fmt.Sprintf(", about %s left", eta.Round(time.Second))
+	}
+	fmt.Fprint(m.w, "\r\x1b[K"+line)
+}
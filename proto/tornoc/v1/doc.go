@@ -0,0 +1,5 @@
+// Package tornocv1 is the gRPC API of server mode, generated from
+// tornoc.proto with protoc-gen-go and protoc-gen-go-grpc.
+package tornocv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative tornoc/v1/tornoc.proto
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// rawHeader names the columns of the raw-data tab.
+var rawHeader = []interface{}{"Executed At", "Crime ID", "Crime", "Difficulty", "Member ID", "Member", "In OC", "Position", "CPR", "Outcome"}
+
+// Indexes of the Executed At and CPR columns in rawHeader.
+const (
+	rawExecutedColumn = 0
+	rawCPRColumn      = 8
+)
+
+// observation is one member's CPR in one slot of a completed crime.
+type observation struct {
+	Crime     torn.Crime
+	Slot      torn.Slot
+	Member    torn.Member // zero apart from ID for members who have left
+	InFaction bool
+}
+
+// observations flattens every completed crime into one observation per filled
+// slot, newest first.
+func observations(crimes []torn.Crime, members []torn.Member) []observation {
+	byID := make(map[int]torn.Member, len(members))
+	for _, m := range members {
+		byID[m.ID] = m
+	}
+	sorted := append([]torn.Crime(nil), crimes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ExecutedAt != sorted[j].ExecutedAt {
+			return sorted[i].ExecutedAt > sorted[j].ExecutedAt
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+
+	var obs []observation
+	for _, c := range sorted {
+		for _, slot := range c.Slots {
+			if slot.User.ID == 0 {
+				continue
+			}
+			m, ok := byID[slot.User.ID]
+			if !ok {
+				m = torn.Member{ID: slot.User.ID}
+			}
+			obs = append(obs, observation{Crime: c, Slot: slot, Member: m, InFaction: ok})
+		}
+	}
+	return obs
+}
+
+// buildRawRows builds the raw-data tab from observations, for pivot tables
+// and user-built views. Members who have left the faction keep their ID but
+// get an empty name.
+func buildRawRows(crimes []torn.Crime, members []torn.Member) [][]interface{} {
+	rows := [][]interface{}{rawHeader}
+	for _, ob := range observations(crimes, members) {
+		var inOC interface{} = ""
+		if ob.InFaction {
+			inOC = ob.Member.IsInOC
+		}
+		c := ob.Crime
+		rows = append(rows, []interface{}{
+			serialTime(c.ExecutedAt), c.ID, c.Name, c.Difficulty,
+			ob.Member.ID, ob.Member.Name, inOC, ob.Slot.Position, cprValue(ob.Slot.CheckpointPassRate), ob.Slot.User.Outcome,
+		})
+	}
+	return rows
+}
+
+// formatRawSheet applies number formats to the raw-data tab and styles its
+// header, leaving everything else to the user.
+func formatRawSheet(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string) {
+	name := sheetspkg.SheetName(targetRange)
+	if name == "" {
+		return
+	}
+	row, col := sheetspkg.StartCell(targetRange)
+	if err := client.StyleHeaderRow(ctx, spreadsheetID, name, row, col, col+int64(len(rawHeader)), sheetHeaderBackground); err != nil {
+		slog.Warn("style header row", "range", targetRange, "error", err)
+	}
+	if err := client.SetNumberFormat(ctx, spreadsheetID, name, row+1, col+rawExecutedColumn, col+rawExecutedColumn+1, "DATE_TIME", "yyyy-mm-dd hh:mm"); err != nil {
+		slog.Warn("format executed_at column", "range", targetRange, "error", err)
+	}
+	if err := client.SetNumberFormat(ctx, spreadsheetID, name, row+1, col+rawCPRColumn, col+rawCPRColumn+1, "PERCENT", "0%"); err != nil {
+		slog.Warn("format CPR column", "range", targetRange, "error", err)
+	}
+}
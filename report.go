@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+	"github.com/mnuck/torn-oc-history/pkg/ochistory"
+)
+
+// The stats and reports are those of the exported pipeline.
+type (
+	RateInfo         = ochistory.RateInfo
+	MemberStats      = ochistory.MemberStats
+	Report           = ochistory.Report
+	MemberReport     = ochistory.MemberReport
+	DifficultyReport = ochistory.DifficultyReport
+	PositionReport   = ochistory.PositionReport
+	MemberSummary    = ochistory.MemberSummary
+)
+
+func buildStats(crimes []torn.Crime) MemberStats {
+	return ochistory.BuildStats(crimes)
+}
+
+func buildReport(selected map[int]torn.Member, stats MemberStats) Report {
+	members := make([]torn.Member, 0, len(selected))
+	for _, m := range selected {
+		members = append(members, m)
+	}
+	return ochistory.BuildReport(members, stats)
+}
+
+// renderOptions are the --timezone, --date-format and CPR bands for the
+// renderers of the pipeline.
+func renderOptions() ochistory.Options {
+	return ochistory.Options{Location: timeZone, DateFormat: dateFormat, CPRLow: cprLow, CPRHigh: cprHigh}
+}
+
+// generateReportLines assembles the human-readable report lines that are printed to stdout.
+func generateReportLines(report Report) []string {
+	return renderOptions().TextLines(report)
+}
+
+// generateCompactLines renders the report with one line per member.
+func generateCompactLines(report Report) []string {
+	return renderOptions().CompactLines(report)
+}
+
+func summarize(mr MemberReport) MemberSummary {
+	return ochistory.Summarize(mr)
+}
+
+// provenance describes which crime a pass rate came from.
+func provenance(st RateInfo) string {
+	return renderOptions().Provenance(st)
+}
+
+// reportFilter narrows a report down to some members, positions and
+// difficulties. Empty lists match everything.
+type reportFilter struct {
+	members      []string // IDs or case-insensitive name fragments
+	positions    map[string]bool
+	difficulties map[int]bool
+	// ids, from --members, replace the OC-status selection of the members
+	// reported rather than narrowing it
+	ids []int
+}
+
+// parseMemberIDs parses --members: comma-separated member IDs, or the path
+// of a file of IDs separated by commas, spaces or newlines, with # comments.
+func parseMemberIDs(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	items := splitList(s)
+	if strings.Trim(s, "0123456789, ") != "" {
+		data, err := os.ReadFile(s)
+		if err != nil {
+			return nil, err
+		}
+		items = nil
+		for _, line := range strings.Split(string(data), "\n") {
+			line, _, _ = strings.Cut(line, "#")
+			items = append(items, strings.FieldsFunc(line, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })...)
+		}
+	}
+	var ids []int
+	for _, item := range items {
+		id, err := strconv.Atoi(item)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid member ID %q", item)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no member IDs in %q", s)
+	}
+	return ids, nil
+}
+
+// parseReportFilter parses the comma-separated --filter-* flag values.
+func parseReportFilter(members, positions, difficulties string) (reportFilter, error) {
+	var f reportFilter
+	for _, m := range splitList(members) {
+		f.members = append(f.members, strings.ToLower(m))
+	}
+	for _, p := range splitList(positions) {
+		if f.positions == nil {
+			f.positions = make(map[string]bool)
+		}
+		f.positions[strings.ToLower(p)] = true
+	}
+	for _, d := range splitList(difficulties) {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return f, fmt.Errorf("invalid difficulty %q", d)
+		}
+		if f.difficulties == nil {
+			f.difficulties = make(map[int]bool)
+		}
+		f.difficulties[n] = true
+	}
+	return f, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (f reportFilter) matchMember(m torn.Member) bool {
+	if len(f.members) == 0 {
+		return true
+	}
+	for _, q := range f.members {
+		if q == strconv.Itoa(m.ID) || strings.Contains(strings.ToLower(m.Name), q) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the report with only the matching members, difficulties and
+// positions. When positions or difficulties are filtered, members left with
+// none of them are dropped too.
+func (f reportFilter) apply(r Report) Report {
+	narrowed := f.positions != nil || f.difficulties != nil
+	out := Report{GeneratedAt: r.GeneratedAt}
+	for _, mr := range r.Members {
+		if !f.matchMember(mr.Member) {
+			continue
+		}
+		if !narrowed {
+			out.Members = append(out.Members, mr)
+			continue
+		}
+		kept := MemberReport{Member: mr.Member}
+		for _, dr := range mr.Difficulties {
+			if f.difficulties != nil && !f.difficulties[dr.Difficulty] {
+				continue
+			}
+			d := DifficultyReport{Difficulty: dr.Difficulty}
+			for _, pr := range dr.Positions {
+				if f.positions == nil || f.positions[strings.ToLower(pr.Position)] {
+					d.Positions = append(d.Positions, pr)
+				}
+			}
+			if len(d.Positions) > 0 {
+				kept.Difficulties = append(kept.Difficulties, d)
+			}
+		}
+		if len(kept.Difficulties) > 0 {
+			out.Members = append(out.Members, kept)
+		}
+	}
+	return out
+}
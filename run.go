@@ -0,0 +1,491 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/metrics"
+	"github.com/mnuck/torn-oc-history/internal/schedule"
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// cronSchedule is the parsed --schedule, nil without one. Set by
+// validateOptions.
+var cronSchedule *schedule.Schedule
+
+// parseSchedule parses --schedule: a cron expression in --timezone, or in the
+// zone of a leading TZ=<zone> (or CRON_TZ=<zone>).
+func parseSchedule(spec string) (*schedule.Schedule, error) {
+	loc := timeZone
+	if first, rest, ok := strings.Cut(strings.TrimSpace(spec), " "); ok && (strings.HasPrefix(first, "TZ=") || strings.HasPrefix(first, "CRON_TZ=")) {
+		_, zone, _ := strings.Cut(first, "=")
+		var err error
+		if loc, err = parseTimeZone(zone); err != nil {
+			return nil, err
+		}
+		spec = rest
+	}
+	return schedule.Parse(spec, loc)
+}
+
+// parseDailyAt parses --daily-at: a time of day such as 18:00, optionally
+// followed by a zone as for --timezone, e.g. "18:00 TCT".
+func parseDailyAt(s string) (*schedule.Schedule, error) {
+	at, zone, _ := strings.Cut(strings.TrimSpace(s), " ")
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a time of day such as 18:00", at)
+	}
+	loc := timeZone
+	if zone = strings.TrimSpace(zone); zone != "" {
+		if loc, err = parseTimeZone(zone); err != nil {
+			return nil, err
+		}
+	}
+	return schedule.Parse(fmt.Sprintf("%d %d * * *", t.Minute(), t.Hour()), loc)
+}
+
+// jitter is a random offset for repeated runs: up to max, or up to frac of
+// the time between runs, either way.
+type jitter struct {
+	frac float64
+	max  time.Duration
+}
+
+// runJitter is the parsed --jitter. Set by validateOptions.
+var runJitter jitter
+
+// parseJitter parses --jitter: a duration such as 30s or a percentage such
+// as 10%.
+func parseJitter(s string) (jitter, error) {
+	if s == "" {
+		return jitter{}, nil
+	}
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil || p < 0 || p > 50 {
+			return jitter{}, fmt.Errorf("%q is not a percentage from 0%% to 50%%", s)
+		}
+		return jitter{frac: p / 100}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return jitter{}, fmt.Errorf("%q is neither a duration such as 30s nor a percentage such as 10%%", s)
+	}
+	return jitter{max: d}, nil
+}
+
+// offset returns a random offset for a run gap apart from the previous one.
+func (j jitter) offset(gap time.Duration) time.Duration {
+	limit := j.max
+	if j.frac > 0 {
+		limit = time.Duration(j.frac * float64(gap))
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(2*limit+1))) - limit
+}
+
+// maxBackoff is the --max-backoff limit on the wait after failed runs. Set
+// by validateOptions.
+var maxBackoff time.Duration
+
+// backoffWait is the wait before the next run after failures runs in a row
+// failed, when it would usually be gap: doubled for each failure, up to
+// maxBackoff, but never less than gap.
+func backoffWait(gap time.Duration, failures int) time.Duration {
+	wait := gap
+	for i := 0; i < failures && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return max(gap, min(wait, maxBackoff))
+}
+
+// runTimer fires for the runs after the first: every interval, like a
+// ticker, or at each time the cron schedule matches. After failed runs it
+// backs off.
+type runTimer struct {
+	C <-chan time.Time
+
+	timer    *time.Timer
+	due      time.Time
+	interval time.Duration
+	sched    *schedule.Schedule
+	// failures counts the runs in a row that failed
+	failures int
+}
+
+func newRunTimer(interval time.Duration, sched *schedule.Schedule) *runTimer {
+	t := &runTimer{timer: time.NewTimer(time.Hour)}
+	t.C = t.timer.C
+	t.reset(interval, sched)
+	return t
+}
+
+// reset starts over from now with a new interval or schedule.
+func (t *runTimer) reset(interval time.Duration, sched *schedule.Schedule) {
+	t.interval, t.sched = interval, sched
+	t.due = time.Now()
+	t.next()
+}
+
+// record counts a run towards the backoff. Only runs that produced no
+// reports count as failed; a run skipped for the lock counts as nothing.
+func (t *runTimer) record(info *runInfo) {
+	switch info.Code {
+	case exitLocked:
+	case exitOK, exitPartial:
+		if t.failures > 0 {
+			slog.Info("Run succeeded; back to the usual schedule", "failed_runs", t.failures)
+		}
+		t.failures = 0
+	default:
+		t.failures++
+	}
+}
+
+// next arms the timer for the run after the one due, moved by --jitter.
+// Intervals missed while a run overran are skipped, as a ticker drops ticks.
+// The jitter doesn't accumulate: each run is due an interval after the
+// previous run was due. After failed runs the wait is stretched by
+// backoffWait, skipping scheduled times that come sooner.
+func (t *runTimer) next() {
+	now := time.Now()
+	gap := t.interval
+	if t.sched != nil {
+		// a run jittered early must not make its own time due again
+		from := now
+		if t.due.After(now) {
+			from = t.due
+		}
+		t.due = t.sched.Next(from)
+		if t.failures > 0 {
+			wait := backoffWait(t.due.Sub(now), t.failures)
+			for !t.due.IsZero() && t.due.Sub(now) < wait {
+				t.due = t.sched.Next(t.due)
+			}
+		}
+		if t.due.IsZero() {
+			slog.Error("The schedule matches no time in the next five years; not running again")
+			t.timer.Stop()
+			return
+		}
+		gap = t.due.Sub(now)
+	} else if t.failures > 0 {
+		gap = backoffWait(t.interval, t.failures)
+		t.due = now.Add(gap)
+	} else {
+		t.due = t.due.Add(t.interval)
+		for !t.due.After(now) {
+			t.due = t.due.Add(t.interval)
+		}
+	}
+	at := t.due.Add(runJitter.offset(gap))
+	if t.failures > 0 {
+		slog.Warn("Backing off after failed runs", "failed_runs", t.failures, "next_run", formatTime(at))
+	} else if t.sched != nil {
+		slog.Info("Next scheduled run", "at", formatTime(at))
+	} else {
+		slog.Debug("Next run", "at", formatTime(at))
+	}
+	t.timer.Reset(max(time.Until(at), 0))
+}
+
+// runLabel tags the outputs of every run, set by --label.
+var runLabel string
+
+// runInfo describes one run of the reports.
+type runInfo struct {
+	StartedAt time.Time
+	// Trigger is what started the run: once, startup, interval, schedule or
+	// watch
+	Trigger            string
+	Label              string
+	Members            int
+	Crimes             int
+	OldestAt, NewestAt int64 // executed_at window of the processed crimes
+	Errors             []string
+	// exit code of a single run, see exitCode
+	Code int
+	// failed names each failure by its step and the class of its error,
+	// such as "fetch members: torn network error", for where the error
+	// text itself shouldn't go
+	failed []string
+
+	// mu guards what the run's concurrent fetches and writes record
+	mu sync.Mutex
+	// for the run summary: how many times each step (an API request, a
+	// sheet write) was taken and how long they took together, and the rows
+	// written to each range (or messages to Discord)
+	steps map[string]*runStep
+	rows  map[string]int
+}
+
+type runStep struct {
+	count int
+	took  time.Duration
+}
+
+// Exit codes of a single run, for cron, CI and systemd OnFailure handlers.
+// Configuration and usage errors exit with 1 before running, and flag parsing
+// errors with 2.
+const (
+	exitOK = 0
+	// exitError is a failed run without a more specific code.
+	exitError = 1
+	// exitTornAuth means Torn rejected the API key or its access level.
+	exitTornAuth = 3
+	// exitRateLimited means Torn or Google kept refusing requests for going
+	// over the rate limit.
+	exitRateLimited = 4
+	// exitSheetsWrite means the report tabs could not be written to Google
+	// Sheets.
+	exitSheetsWrite = 5
+	// exitPartial means the reports were produced but some other output
+	// failed, e.g. the summary tab or a Discord message.
+	exitPartial = 6
+	// exitLocked means the run was skipped as another instance held the
+	// --lock-file.
+	exitLocked = 7
+)
+
+func newRunInfo() *runInfo {
+	return &runInfo{StartedAt: time.Now(), Label: runLabel}
+}
+
+// fail logs a non-fatal error and records it for the run summary, making the
+// run a partial success.
+func (ri *runInfo) fail(msg string, err error, args ...any) {
+	ri.failWith(exitPartial, msg, err, args...)
+}
+
+// failWith is fail for errors that warrant a specific exit code. Rejected
+// keys and rate limits are recognised whatever the code given. The first
+// failure other than a partial one decides the run's exit code.
+func (ri *runInfo) failWith(code int, msg string, err error, args ...any) {
+	slog.Error(msg, append(args, "error", err)...)
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	ri.Errors = append(ri.Errors, fmt.Sprintf("%s: %v", msg, err))
+	class := "error"
+	if api, kind := apiErrorLabels(err); api != "" {
+		metrics.APIError(api, kind)
+		class = api + " " + kind + " error"
+	}
+	ri.failed = append(ri.failed, msg+": "+class)
+
+	if c := errorExitCode(err); c != exitError {
+		code = c
+	}
+	if ri.Code == exitOK || (ri.Code == exitPartial && code != exitPartial) {
+		ri.Code = code
+	}
+}
+
+// status is the outcome of the run in a word: OK, Partial, Failed or
+// Skipped (for the lock).
+func (ri *runInfo) status() string {
+	switch ri.Code {
+	case exitOK:
+		return "OK"
+	case exitPartial:
+		return "Partial"
+	case exitLocked:
+		return "Skipped"
+	}
+	return "Failed"
+}
+
+// redactedErrors are the run's errors with API keys taken out, for the
+// outputs others can read.
+func (ri *runInfo) redactedErrors() []string {
+	errs := make([]string, len(ri.Errors))
+	for i, e := range ri.Errors {
+		errs[i] = redact(e)
+	}
+	return errs
+}
+
+// failure describes the run's first failure by its step, the class of its
+// error and the exit code, without the error text, for pushes to topics and
+// services others may read.
+func (ri *runInfo) failure() string {
+	first := "error"
+	if len(ri.failed) > 0 {
+		first = ri.failed[0]
+	}
+	return fmt.Sprintf("%s (exit %d)", first, ri.Code)
+}
+
+// summary describes the run in one line, e.g. for --quiet.
+func (ri *runInfo) summary() string {
+	took := time.Since(ri.StartedAt).Round(time.Second)
+	started := formatTime(ri.StartedAt)
+	if ri.Label != "" {
+		started += " [" + ri.Label + "]"
+	}
+	if len(ri.Errors) == 0 {
+		return fmt.Sprintf("%s OK: %d crimes processed in %s", started, ri.Crimes, took)
+	}
+	return fmt.Sprintf("%s FAILED (exit %d): %d crimes processed in %s, %d errors, first: %s",
+		started, ri.Code, ri.Crimes, took, len(ri.Errors), ri.Errors[0])
+}
+
+// timed records a step of the run taking d. A nil runInfo records nothing.
+func (ri *runInfo) timed(step string, d time.Duration) {
+	if ri == nil {
+		return
+	}
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	if ri.steps == nil {
+		ri.steps = make(map[string]*runStep)
+	}
+	if ri.steps[step] == nil {
+		ri.steps[step] = &runStep{}
+	}
+	ri.steps[step].count++
+	ri.steps[step].took += d
+}
+
+// wrote records rows written to dest.
+func (ri *runInfo) wrote(dest string, rows int) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	if ri.rows == nil {
+		ri.rows = make(map[string]int)
+	}
+	ri.rows[dest] += rows
+}
+
+// logSummary logs the run as a single event for log aggregation: counts, the
+// time taken by each step, and the errors.
+func (ri *runInfo) logSummary() {
+	steps := make([]any, 0, len(ri.steps))
+	for _, name := range slices.Sorted(maps.Keys(ri.steps)) {
+		s := ri.steps[name]
+		steps = append(steps, slog.Group(strings.NewReplacer(" ", "_", "/", "_").Replace(name),
+			"count", s.count, "seconds", s.took.Seconds()))
+	}
+	rows := make([]any, 0, len(ri.rows))
+	for _, dest := range slices.Sorted(maps.Keys(ri.rows)) {
+		rows = append(rows, slog.Int(dest, ri.rows[dest]))
+	}
+	pages := 0
+	if s := ri.steps["torn faction/crimes"]; s != nil {
+		pages = s.count
+	}
+	args := []any{
+		"started_at", formatTime(ri.StartedAt),
+		"seconds", time.Since(ri.StartedAt).Seconds(),
+		"exit_code", ri.Code,
+		"members", ri.Members,
+		"crimes", ri.Crimes,
+		"pages", pages,
+		slog.Group("rows", rows...),
+		slog.Group("steps", steps...),
+		"errors", len(ri.Errors),
+	}
+	if ri.Label != "" {
+		args = append(args, "label", ri.Label)
+	}
+	if len(ri.Errors) > 0 {
+		args = append(args, "first_error", ri.Errors[0])
+	}
+	slog.Info("Run summary", args...)
+}
+
+func (ri *runInfo) recordCrimes(crimes []torn.Crime) {
+	for _, c := range crimes {
+		ri.recordCrime(c)
+	}
+}
+
+// recordCrime counts one processed crime into the run's window.
+func (ri *runInfo) recordCrime(c torn.Crime) {
+	ri.Crimes++
+	if ri.OldestAt == 0 || c.ExecutedAt < ri.OldestAt {
+		ri.OldestAt = c.ExecutedAt
+	}
+	if c.ExecutedAt > ri.NewestAt {
+		ri.NewestAt = c.ExecutedAt
+	}
+}
+
+// redact takes the Torn API key, and the other keys given, out of error
+// text that leaves the process: health checks, notifications, error reports,
+// traces and sheets. The Torn client keeps keys out of its errors; this
+// guards against any other error quoting one.
+func redact(msg string, keys ...string) string {
+	for _, k := range append(keys, os.Getenv("TORN_API_KEY")) {
+		if k != "" {
+			msg = strings.ReplaceAll(msg, k, "[redacted]")
+		}
+	}
+	return msg
+}
+
+// redactErr is redact for an error, such as one ending a span.
+func redactErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.New(redact(err.Error()))
+}
+
+// apiErrorLabels names the API an error came from, and its kind, for the
+// torn_oc_api_errors_total metric. api is "" for errors not from an API call.
+func apiErrorLabels(err error) (api, kind string) {
+	var tornErr *torn.Error
+	var urlErr *url.Error
+	switch {
+	case torn.IsAuthError(err):
+		return "torn", "auth"
+	case torn.IsRateLimited(err):
+		return "torn", "rate_limit"
+	case errors.As(err, &tornErr):
+		return "torn", "other"
+	case sheetspkg.IsRateLimited(err):
+		return "sheets", "rate_limit"
+	case sheetspkg.IsAPIError(err):
+		return "sheets", "other"
+	case errors.As(err, &urlErr):
+		api = "other"
+		if u, perr := url.Parse(urlErr.URL); perr == nil {
+			switch host := u.Hostname(); {
+			case strings.HasSuffix(host, "torn.com"):
+				api = "torn"
+			case strings.HasSuffix(host, "googleapis.com"):
+				api = "sheets"
+			case strings.Contains(host, "discord"):
+				api = "discord"
+			}
+		}
+		return api, "network"
+	}
+	return "", ""
+}
+
+// errorExitCode is the exit code for a command failing with err: exitTornAuth
+// or exitRateLimited when err is one of those, else exitError.
+func errorExitCode(err error) int {
+	switch {
+	case torn.IsAuthError(err):
+		return exitTornAuth
+	case torn.IsRateLimited(err), sheetspkg.IsRateLimited(err):
+		return exitRateLimited
+	}
+	return exitError
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// runStateMaxAge is how old an interrupted run may be and still be resumed;
+// an older one's crimes are fetched afresh.
+const runStateMaxAge = 24 * time.Hour
+
+// runState is the progress of a run, kept in --state-file until the run
+// finishes so that a run cut short by a crash, OOM kill or reboot is resumed
+// by the next start rather than redone from scratch. The file holds the
+// cursor of the fetch; the crimes themselves are appended a page at a time
+// to the --state-file with .crimes added, so a checkpoint costs a page of
+// writing however long the history.
+type runState struct {
+	path string
+
+	StartedAt time.Time `json:"started_at"`
+	// Offset is where the next page of completed crimes starts, Saved how
+	// many of Crimes, those fetched so far, are in the first Size bytes of
+	// the crimes file, and Fetched set once every page is in.
+	Offset  int          `json:"offset"`
+	Crimes  []torn.Crime `json:"-"`
+	Saved   int          `json:"crimes"`
+	Size    int64        `json:"crimes_size"`
+	Fetched bool         `json:"fetched"`
+	// Done lists the outputs already sent that repeating would duplicate,
+	// such as history log rows and Discord messages. Sheet writes replace
+	// what was there, so they are simply redone.
+	Done []string `json:"done"`
+}
+
+// loadRunState returns the state of the run interrupted at path, or a new
+// one; nil without a path.
+func loadRunState(path string) *runState {
+	if path == "" {
+		return nil
+	}
+	fresh := &runState{path: path, StartedAt: time.Now()}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fresh
+	}
+	s := &runState{path: path}
+	if err == nil {
+		err = json.Unmarshal(data, s)
+	}
+	if err == nil {
+		s.Crimes, err = readCrimePages(s.crimesPath(), s.Size, s.Saved)
+	}
+	switch {
+	case err != nil:
+		slog.Warn("Ignoring unreadable run state", "path", path, "error", err)
+		return fresh
+	case time.Since(s.StartedAt) > runStateMaxAge:
+		slog.Info("Ignoring the state of a run interrupted too long ago", "path", path, "started_at", formatTime(s.StartedAt))
+		return fresh
+	}
+	slog.Warn("Resuming an interrupted run", "started_at", formatTime(s.StartedAt), "crimes", len(s.Crimes), "fetched", s.Fetched)
+	return s
+}
+
+func (s *runState) crimesPath() string {
+	return s.path + ".crimes"
+}
+
+// readCrimePages reads the n crimes in the first size bytes of a crimes file,
+// a JSON array of crimes per line. What follows is a page appended by a run
+// interrupted before it saved its cursor.
+func readCrimePages(path string, size int64, n int) ([]torn.Crime, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var crimes []torn.Crime
+	sc := bufio.NewScanner(io.LimitReader(f, size))
+	sc.Buffer(nil, 64<<20)
+	for sc.Scan() {
+		var page []torn.Crime
+		if err := json.Unmarshal(sc.Bytes(), &page); err != nil {
+			return nil, err
+		}
+		crimes = append(crimes, page...)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(crimes) != n {
+		return nil, fmt.Errorf("%s has %d crimes, not the %d saved", path, len(crimes), n)
+	}
+	return crimes, nil
+}
+
+// saveCrimes appends the crimes fetched since the last checkpoint to the
+// crimes file, after the pages already saved, then saves the cursor.
+func (s *runState) saveCrimes() {
+	if s.Saved == len(s.Crimes) {
+		s.save()
+		return
+	}
+	page, err := json.Marshal(s.Crimes[s.Saved:])
+	if err == nil {
+		var f *os.File
+		if f, err = os.OpenFile(s.crimesPath(), os.O_WRONLY|os.O_CREATE, 0o600); err == nil {
+			if err = f.Truncate(s.Size); err == nil {
+				_, err = f.WriteAt(append(page, '\n'), s.Size)
+			}
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		slog.Warn("Failed to save run state", "path", s.crimesPath(), "error", err)
+		return
+	}
+	s.Saved, s.Size = len(s.Crimes), s.Size+int64(len(page))+1
+	s.save()
+}
+
+// save writes the state, by way of a temporary file so a crash mid-write
+// leaves the previous state.
+func (s *runState) save() {
+	if s == nil {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err == nil {
+		tmp := s.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, s.path)
+		}
+	}
+	if err != nil {
+		slog.Warn("Failed to save run state", "path", s.path, "error", err)
+	}
+}
+
+// clear removes the state of a finished run.
+func (s *runState) clear() {
+	if s == nil {
+		return
+	}
+	for _, path := range []string{s.path, s.crimesPath()} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Failed to remove run state", "path", path, "error", err)
+		}
+	}
+}
+
+// done reports whether output was already sent by the interrupted run.
+func (s *runState) done(output string) bool {
+	return s != nil && slices.Contains(s.Done, output)
+}
+
+// markDone records that output was sent.
+func (s *runState) markDone(output string) {
+	if s == nil {
+		return
+	}
+	s.Done = append(s.Done, output)
+	s.save()
+}
+
+// fetchCompleted fetches every completed crime, resuming the fetch of an
+// interrupted run and checkpointing each page to s. Fetches limited by
+// --max-pages or --max-crimes go newest first, so aren't resumable.
+func fetchCompleted(c *torn.Client, s *runState) ([]torn.Crime, error) {
+	if s == nil || c.MaxPages > 0 || c.MaxCrimes > 0 {
+		return c.FetchAllCrimes()
+	}
+	if s.Fetched {
+		return s.Crimes, nil
+	}
+	crimes, err := c.FetchCrimesFrom("completed", s.Offset, s.Crimes, func(next int, crimes []torn.Crime) {
+		s.Offset, s.Crimes = next, crimes
+		s.saveCrimes()
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Crimes, s.Fetched = crimes, true
+	s.saveCrimes()
+	return crimes, nil
+}
@@ -1,13 +1,42 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 
-	"torn-oc-history/internal/env"
-	"torn-oc-history/internal/log"
+	"github.com/mnuck/torn-oc-history/internal/env"
+	"github.com/mnuck/torn-oc-history/internal/keyring"
+	"github.com/mnuck/torn-oc-history/internal/log"
+	"github.com/mnuck/torn-oc-history/internal/torn"
 )
 
+// envFile is where settings the tool discovers itself, like the ID of a
+// spreadsheet it created, are saved. Each config profile has its own.
+var envFile = ".env"
+
+// configPath is the config file the flags were read from, if any.
+var configPath string
+
+// maxPages and maxCrimes cap crime fetching, set by --max-pages and
+// --max-crimes.
+var maxPages, maxCrimes int
+
+// newTornClient creates a Torn client applying the fetch limits, showing
+// fetch progress on an interactive stderr.
+func newTornClient(key string) *torn.Client {
+	c := torn.NewClient(key)
+	c.MaxPages, c.MaxCrimes = maxPages, maxCrimes
+	if m := newProgressMeter(); m != nil {
+		c.Progress = m.update
+	}
+	return c
+}
+
+// quiet suppresses the report body and informational logs, set by --quiet.
+var quiet bool
+
 // setupEnvironment loads .env file and configures logging.
 func setupEnvironment() {
 	// Load .env file if it exists
@@ -28,7 +57,7 @@ func setupEnvironment() {
 func getRequiredEnv(key string) string {
 	value := os.Getenv(key)
 	if value == "" {
-		slog.Error(key+" environment variable is required.")
+		slog.Error(key + " environment variable is required.")
 		os.Exit(1)
 	}
 	return value
@@ -42,3 +71,38 @@ func getEnvWithDefault(key, defaultValue string) string {
 	}
 	return value
 }
+
+// secretEnv are the environment variables that may hold secrets, and so can
+// also be read from a file named by their _FILE variable or from the keyring.
+var secretEnv = []string{
+	"TORN_API_KEY", "SPREADSHEET_ID", "SPREADSHEET_ID_NOC", "SPREADSHEET_ID_ALL",
+	"DISCORD_WEBHOOK_URL", "GOOGLE_CREDENTIALS_JSON", "NTFY_TOKEN", "PUSHOVER_TOKEN", "PUSHOVER_USER",
+	"DISCORD_ALERT_WEBHOOK_URL", "TELEGRAM_BOT_TOKEN", "SMTP_PASSWORD", "SENTRY_DSN", "ERROR_REPORT_WEBHOOK_URL",
+	"DISCORD_BOT_TOKEN", "SLACK_SIGNING_SECRET", "SERVER_AUTH_TOKENS", "SERVER_BASIC_AUTH",
+}
+
+// resolveSecrets fills unset secret variables from their _FILE variables and
+// then, with useKeyring, from the OS keyring.
+func resolveSecrets(useKeyring bool) error {
+	if err := env.LoadFiles(secretEnv); err != nil {
+		return err
+	}
+	if !useKeyring {
+		return nil
+	}
+	for _, key := range secretEnv {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		secret, err := keyring.Get(key)
+		if errors.Is(err, keyring.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("keyring: %w", err)
+		}
+		os.Setenv(key, secret)
+		slog.Debug("Read secret from keyring", "name", key)
+	}
+	return nil
+}
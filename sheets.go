@@ -0,0 +1,696 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/env"
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// createSpreadsheet creates a spreadsheet for the faction, or copies the one
+// in TEMPLATE_SPREADSHEET_ID, with a tab for every target range and records
+// its ID in the .env file (of the profile) so later runs reuse it.
+func createSpreadsheet(ctx context.Context, client *sheetspkg.Client, tornClient *torn.Client, ranges []string) (string, error) {
+	faction, err := tornClient.FetchFactionBasic()
+	if err != nil {
+		return "", fmt.Errorf("fetch faction: %w", err)
+	}
+
+	var tabs []string
+	seen := make(map[string]bool)
+	for _, r := range ranges {
+		if name := sheetspkg.SheetName(fallbackRange(r)); name != "" && !seen[name] {
+			seen[name] = true
+			tabs = append(tabs, name)
+		}
+	}
+
+	title := "Torn OC History — " + faction.Name
+	var id, url string
+	if template := os.Getenv("TEMPLATE_SPREADSHEET_ID"); template != "" {
+		if id, url, err = client.CopySpreadsheet(ctx, template, title); err != nil {
+			return "", err
+		}
+		// the template may not have every tab the flags point at
+		if err := client.EnsureSheets(ctx, id, tabs); err != nil {
+			return "", fmt.Errorf("ensure sheets: %w", err)
+		}
+	} else if id, url, err = client.CreateSpreadsheet(ctx, title, tabs); err != nil {
+		return "", err
+	}
+	fmt.Println("Created spreadsheet:", url)
+	slog.Info("Created spreadsheet", "id", id, "url", url)
+
+	shareSpreadsheet(ctx, client, id)
+
+	if err := env.Set(envFile, "SPREADSHEET_ID", id); err != nil {
+		slog.Warn("Could not save SPREADSHEET_ID to "+envFile+"; set it manually to reuse this spreadsheet", "id", id, "error", err)
+	}
+	return id, nil
+}
+
+// shareSpreadsheet grants the SHARE_EMAILS addresses SHARE_ROLE access
+// ("writer" by default) so leaders can open the service-account-owned sheet.
+func shareSpreadsheet(ctx context.Context, client *sheetspkg.Client, spreadsheetID string) {
+	role := getEnvWithDefault("SHARE_ROLE", "writer")
+	if role != "reader" && role != "writer" {
+		slog.Error("SHARE_ROLE must be either 'reader' or 'writer'; not sharing spreadsheet", "role", role)
+		return
+	}
+	for _, email := range strings.Split(os.Getenv("SHARE_EMAILS"), ",") {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		if err := client.Share(ctx, spreadsheetID, email, role); err != nil {
+			slog.Error("share spreadsheet", "email", email, "error", err)
+		} else {
+			slog.Info("Shared spreadsheet", "email", email, "role", role)
+		}
+	}
+}
+
+// namedRangePrefix marks a range flag as a named range: "@Name" targets the
+// named range Name, and "@Name=History!A1" also says where to create it if the
+// spreadsheet has no such named range yet. Without a location, a missing named
+// range is created at A1 of a tab called Name.
+const namedRangePrefix = "@"
+
+// parseNamedTarget splits a named-range target into its name and the A1 range
+// to create it at. ok is false for plain A1 ranges.
+func parseNamedTarget(target string) (name, fallback string, ok bool) {
+	if !strings.HasPrefix(target, namedRangePrefix) {
+		return "", "", false
+	}
+	name, fallback, _ = strings.Cut(strings.TrimPrefix(target, namedRangePrefix), "=")
+	if fallback == "" {
+		fallback = sheetspkg.QuoteSheet(name) + "!A1"
+	}
+	return name, fallback, true
+}
+
+// fallbackRange returns the A1 range a target refers to before any named range
+// has been looked up.
+func fallbackRange(target string) string {
+	if _, fallback, ok := parseNamedTarget(target); ok {
+		return fallback
+	}
+	return target
+}
+
+// resolveTargets replaces named-range targets with the current top-left cell
+// of their named range, creating missing named ranges at their fallback
+// location. Plain A1 ranges are returned unchanged, and the spreadsheet is
+// only consulted when some target is a named range.
+func resolveTargets(ctx context.Context, client *sheetspkg.Client, spreadsheetID string, targets []string) ([]string, error) {
+	resolved := make([]string, len(targets))
+	var named map[string]string
+	for i, t := range targets {
+		name, fallback, ok := parseNamedTarget(t)
+		if !ok {
+			resolved[i] = t
+			continue
+		}
+		if named == nil {
+			var err error
+			if named, err = client.NamedRanges(ctx, spreadsheetID); err != nil {
+				return nil, err
+			}
+		}
+		if r, ok := named[name]; ok {
+			resolved[i] = r
+			continue
+		}
+		if err := client.AddNamedRange(ctx, spreadsheetID, name, fallback); err != nil {
+			return nil, fmt.Errorf("create named range %s: %w", name, err)
+		}
+		slog.Info("Created named range", "name", name, "range", fallback)
+		named[name] = fallback
+		resolved[i] = fallback
+	}
+	return resolved, nil
+}
+
+// writeSheets replaces the contents of every range, creating missing tabs
+// first. All ranges are cleared in one call and written in another, keeping
+// quota usage and the window for partial writes small.
+func writeSheets(ctx context.Context, client *sheetspkg.Client, spreadsheetID string, writes []sheetspkg.RangeValues) error {
+	var titles, ranges []string
+	for _, w := range writes {
+		if name := sheetspkg.SheetName(w.Range); name != "" {
+			titles = append(titles, name)
+		}
+		ranges = append(ranges, clearRange(w))
+	}
+	if len(titles) > 0 {
+		if err := client.EnsureSheets(ctx, spreadsheetID, titles); err != nil {
+			return fmt.Errorf("ensure sheets: %w", err)
+		}
+	}
+	if err := client.BatchClearRanges(ctx, spreadsheetID, ranges); err != nil {
+		return fmt.Errorf("clear: %w", err)
+	}
+	if err := client.BatchUpdateRanges(ctx, spreadsheetID, writes); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return verifyWritten(ctx, client, spreadsheetID, writes)
+}
+
+// singleCell matches the cell of a range given as its top-left cell.
+var singleCell = regexp.MustCompile(`^[A-Za-z]+[0-9]+$`)
+
+// clearRange is the block writeSheets clears before a write: a range given
+// as its top-left cell, such as History!A1, is cleared over the width of the
+// rows written, from that cell to the bottom of the sheet, so rows left over
+// from a longer report go too. Other ranges are cleared as given.
+func clearRange(w sheetspkg.RangeValues) string {
+	if !singleCell.MatchString(w.Range[strings.LastIndex(w.Range, "!")+1:]) {
+		return w.Range
+	}
+	sheet := sheetspkg.SheetName(w.Range)
+	width := 1
+	for _, r := range w.Values {
+		width = max(width, len(r))
+	}
+	row, col := sheetspkg.StartCell(w.Range)
+	return sheetspkg.GridA1(sheet, row, col, -1, col+int64(width)-1)
+}
+
+// writeSheetsDiff updates only the cells whose values changed since the last
+// write. Nothing outside each report's columns is touched, so annotations users
+// keep in adjacent columns survive, and unchanged reports cost a single read.
+func writeSheetsDiff(ctx context.Context, client *sheetspkg.Client, spreadsheetID string, writes []sheetspkg.RangeValues) error {
+	var titles []string
+	for _, w := range writes {
+		if name := sheetspkg.SheetName(w.Range); name != "" {
+			titles = append(titles, name)
+		}
+	}
+	if len(titles) > 0 {
+		if err := client.EnsureSheets(ctx, spreadsheetID, titles); err != nil {
+			return fmt.Errorf("ensure sheets: %w", err)
+		}
+	}
+
+	var changes []sheetspkg.RangeValues
+	for _, w := range writes {
+		sheet := sheetspkg.SheetName(w.Range)
+		row, col := sheetspkg.StartCell(w.Range)
+		width := 0
+		for _, r := range w.Values {
+			width = max(width, len(r))
+		}
+		existing, err := client.ReadFormulas(ctx, spreadsheetID, sheetspkg.GridA1(sheet, row, col, -1, col+int64(width)-1))
+		if err != nil {
+			return fmt.Errorf("read %s: %w", w.Range, err)
+		}
+		changed := diffCells(sheet, row, col, width, existing, w.Values)
+		for i := range changed {
+			changed[i].UserEntered = w.UserEntered
+		}
+		slog.Debug("Computed sheet diff", "range", w.Range, "updates", len(changed))
+		changes = append(changes, changed...)
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := client.BatchUpdateRanges(ctx, spreadsheetID, changes); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return verifyWritten(ctx, client, spreadsheetID, writes)
+}
+
+// previewRows is how many rows of each range previewWrites prints.
+const previewRows = 5
+
+// previewWrites prints what writing writes would do, without calling the
+// API: the ranges that would be cleared first (when clear is set) and the
+// size and first rows of each write.
+func previewWrites(w io.Writer, spreadsheetID, action string, writes []sheetspkg.RangeValues, clear bool) {
+	for _, rv := range writes {
+		width := 0
+		for _, r := range rv.Values {
+			width = max(width, len(r))
+		}
+		if clear {
+			fmt.Fprintf(w, "[dry run] %s: clear %s\n", spreadsheetID, clearRange(rv))
+		}
+		fmt.Fprintf(w, "[dry run] %s: %s %d rows x %d columns to %s\n", spreadsheetID, action, len(rv.Values), width, rv.Range)
+		for r := range rv.Values[:min(len(rv.Values), previewRows)] {
+			cells := make([]string, width)
+			for c := range cells {
+				cells[c] = cellString(rv.Values, r, c)
+			}
+			fmt.Fprintf(w, "    %s\n", strings.Join(cells, " | "))
+		}
+		if n := len(rv.Values) - previewRows; n > 0 {
+			fmt.Fprintf(w, "    … %d more rows\n", n)
+		}
+	}
+}
+
+// verifyWrites and verifyRetries are set by the --verify-writes and
+// --verify-retries flags.
+var (
+	verifyWrites  = false
+	verifyRetries = 1
+)
+
+// verifyWritten reads each write back and compares its row count and a hash of
+// its contents with what was sent, logging a warning for every mismatch and
+// rewriting mismatched ranges up to verifyRetries times. Under quota pressure
+// the API has been seen to accept a write but store only part of it. It is a
+// no-op unless --verify-writes is set.
+func verifyWritten(ctx context.Context, client *sheetspkg.Client, spreadsheetID string, writes []sheetspkg.RangeValues) error {
+	if !verifyWrites {
+		return nil
+	}
+	for attempt := 0; len(writes) > 0; attempt++ {
+		var bad []sheetspkg.RangeValues
+		for _, w := range writes {
+			sheet := sheetspkg.SheetName(w.Range)
+			row, col := sheetspkg.StartCell(w.Range)
+			width := 0
+			for _, r := range w.Values {
+				width = max(width, len(r))
+			}
+			if width == 0 {
+				continue
+			}
+			got, err := client.ReadFormulas(ctx, spreadsheetID, sheetspkg.GridA1(sheet, row, col, row+int64(len(w.Values))-1, col+int64(width)-1))
+			if err != nil {
+				return fmt.Errorf("read back %s: %w", w.Range, err)
+			}
+			if want, have := contentHash(w.Values, width), contentHash(got, width); want != have || len(got) != len(w.Values) {
+				slog.Warn("Sheet write did not read back as written", "range", w.Range, "rows", len(w.Values), "read", len(got), "hash", want, "readHash", have, "attempt", attempt+1)
+				bad = append(bad, w)
+			}
+		}
+		if len(bad) == 0 || attempt >= verifyRetries {
+			return nil
+		}
+		if err := client.BatchUpdateRanges(ctx, spreadsheetID, bad); err != nil {
+			return fmt.Errorf("rewrite: %w", err)
+		}
+		writes = bad
+	}
+	return nil
+}
+
+// contentHash hashes the first width columns of every row of grid.
+func contentHash(grid [][]interface{}, width int) string {
+	h := sha256.New()
+	for r := range grid {
+		for c := 0; c < width; c++ {
+			fmt.Fprintf(h, "%s\t", cellString(grid, r, c))
+		}
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// cellString returns the cell at row r, column c of grid as text comparable
+// between values written and values read back with ReadFormulas. Missing
+// cells are empty.
+func cellString(grid [][]interface{}, r, c int) string {
+	if r >= len(grid) || c >= len(grid[r]) {
+		return ""
+	}
+	// the API returns numbers as float64; avoid exponent notation for large IDs
+	if f, ok := grid[r][c].(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(grid[r][c])
+}
+
+// diffCells compares the existing block of cells with the new rows and returns
+// one update per run of adjacent changed cells in a row. Cells beyond the new
+// rows are blanked.
+func diffCells(sheet string, startRow, startCol int64, width int, existing, rows [][]interface{}) []sheetspkg.RangeValues {
+	var changes []sheetspkg.RangeValues
+	for r := 0; r < max(len(existing), len(rows)); r++ {
+		for c := 0; c < width; {
+			if cellString(existing, r, c) == cellString(rows, r, c) {
+				c++
+				continue
+			}
+			start := c
+			var run []interface{}
+			for c < width && cellString(existing, r, c) != cellString(rows, r, c) {
+				if r < len(rows) && c < len(rows[r]) {
+					run = append(run, rows[r][c])
+				} else {
+					run = append(run, "")
+				}
+				c++
+			}
+			changes = append(changes, sheetspkg.RangeValues{
+				Range:  sheetspkg.GridA1(sheet, startRow+int64(r), startCol+int64(start), startRow+int64(r), startCol+int64(c-1)),
+				Values: [][]interface{}{run},
+			})
+		}
+	}
+	return changes
+}
+
+// columnWidths holds the fixed pixel widths from --column-widths, applied to
+// the leading columns of every table; other columns are auto-resized.
+var columnWidths []int64
+
+// parseColumnWidths parses a comma-separated list of pixel widths.
+func parseColumnWidths(v string) ([]int64, error) {
+	var widths []int64
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		w, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || w <= 0 {
+			return nil, fmt.Errorf("invalid column width %q", part)
+		}
+		widths = append(widths, w)
+	}
+	return widths, nil
+}
+
+// CPR band colours for conditional formatting.
+var (
+	sheetRed              = sheetspkg.Color{Red: 0.96, Green: 0.8, Blue: 0.8}
+	sheetYellow           = sheetspkg.Color{Red: 1, Green: 0.95, Blue: 0.7}
+	sheetGreen            = sheetspkg.Color{Red: 0.72, Green: 0.88, Blue: 0.72}
+	sheetHeaderBackground = sheetspkg.Color{Red: 0.85, Green: 0.88, Blue: 0.95}
+)
+
+// formatReportSheet styles the header row and colours the CPR column of a
+// report written by buildSheetRows.
+func formatReportSheet(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string) {
+	formatTable(ctx, client, spreadsheetID, targetRange, len(sheetHeader), cprColumn, cprColumn+1)
+
+	name := sheetspkg.SheetName(targetRange)
+	if name == "" {
+		return
+	}
+	row, col := sheetspkg.StartCell(targetRange)
+	if err := client.SetNumberFormat(ctx, spreadsheetID, name, row+1, col+executedColumn, col+executedColumn+1, "DATE_TIME", "yyyy-mm-dd hh:mm"); err != nil {
+		slog.Warn("format executed_at column", "range", targetRange, "error", err)
+	}
+}
+
+// cprValue converts a pass rate to the fraction written to Sheets, displayed
+// with a percent number format.
+func cprValue(rate int) float64 {
+	return float64(rate) / 100
+}
+
+// protectMode is set by the --protect flag: "off" leaves tables editable,
+// "warn" asks editors to confirm changes and "lock" blocks them.
+var protectMode = "off"
+
+// protectMarker starts the description of the protections protectTable
+// adds, telling them from those users add themselves.
+const protectMarker = "Written by torn-oc-history"
+
+// protectTable protects the table of the given width written at targetRange,
+// header included, according to protectMode. Everything the tool writes there
+// is overwritten on the next run anyway. The protection is named after the
+// table's top-left cell, so it replaces the previous run's when the table
+// grows or shrinks.
+func protectTable(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, width int) {
+	name := sheetspkg.SheetName(targetRange)
+	if name == "" || protectMode == "off" {
+		return
+	}
+	row, col := sheetspkg.StartCell(targetRange)
+	desc := protectMarker + " from " + sheetspkg.QuoteSheet(name) + "!" + sheetspkg.ColumnLetter(col) + strconv.FormatInt(row+1, 10) + "; edits are overwritten on the next run"
+	if err := client.ProtectColumns(ctx, spreadsheetID, name, row, col, col+int64(width), protectMarker, desc, protectMode == "warn"); err != nil {
+		slog.Warn("protect range", "range", targetRange, "error", err)
+	}
+}
+
+// formatTable styles the header row of a table of the given width written at
+// targetRange, sizes its columns, and formats its CPR columns [cprStart,
+// cprEnd), relative to the table, as colour-coded percentages. Formatting
+// failures are logged rather than failing the run; the data is already
+// written.
+func formatTable(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, width, cprStart, cprEnd int) {
+	name := sheetspkg.SheetName(targetRange)
+	if name == "" {
+		return
+	}
+	row, col := sheetspkg.StartCell(targetRange)
+	if err := client.StyleHeaderRow(ctx, spreadsheetID, name, row, col, col+int64(width), sheetHeaderBackground); err != nil {
+		slog.Warn("style header row", "range", targetRange, "error", err)
+	}
+	if err := client.SizeColumns(ctx, spreadsheetID, name, col, col+int64(width), columnWidths); err != nil {
+		slog.Warn("size columns", "range", targetRange, "error", err)
+	}
+	if cprEnd <= cprStart {
+		return
+	}
+	if err := client.SetNumberFormat(ctx, spreadsheetID, name, row+1, col+int64(cprStart), col+int64(cprEnd), "PERCENT", "0%"); err != nil {
+		slog.Warn("format CPR columns", "range", targetRange, "error", err)
+	}
+	thresholds := []sheetspkg.Threshold{
+		{Min: cprValue(cprHigh), Color: sheetGreen},
+		{Min: cprValue(cprLow), Color: sheetYellow},
+	}
+	if err := client.SetThresholds(ctx, spreadsheetID, name, row+1, col+int64(cprStart), col+int64(cprEnd), thresholds, sheetRed); err != nil {
+		slog.Warn("apply CPR conditional formatting", "range", targetRange, "error", err)
+	}
+}
+
+// difficultyTab is one per-difficulty matrix written by --split-difficulty.
+type difficultyTab struct {
+	sheetspkg.RangeValues
+	Positions int
+	Notes     [][]string // provenance of each position cell, excluding the header
+}
+
+// matrixColumns is the number of member columns before the positions in a difficulty tab.
+const matrixColumns = 3
+
+// buildDifficultyTabs splits a report into one member × position matrix per
+// difficulty. Tabs are named "<base> D<difficulty>". Besides the positions
+// with data, each has a column for every other position the catalog's crimes
+// of the difficulty have that f keeps, and the cells of positions a member
+// has never attempted at any difficulty, by stats, read "never".
+func buildDifficultyTabs(report Report, base string, stats MemberStats, f reportFilter) []difficultyTab {
+	if base == "" {
+		base = "Difficulty"
+	}
+	positions := make(map[int]map[string]bool)
+	for _, mr := range report.Members {
+		for _, dr := range mr.Difficulties {
+			if positions[dr.Difficulty] == nil {
+				positions[dr.Difficulty] = make(map[string]bool)
+			}
+			for _, pr := range dr.Positions {
+				positions[dr.Difficulty][pr.Position] = true
+			}
+		}
+	}
+	diffs := make([]int, 0, len(positions))
+	for d := range positions {
+		diffs = append(diffs, d)
+	}
+	sort.Ints(diffs)
+
+	var tabs []difficultyTab
+	for _, d := range diffs {
+		for _, p := range catalogPositions(d, f, positions[d]) {
+			positions[d][p] = true
+		}
+		var names []string
+		for p := range positions[d] {
+			names = append(names, p)
+		}
+		sort.Strings(names)
+
+		header := []interface{}{"Member", "ID", "In OC"}
+		for _, p := range names {
+			header = append(header, p)
+		}
+		rows := [][]interface{}{header}
+		var notes [][]string
+		for _, mr := range report.Members {
+			row := []interface{}{profileLink(mr.Member), mr.Member.ID, mr.Member.IsInOC}
+			var rowNotes []string
+			for _, p := range names {
+				value, note := matrixCell(mr, stats[mr.Member.ID], d, p)
+				row = append(row, value)
+				rowNotes = append(rowNotes, note)
+			}
+			rows = append(rows, row)
+			notes = append(notes, rowNotes)
+		}
+		tabs = append(tabs, difficultyTab{
+			RangeValues: sheetspkg.RangeValues{Range: sheetspkg.QuoteSheet(fmt.Sprintf("%s D%d", base, d)) + "!A1", Values: rows, UserEntered: true},
+			Positions:   len(names),
+			Notes:       notes,
+		})
+	}
+	return tabs
+}
+
+// matrixCell returns the value and provenance note of a member's position at
+// a difficulty: empty without data there, "never" if the member's stats have
+// none at any difficulty.
+func matrixCell(mr MemberReport, stats map[int]map[string]RateInfo, difficulty int, position string) (interface{}, string) {
+	for _, dr := range mr.Difficulties {
+		if dr.Difficulty != difficulty {
+			continue
+		}
+		for _, pr := range dr.Positions {
+			if pr.Position == position {
+				if pr.Rate == 0 {
+					return "-", provenance(pr.RateInfo)
+				}
+				return cprValue(pr.Rate), provenance(pr.RateInfo)
+			}
+		}
+	}
+	if !attempted(stats, position) {
+		return "never", ""
+	}
+	return "", ""
+}
+
+// setCPRNotes attaches provenance notes to the CPR cells of a table written at
+// targetRange, starting at column cprStart of the first data row.
+func setCPRNotes(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, cprStart int, notes [][]string) {
+	name := sheetspkg.SheetName(targetRange)
+	if name == "" || len(notes) == 0 {
+		return
+	}
+	row, col := sheetspkg.StartCell(targetRange)
+	if err := client.SetNotes(ctx, spreadsheetID, name, row+1, col+int64(cprStart), notes); err != nil {
+		slog.Warn("set CPR provenance notes", "range", targetRange, "error", err)
+	}
+}
+
+var historyLogHeader = []interface{}{"Run At", "Member", "ID", "In OC", "Best CPR", "Best Position", "Lowest CPR", "Lowest Position", "Positions", "Last OC", "Label"}
+
+// buildHistoryLogRows builds one summary row per member tagged with the run
+// time and label. CPRs are fractions and times date-time serials, as in the
+// report tabs, formatted by appendHistoryLog.
+func buildHistoryLogRows(report Report, label string) [][]interface{} {
+	runAt := serialTime(report.GeneratedAt.Unix())
+	var rows [][]interface{}
+	for _, mr := range report.Members {
+		s := summarize(mr)
+		row := []interface{}{runAt, mr.Member.Name, mr.Member.ID, mr.Member.IsInOC, "", "", "", "", s.Positions, "", label}
+		if s.Positions > 0 {
+			row[4] = cprValue(s.Best.Rate)
+			row[5] = fmt.Sprintf("%s (D%d)", s.Best.Position, s.BestDiff)
+			row[6] = cprValue(s.Worst.Rate)
+			row[7] = fmt.Sprintf("%s (D%d)", s.Worst.Position, s.WorstDiff)
+		}
+		if s.LastExecutedAt > 0 {
+			row[9] = serialTime(s.LastExecutedAt)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// historyLogFormats are the number formats of the history log's columns:
+// the run time and last OC as date-times, the CPRs as percentages.
+var historyLogFormats = []struct {
+	col           int64
+	kind, pattern string
+}{
+	{0, "DATE_TIME", "yyyy-mm-dd hh:mm"},
+	{4, "PERCENT", "0%"},
+	{6, "PERCENT", "0%"},
+	{9, "DATE_TIME", "yyyy-mm-dd hh:mm"},
+}
+
+// appendHistoryLog appends rows to the history log, writing the header first
+// when the log is still empty, and formats the rows appended. Rows logged
+// before CPRs were written as fractions keep their formats.
+func appendHistoryLog(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, rows [][]interface{}) error {
+	first, err := appendRows(ctx, client, spreadsheetID, targetRange, historyLogHeader, rows)
+	if err != nil {
+		return err
+	}
+	name := sheetspkg.SheetName(targetRange)
+	if name == "" {
+		return nil
+	}
+	_, col := sheetspkg.StartCell(targetRange)
+	for _, f := range historyLogFormats {
+		if err := client.SetRowsNumberFormat(ctx, spreadsheetID, name, first, first+int64(len(rows)), col+f.col, col+f.col+1, f.kind, f.pattern); err != nil {
+			slog.Warn("format history log", "range", targetRange, "error", err)
+		}
+	}
+	return nil
+}
+
+// appendRows appends rows to the append-only log at targetRange, creating its
+// tab if missing and starting it with header if it is empty. first is the
+// sheet row, zero-based, of the first of rows.
+func appendRows(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, header []interface{}, rows [][]interface{}) (first int64, err error) {
+	if name := sheetspkg.SheetName(targetRange); name != "" {
+		if err := client.EnsureSheet(ctx, spreadsheetID, name); err != nil {
+			return 0, fmt.Errorf("ensure sheet: %w", err)
+		}
+	}
+	existing, err := client.ReadSheet(ctx, spreadsheetID, targetRange)
+	if err != nil {
+		return 0, fmt.Errorf("read: %w", err)
+	}
+	row, _ := sheetspkg.StartCell(targetRange)
+	first = row + int64(len(existing))
+	if len(existing) == 0 {
+		rows = append([][]interface{}{header}, rows...)
+		first++
+	}
+	if err := client.AppendRows(ctx, spreadsheetID, targetRange, rows); err != nil {
+		return 0, fmt.Errorf("append: %w", err)
+	}
+	return first, nil
+}
+
+// buildAboutRows builds the key/value block describing the latest run, so
+// spreadsheet viewers can tell whether the data is fresh.
+func buildAboutRows(info *runInfo) [][]interface{} {
+	errs := "none"
+	if len(info.Errors) > 0 {
+		errs = strings.Join(info.redactedErrors(), "\n")
+	}
+	window := "-"
+	if info.Crimes > 0 {
+		window = fmt.Sprintf("%s to %s", formatUnix(info.OldestAt), formatUnix(info.NewestAt))
+	}
+	rows := [][]interface{}{
+		{"Last run", formatTime(info.StartedAt)},
+		{"Status", info.status()},
+		{"Crimes processed", info.Crimes},
+		{"Data window", window},
+		{"Tool version", version},
+		{"Errors", errs},
+	}
+	if info.Label != "" {
+		rows = append(rows[:1], append([][]interface{}{{"Label", info.Label}}, rows[1:]...)...)
+	}
+	return rows
+}
+
+// profileLink renders a member's name as a formula linking to their Torn profile.
+// Rows containing it must be written with UserEntered.
+func profileLink(m torn.Member) string {
+	return fmt.Sprintf(`=HYPERLINK("https://www.torn.com/profiles.php?XID=%d", "%s")`, m.ID, strings.ReplaceAll(m.Name, `"`, `""`))
+}
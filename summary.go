@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+)
+
+// cprBlock locates the CPR cells of a table written to the spreadsheet, so
+// the summary tab can reference them with formulas.
+type cprBlock struct {
+	Label            string
+	Sheet            string
+	StartRow         int64 // first data row, below the header
+	StartCol, EndCol int64 // CPR columns [StartCol, EndCol)
+}
+
+func newCPRBlock(label, targetRange string, cprStart, cprEnd int) (cprBlock, bool) {
+	sheet := sheetspkg.SheetName(targetRange)
+	if sheet == "" || cprEnd <= cprStart {
+		return cprBlock{}, false
+	}
+	row, col := sheetspkg.StartCell(targetRange)
+	return cprBlock{Label: label, Sheet: sheet, StartRow: row + 1, StartCol: col + int64(cprStart), EndCol: col + int64(cprEnd)}, true
+}
+
+func (b cprBlock) a1() string {
+	return sheetspkg.GridA1(b.Sheet, b.StartRow, b.StartCol, -1, b.EndCol-1)
+}
+
+// buildSummaryRows builds the one-page Summary tab: faction-level aggregates
+// computed from the all-members report, followed by live formulas into each
+// detail tab so the figures stay correct if users edit or filter those tabs.
+func buildSummaryRows(all Report, info *runInfo, blocks []cprBlock) [][]interface{} {
+	inOC, noHistory, belowLow, atHigh := 0, 0, 0, 0
+	for _, mr := range all.Members {
+		if mr.Member.IsInOC {
+			inOC++
+		}
+		s := summarize(mr)
+		if len(mr.Difficulties) == 0 {
+			noHistory++
+		}
+		if s.Positions > 0 && s.Worst.Rate < cprLow {
+			belowLow++
+		}
+		if s.Positions > 0 && s.Best.Rate >= cprHigh {
+			atHigh++
+		}
+	}
+
+	rows := [][]interface{}{
+		{"Faction summary", ""},
+		{"Members", len(all.Members)},
+		{"In OC", inOC},
+		{"Not in OC", len(all.Members) - inOC},
+		{"Without OC history", noHistory},
+		{fmt.Sprintf("With a position below %d%%", cprLow), belowLow},
+		{fmt.Sprintf("With a position at or above %d%%", cprHigh), atHigh},
+		{"Crimes processed", info.Crimes},
+		{"Generated at", formatTime(all.GeneratedAt)},
+	}
+
+	low, high := cprValue(cprLow), cprValue(cprHigh)
+	for _, b := range blocks {
+		ref := b.a1()
+		rows = append(rows,
+			[]interface{}{"", ""},
+			[]interface{}{b.Label, ""},
+			[]interface{}{"Average CPR", fmt.Sprintf(`=IFERROR(TEXT(AVERAGE(%s),"0.0%%"),"-")`, ref)},
+			[]interface{}{fmt.Sprintf("CPR values below %d%%", cprLow), fmt.Sprintf(`=COUNTIF(%s,"<%v")`, ref, low)},
+			[]interface{}{fmt.Sprintf("CPR values %d%%-%d%%", cprLow, cprHigh-1), fmt.Sprintf(`=COUNTIFS(%s,">=%v",%s,"<%v")`, ref, low, ref, high)},
+			[]interface{}{fmt.Sprintf("CPR values at or above %d%%", cprHigh), fmt.Sprintf(`=COUNTIF(%s,">=%v")`, ref, high)},
+		)
+	}
+	return rows
+}
+
+// writeSummary replaces the Summary tab contents and styles its title row.
+func writeSummary(ctx context.Context, client *sheetspkg.Client, spreadsheetID, targetRange string, rows [][]interface{}) error {
+	if name := sheetspkg.SheetName(targetRange); name != "" {
+		if err := client.EnsureSheet(ctx, spreadsheetID, name); err != nil {
+			return fmt.Errorf("ensure sheet: %w", err)
+		}
+	}
+	if err := client.ClearRange(ctx, spreadsheetID, targetRange); err != nil {
+		return fmt.Errorf("clear: %w", err)
+	}
+	if err := client.UpdateFormulas(ctx, spreadsheetID, targetRange, rows); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	formatTable(ctx, client, spreadsheetID, targetRange, 2, 0, 0)
+	protectTable(ctx, client, spreadsheetID, targetRange, 2)
+	return nil
+}
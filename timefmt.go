@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+)
+
+// timeZone and dateFormat render every timestamp meant for people: stdout,
+// Discord, notifications, text cells of the sheets and CSV exports. Set by
+// --timezone and --date-format.
+var (
+	timeZone   = time.Local
+	dateFormat = time.RFC3339
+)
+
+// dateFormats are the named --date-format values; anything else is taken as
+// a Go reference-time layout.
+var dateFormats = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"datetime": "2006-01-02 15:04",
+	"date":     "2006-01-02",
+}
+
+// parseTimeZone accepts "local", "UTC", Torn's "TCT" (which is UTC) or an
+// IANA name such as "Europe/London".
+func parseTimeZone(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "", "local":
+		return time.Local, nil
+	case "utc", "tct":
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// parseDateFormat resolves a named format or checks that a layout contains
+// at least one element of Go's reference time.
+func parseDateFormat(s string) (string, error) {
+	if layout, ok := dateFormats[strings.ToLower(s)]; ok {
+		return layout, nil
+	}
+	ref := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if ref.Format(s) == s {
+		return "", fmt.Errorf("%q is neither rfc3339, datetime, date nor a Go time layout such as 02 Jan 2006 15:04", s)
+	}
+	return s, nil
+}
+
+// formatTime renders t in the configured zone and format.
+func formatTime(t time.Time) string {
+	return t.In(timeZone).Format(dateFormat)
+}
+
+// formatUnix renders a Unix timestamp in the configured zone and format.
+func formatUnix(unix int64) string {
+	return formatTime(time.Unix(unix, 0))
+}
+
+// serialTime converts a Unix timestamp to a spreadsheet date-time serial in
+// the configured zone, so date cells show the same wall time as the text.
+func serialTime(unix int64) float64 {
+	_, offset := time.Unix(unix, 0).In(timeZone).Zone()
+	return sheetspkg.SerialTime(unix + int64(offset))
+}
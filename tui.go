@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// browserPage is how many lines the browser prints before waiting for "more".
+const browserPage = 20
+
+// browser is an interactive, line-oriented report browser: it lists members
+// narrowed down by name, position and difficulty and shows a member's full
+// CPR table and crime history on request.
+type browser struct {
+	report Report
+	obs    []observation
+	out    io.Writer
+
+	name       string
+	position   string
+	difficulty int
+
+	// the rest of the last listing, shown by "more"
+	pending []string
+}
+
+func tuiCommand(ctx context.Context, args []string) {
+	fs := newFlagSet("tui")
+	parseFlags(fs, args)
+
+	tornClient := newTornClient(getRequiredEnv("TORN_API_KEY"))
+	members, err := tornClient.FetchMembers()
+	if err != nil {
+		slog.Error("fetch members", "error", err)
+		os.Exit(errorExitCode(err))
+	}
+	crimes, err := tornClient.FetchAllCrimes()
+	if err != nil {
+		slog.Error("fetch crimes", "error", err)
+		os.Exit(errorExitCode(err))
+	}
+
+	all := make(map[int]torn.Member, len(members))
+	for _, m := range members {
+		all[m.ID] = m
+	}
+	b := &browser{
+		report: buildReport(all, buildStats(crimes)),
+		obs:    observations(crimes, members),
+		out:    os.Stdout,
+	}
+	b.run(os.Stdin)
+}
+
+// run reads commands from in until "quit" or end of input.
+func (b *browser) run(in io.Reader) {
+	fmt.Fprintf(b.out, "%d members, %d crime slots. Type help for commands.\n", len(b.report.Members), len(b.obs))
+	b.list()
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(b.out, b.prompt())
+		if !scanner.Scan() {
+			fmt.Fprintln(b.out)
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		arg = strings.TrimSpace(arg)
+		switch strings.ToLower(cmd) {
+		case "", "more", "m":
+			b.more()
+		case "list", "l", "ls":
+			b.list()
+		case "name", "n", "find":
+			b.name = arg
+			b.list()
+		case "position", "pos", "p":
+			b.position = arg
+			b.list()
+		case "difficulty", "diff", "d":
+			b.difficulty = 0
+			if arg != "" {
+				d, err := strconv.Atoi(arg)
+				if err != nil {
+					fmt.Fprintln(b.out, "difficulty must be a number")
+					continue
+				}
+				b.difficulty = d
+			}
+			b.list()
+		case "clear", "c":
+			b.name, b.position, b.difficulty = "", "", 0
+			b.list()
+		case "show", "s":
+			b.show(arg)
+		case "help", "h", "?":
+			b.help()
+		case "quit", "q", "exit":
+			return
+		default:
+			// a bare name or ID drills into that member
+			b.show(strings.TrimSpace(scanner.Text()))
+		}
+	}
+}
+
+func (b *browser) prompt() string {
+	var filters []string
+	if b.name != "" {
+		filters = append(filters, "name~"+b.name)
+	}
+	if b.position != "" {
+		filters = append(filters, b.position)
+	}
+	if b.difficulty != 0 {
+		filters = append(filters, fmt.Sprintf("D%d", b.difficulty))
+	}
+	return "[" + strings.Join(filters, " ") + "]> "
+}
+
+func (b *browser) help() {
+	fmt.Fprint(b.out, `Commands:
+  list                   list the members matching the filters
+  name <text>            only members whose name contains text (empty clears)
+  position <name>        only members with a CPR at this position (empty clears)
+  difficulty <n>         only members with a CPR at this difficulty (empty clears)
+  clear                  drop all filters
+  show <name or ID>      a member's CPR table and crime history (or just type it)
+  more                   next page of the last listing (or press enter)
+  quit
+`)
+}
+
+// page prints the first page of lines and keeps the rest for "more".
+func (b *browser) page(lines []string) {
+	n := min(len(lines), browserPage)
+	for _, l := range lines[:n] {
+		fmt.Fprintln(b.out, l)
+	}
+	b.pending = lines[n:]
+	if len(b.pending) > 0 {
+		fmt.Fprintf(b.out, "-- %d more; press enter --\n", len(b.pending))
+	}
+}
+
+func (b *browser) more() {
+	if len(b.pending) == 0 {
+		return
+	}
+	b.page(b.pending)
+}
+
+// matches returns the positions of mr that pass the position and difficulty
+// filters, with the difficulty they were recorded at.
+func (b *browser) matches(mr MemberReport) ([]PositionReport, []int) {
+	var prs []PositionReport
+	var diffs []int
+	for _, dr := range mr.Difficulties {
+		if b.difficulty != 0 && dr.Difficulty != b.difficulty {
+			continue
+		}
+		for _, pr := range dr.Positions {
+			if b.position != "" && !strings.EqualFold(pr.Position, b.position) {
+				continue
+			}
+			prs = append(prs, pr)
+			diffs = append(diffs, dr.Difficulty)
+		}
+	}
+	return prs, diffs
+}
+
+// list prints one line per matching member. Without position or difficulty
+// filters it shows each member's best CPR; with them, every matching CPR,
+// highest first.
+func (b *browser) list() {
+	type row struct {
+		rate int
+		line string
+	}
+	var rows []row
+	filtered := b.position != "" || b.difficulty != 0
+	for _, mr := range b.report.Members {
+		m := mr.Member
+		if b.name != "" && !strings.Contains(strings.ToLower(m.Name), strings.ToLower(b.name)) {
+			continue
+		}
+		label := fmt.Sprintf("%-20s %-10s", fmt.Sprintf("%s [%d]", m.Name, m.ID), ocStatus(m))
+		if !filtered {
+			s := summarize(mr)
+			if s.Positions == 0 {
+				rows = append(rows, row{0, label + " no OC history"})
+				continue
+			}
+			rows = append(rows, row{s.Best.Rate, fmt.Sprintf("%s best %3d%% %s D%d, %d positions", label, s.Best.Rate, s.Best.Position, s.BestDiff, s.Positions)})
+			continue
+		}
+		prs, diffs := b.matches(mr)
+		for i, pr := range prs {
+			if pr.Rate == 0 {
+				continue
+			}
+			rows = append(rows, row{pr.Rate, fmt.Sprintf("%s %3d%% %s D%d", label, pr.Rate, pr.Position, diffs[i])})
+		}
+	}
+	if filtered {
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].rate > rows[j].rate })
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(b.out, "No matching members.")
+		b.pending = nil
+		return
+	}
+	lines := make([]string, len(rows))
+	for i, r := range rows {
+		lines[i] = r.line
+	}
+	b.page(lines)
+}
+
+func ocStatus(m torn.Member) string {
+	if m.IsInOC {
+		return "in OC"
+	}
+	return "not in OC"
+}
+
+// show prints the CPR table and the crime history, newest first, of the
+// member with the given ID, exact name or only name containing query.
+func (b *browser) show(query string) {
+	if query == "" {
+		fmt.Fprintln(b.out, "show needs a member name or ID")
+		return
+	}
+	var found []MemberReport
+	id, _ := strconv.Atoi(query)
+	for _, mr := range b.report.Members {
+		if mr.Member.ID == id || strings.EqualFold(mr.Member.Name, query) {
+			found = []MemberReport{mr}
+			break
+		}
+		if strings.Contains(strings.ToLower(mr.Member.Name), strings.ToLower(query)) {
+			found = append(found, mr)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		fmt.Fprintf(b.out, "No member matches %q.\n", query)
+		return
+	case len(found) > 1:
+		names := make([]string, len(found))
+		for i, mr := range found {
+			names[i] = mr.Member.Name
+		}
+		fmt.Fprintf(b.out, "%q matches %s.\n", query, strings.Join(names, ", "))
+		return
+	}
+
+	mr := found[0]
+	// skip the generated-at line
+	lines := generateReportLines(Report{Members: found})[1:]
+	lines = append(lines, "", "  History:")
+	for _, ob := range b.obs {
+		if ob.Member.ID != mr.Member.ID {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("    %s  %-22s D%d  %-12s %3d%%  %s",
+			time.Unix(ob.Crime.ExecutedAt, 0).In(timeZone).Format("2006-01-02"), ob.Crime.Name, ob.Crime.Difficulty, ob.Slot.Position, ob.Slot.CheckpointPassRate, ob.Slot.User.Outcome))
+	}
+	b.page(lines)
+}
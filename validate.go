@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/config"
+	"github.com/mnuck/torn-oc-history/internal/discord"
+	"github.com/mnuck/torn-oc-history/internal/errreport"
+	"github.com/mnuck/torn-oc-history/internal/server"
+	sheetspkg "github.com/mnuck/torn-oc-history/internal/sheets"
+)
+
+// otherCommandFlags are flags only some commands outside validate's flag set
+// take, which a config file may still set.
+var otherCommandFlags = []string{"out"}
+
+func configCommand(ctx context.Context, args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintf(os.Stderr, "Usage: %s config validate [flags]\n", os.Args[0])
+		os.Exit(2)
+	}
+	args = args[1:]
+
+	o := defaultOptions()
+	fs := newFlagSet("config validate")
+	fs.StringVar(&o.Output, "output", o.Output, "output destination to validate for: stdout, sheets or discord")
+	o.printFlags(fs)
+	o.selectionFlags(fs)
+	o.sheetsFlags(fs)
+	o.scheduleFlags(fs)
+	o.serverFlags(fs)
+	o.dryRunFlag(fs)
+	// parseFlags also resolves the secrets and exits on an unreadable config
+	// file, profile or secret
+	parseFlags(fs, args)
+
+	problems := validateConfig(o, fs, args)
+	for _, p := range problems {
+		fmt.Println("error: " + p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problems found\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Println("Configuration OK")
+}
+
+// validateConfig checks the settings of a run without calling any API and
+// returns a description of each problem.
+func validateConfig(o *options, fs *flag.FlagSet, args []string) []string {
+	var problems []string
+	add := func(format string, a ...any) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	if path := config.PathFromArgs(args); path != "" {
+		// parseFlags has loaded it already
+		if cfg, err := config.Load(path); err == nil {
+			for _, name := range cfg.UnknownFlags(fs) {
+				if !slices.Contains(otherCommandFlags, name) {
+					add("%s: unknown flag %q", path, name)
+				}
+			}
+		}
+	}
+
+	if _, err := validateOptions(o); err != nil {
+		add("%v", err)
+	}
+	if os.Getenv("TORN_API_KEY") == "" {
+		add("TORN_API_KEY is not set (nor TORN_API_KEY_FILE, nor in the keyring with --keyring)")
+	}
+
+	ranges := []struct{ flag, value string }{
+		{"range-noc", o.NocRange}, {"range-all", o.AllRange}, {"range-log", o.LogRange},
+		{"range-summary", o.SummaryRange}, {"range-charts", o.ChartsRange}, {"range-planner", o.PlannerRange},
+		{"range-raw", o.RawRange}, {"range-about", o.AboutRange}, {"range-audit", o.AuditRange},
+	}
+	for _, r := range ranges {
+		if r.value == "" {
+			continue
+		}
+		target := r.value
+		if name, fallback, ok := parseNamedTarget(r.value); ok {
+			if name == "" {
+				add("--%s: %q has no named range after %s", r.flag, r.value, namedRangePrefix)
+				continue
+			}
+			target = fallback
+		}
+		if err := sheetspkg.ValidateA1(target); err != nil {
+			add("--%s: %v", r.flag, err)
+		}
+	}
+
+	if o.Output == "sheets" {
+		if creds := os.Getenv("GOOGLE_CREDENTIALS_JSON"); creds != "" && !json.Valid([]byte(creds)) {
+			add("GOOGLE_CREDENTIALS_JSON is not valid JSON")
+		} else if _, _, err := sheetspkg.CredentialsFromEnv("credentials.json"); err != nil {
+			add("Google credentials: %v", err)
+		}
+		if file := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); file != "" {
+			if _, err := os.Stat(file); err != nil {
+				add("GOOGLE_APPLICATION_CREDENTIALS: %v", err)
+			}
+		}
+		if role := getEnvWithDefault("SHARE_ROLE", "writer"); role != "writer" && role != "reader" {
+			add("SHARE_ROLE must be writer or reader, not %q", role)
+		}
+	}
+
+	if o.Output == "discord" && !o.DryRun {
+		if hook := os.Getenv("DISCORD_WEBHOOK_URL"); hook == "" {
+			add("DISCORD_WEBHOOK_URL is required with --output discord")
+		} else if u, err := url.Parse(hook); err != nil || u.Scheme != "https" || !strings.Contains(u.Path, "/api/webhooks/") {
+			add("DISCORD_WEBHOOK_URL does not look like a Discord webhook URL (https://discord.com/api/webhooks/...)")
+		}
+	}
+	if server := os.Getenv("NTFY_SERVER"); server != "" {
+		if u, err := url.Parse(server); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			add("NTFY_SERVER must be an http or https URL, not %q", server)
+		}
+		if os.Getenv("NTFY_TOPIC") == "" {
+			add("NTFY_SERVER is set but NTFY_TOPIC is not, so nothing is sent to ntfy")
+		}
+	}
+	if (os.Getenv("PUSHOVER_TOKEN") == "") != (os.Getenv("PUSHOVER_USER") == "") {
+		add("Pushover needs both PUSHOVER_TOKEN and PUSHOVER_USER")
+	}
+	if hook := os.Getenv("DISCORD_ALERT_WEBHOOK_URL"); hook != "" {
+		if u, err := url.Parse(hook); err != nil || u.Scheme != "https" || !strings.Contains(u.Path, "/api/webhooks/") {
+			add("DISCORD_ALERT_WEBHOOK_URL does not look like a Discord webhook URL (https://discord.com/api/webhooks/...)")
+		}
+	}
+	if (os.Getenv("TELEGRAM_BOT_TOKEN") == "") != (os.Getenv("TELEGRAM_CHAT_ID") == "") {
+		add("Telegram needs both TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID")
+	}
+	if (os.Getenv("SMTP_HOST") == "") != (os.Getenv("ALERT_EMAIL_TO") == "") {
+		add("Email alerts need both SMTP_HOST and ALERT_EMAIL_TO")
+	} else if os.Getenv("SMTP_HOST") != "" && os.Getenv("ALERT_EMAIL_FROM") == "" && os.Getenv("SMTP_USERNAME") == "" {
+		add("Email alerts need ALERT_EMAIL_FROM (or SMTP_USERNAME) as the sender")
+	}
+	if key := os.Getenv("DISCORD_PUBLIC_KEY"); key != "" {
+		if _, err := discord.ParsePublicKey(key); err != nil {
+			add("DISCORD_PUBLIC_KEY: %v", err)
+		}
+		if o.Listen == "" {
+			add("DISCORD_PUBLIC_KEY is set but the slash commands are only answered with --listen")
+		}
+	}
+	if os.Getenv("SLACK_SIGNING_SECRET") != "" && o.Listen == "" {
+		add("SLACK_SIGNING_SECRET is set but the slash command is only answered with --listen")
+	}
+	if _, err := server.ParseTokens(os.Getenv("SERVER_AUTH_TOKENS")); err != nil {
+		add("SERVER_AUTH_TOKENS: %v", err)
+	}
+	if basic := os.Getenv("SERVER_BASIC_AUTH"); basic != "" {
+		if user, password, ok := strings.Cut(basic, ":"); !ok || user == "" || password == "" {
+			add("SERVER_BASIC_AUTH must be user:password")
+		}
+	}
+	if (os.Getenv("SERVER_AUTH_TOKENS") != "" || os.Getenv("SERVER_BASIC_AUTH") != "") && o.Listen == "" {
+		add("SERVER_AUTH_TOKENS or SERVER_BASIC_AUTH is set but only protects the --listen server")
+	}
+	if (os.Getenv("DISCORD_APPLICATION_ID") == "") != (os.Getenv("DISCORD_BOT_TOKEN") == "") {
+		add("Registering the Discord slash commands needs both DISCORD_APPLICATION_ID and DISCORD_BOT_TOKEN")
+	}
+	if _, err := errreport.FromEnv(version); err != nil {
+		add("%v", err)
+	}
+	if hook := os.Getenv("ERROR_REPORT_WEBHOOK_URL"); hook != "" {
+		if u, err := url.Parse(hook); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			add("ERROR_REPORT_WEBHOOK_URL must be an http or https URL")
+		}
+	}
+
+	if o.Listen != "" {
+		if _, _, err := net.SplitHostPort(o.Listen); err != nil {
+			add("--listen: %v", err)
+		}
+	}
+	if o.GRPCListen != "" {
+		if _, _, err := net.SplitHostPort(o.GRPCListen); err != nil {
+			add("--grpc-listen: %v", err)
+		}
+		if o.Listen == "" {
+			add("--grpc-listen is only served alongside --listen")
+		}
+	}
+	if (o.BasePath != "" || o.CORSOrigins != "" || o.TrustProxy) && o.Listen == "" {
+		add("--base-path, --cors-origins and --trust-proxy only apply alongside --listen")
+	}
+	if o.Factions != "" {
+		if o.Listen == "" {
+			add("--factions are only served alongside --listen")
+		}
+		if _, err := loadFactions(splitList(o.Factions)); err != nil {
+			add("--factions: %v", err)
+		}
+	}
+	if o.StaticDir != "" {
+		if _, err := os.Stat(filepath.Dir(filepath.Clean(o.StaticDir))); err != nil {
+			add("--static-dir: %v", err)
+		}
+	}
+	if o.LockFile != "" {
+		if _, err := os.Stat(filepath.Dir(o.LockFile)); err != nil {
+			add("--lock-file: %v", err)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// version, commit and buildDate are set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=...".
+// Without them commit falls back to the VCS revision Go stamps into binaries
+// built from a checkout.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+func init() {
+	if commit != "" {
+		return
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			commit = s.Value
+		case "vcs.time":
+			if buildDate == "" {
+				buildDate = s.Value
+			}
+		}
+	}
+}
+
+// versionString describes the build in one line.
+func versionString() string {
+	s := "torn-oc-history " + version
+	if commit != "" {
+		s += " (" + commit[:min(len(commit), 12)]
+		if buildDate != "" {
+			s += ", " + buildDate
+		}
+		s += ")"
+	}
+	return s + " " + runtime.Version()
+}
+
+func versionCommand(ctx context.Context, args []string) {
+	newFlagSet("version").Parse(args)
+	fmt.Println(versionString())
+}
+
+// checkCrimesSchema warns when the Torn crimes payload no longer has the
+// fields the reports are built from, which would otherwise show up as
+// silently empty or zero CPR. Failing to fetch is left to the run itself.
+func checkCrimesSchema(client *torn.Client) {
+	missing, err := client.CheckCrimesSchema()
+	if err != nil {
+		slog.Debug("Skipped Torn crimes schema check", "error", err)
+		return
+	}
+	if len(missing) > 0 {
+		slog.Warn("Torn crimes payload is missing fields this version depends on; reports will be wrong until torn-oc-history is updated",
+			"missing", missing, "version", versionString())
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mnuck/torn-oc-history/internal/torn"
+)
+
+// watcher tells, in watch mode, whether anything the reports show has changed
+// since it last looked: newly completed crimes, or members joining, leaving,
+// being renamed or going in or out of an OC. Last actions are ignored, as
+// they change all the time.
+type watcher struct {
+	members string
+	newest  int64
+}
+
+// memberKey sums up the members as the reports see them.
+func memberKey(members []torn.Member) string {
+	keys := make([]string, 0, len(members))
+	for _, m := range members {
+		keys = append(keys, fmt.Sprintf("%d %s %t", m.ID, m.Name, m.IsInOC))
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, "\n")
+}
+
+// newestAt is the latest executed_at of crimes, or since if none is later.
+func newestAt(crimes []torn.Crime, since int64) int64 {
+	for _, c := range crimes {
+		since = max(since, c.ExecutedAt)
+	}
+	return since
+}
+
+// saw records what a run reported on: the members, and the newest crime's
+// executed_at. A nil watcher records nothing.
+func (w *watcher) saw(members []torn.Member, newest int64) {
+	if w == nil {
+		return
+	}
+	w.members, w.newest = memberKey(members), max(newest, w.newest)
+}
+
+// forget makes the next check report a change, e.g. after a failed run.
+func (w *watcher) forget() {
+	if w != nil {
+		w.members = ""
+	}
+}
+
+// changed polls the members and the crimes completed since the newest seen,
+// two requests, and reports whether either differs from what was seen.
+func (w *watcher) changed(c *torn.Client) (bool, error) {
+	members, err := c.FetchMembers()
+	if err != nil {
+		return false, fmt.Errorf("fetch members: %w", err)
+	}
+	crimes, err := c.FetchCrimesSince("completed", w.newest+1)
+	if err != nil {
+		return false, fmt.Errorf("fetch crimes: %w", err)
+	}
+	key, newest := memberKey(members), newestAt(crimes, w.newest)
+	changed := key != w.members || newest > w.newest
+	w.members, w.newest = key, newest
+	return changed, nil
+}